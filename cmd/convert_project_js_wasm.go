@@ -1,12 +1,12 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/converters"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
-	"sort"
-	"strings"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/logger"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sliceutil"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/validation"
 	"syscall/js"
 )
 
@@ -18,25 +18,56 @@ func main() {
 
 func convertProject() js.Func {
 	return js.FuncOf(func(this js.Value, funcArgs []js.Value) any {
-		if len(funcArgs) != 3 {
-			fmt.Println("Must pass in url, space, and project slug")
+		if len(funcArgs) < 3 {
+			logger.Error("must pass in url, space, and project slug, and optionally an AbortSignal")
 		}
 
 		handler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 			resolve := args[0]
 			reject := args[1]
 
+			ctx, cancel := context.WithCancel(context.Background())
+
+			// An optional AbortSignal allows the browser to cancel the export, for example when the popup is closed
+			onAbort := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				cancel()
+				return nil
+			})
+
+			var signal js.Value
+			if len(funcArgs) > 3 && funcArgs[3].Truthy() {
+				signal = funcArgs[3]
+				if signal.Get("aborted").Bool() {
+					cancel()
+				}
+				signal.Call("addEventListener", "abort", onAbort)
+			}
+
 			go func() {
-				files, err := convertProjectToTerraform(funcArgs[0].String(), funcArgs[1].String(), funcArgs[2].String())
+				defer func() {
+					if signal.Truthy() {
+						signal.Call("removeEventListener", "abort", onAbort)
+					}
+					onAbort.Release()
+					cancel()
+				}()
+
+				files, err := convertProjectToTerraform(ctx, funcArgs[0].String(), funcArgs[1].String(), funcArgs[2].String())
 
 				if err != nil {
 					reject.Invoke(err.Error())
+					return
+				}
+
+				if err := validation.ValidateFiles(files); err != nil {
+					reject.Invoke(err.Error())
+					return
 				}
 
 				hclBlob := ""
 
-				for _, h := range strutil.UnEscapeDollar(files) {
-					hclBlob += h + "\n"
+				for _, fileName := range sliceutil.SortedKeys(files) {
+					hclBlob += files[fileName] + "\n"
 				}
 
 				resolve.Invoke(hclBlob)
@@ -51,8 +82,8 @@ func convertProject() js.Func {
 	})
 }
 
-func convertProjectToTerraform(url string, space string, projectId string) (map[string]string, error) {
-	client := client.OctopusClient{
+func convertProjectToTerraform(ctx context.Context, url string, space string, projectId string) (map[string]string, error) {
+	client := client.OctopusApiClient{
 		Url:   url,
 		Space: space,
 	}
@@ -183,42 +214,14 @@ func convertProjectToTerraform(url string, space string, projectId string) (map[
 		},
 		VariableSetConverter: variableSetConverter,
 		ChannelConverter:     channelConverter,
-	}.ToHclById(projectId, &dependencies)
+	}.ToHclById(ctx, projectId, &dependencies)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return processJavaScriptResources(dependencies.Resources)
-}
-
-// processResources creates a map of file names to file content
-func processJavaScriptResources(resources []converters.ResourceDetails) (map[string]string, error) {
-	fileMap := map[string]string{}
-
-	// Sort by resource type
-	resourcesSlice := resources[:]
-	sort.Slice(resourcesSlice, func(i, j int) bool {
-		return resourcesSlice[i].ResourceType < resourcesSlice[j].ResourceType
-	})
-
-	for _, r := range resourcesSlice {
-		// Some resources are already resolved by their parent, but exist in the resource details map as a lookup.
-		// In these cases, ToHclByProjectId is nil.
-		if r.ToHcl == nil {
-			continue
-		}
-
-		hcl, err := r.ToHcl()
-
-		if err != nil {
-			return nil, err
-		}
-
-		if len(strings.TrimSpace(hcl)) != 0 {
-			fileMap[r.FileName] = hcl
-		}
-	}
+	// The project is exported with its dependencies, so the browser export does not look up unresolved references
+	files, _, err := dependencies.RenderFiles(ctx)
 
-	return fileMap, nil
+	return files, err
 }
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
+	"strings"
+	"sync"
+)
+
+// CountingOctopusClient passes requests to another client, and counts the calls made for each resource type.
+type CountingOctopusClient struct {
+	Client OctopusClient
+	mu     sync.Mutex
+	calls  map[string]int
+}
+
+// NewCountingOctopusClient creates a client that counts the calls made to the supplied client.
+func NewCountingOctopusClient(client OctopusClient) *CountingOctopusClient {
+	return &CountingOctopusClient{Client: client}
+}
+
+func (c *CountingOctopusClient) GetSpaceBaseUrl(ctx context.Context) (string, error) {
+	c.count("Spaces")
+	return c.Client.GetSpaceBaseUrl(ctx)
+}
+
+func (c *CountingOctopusClient) GetSpace(ctx context.Context, resources *octopus2.Space) error {
+	c.count("Spaces")
+	return c.Client.GetSpace(ctx, resources)
+}
+
+func (c *CountingOctopusClient) GetResourceById(ctx context.Context, resourceType string, id string, resources any) (bool, error) {
+	c.count(resourceType)
+	return c.Client.GetResourceById(ctx, resourceType, id, resources)
+}
+
+func (c *CountingOctopusClient) GetAllResources(ctx context.Context, resourceType string, resources any, queryParams ...[]string) error {
+	c.count(resourceType)
+	return c.Client.GetAllResources(ctx, resourceType, resources, queryParams...)
+}
+
+// Calls returns a copy of the number of calls made for each resource type. Calls for nested resources, like
+// Projects/Projects-1/runbooks, are counted against the top level type.
+func (c *CountingOctopusClient) Calls() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	calls := map[string]int{}
+	for k, v := range c.calls {
+		calls[k] = v
+	}
+
+	return calls
+}
+
+func (c *CountingOctopusClient) count(resourceType string) {
+	resourceType, _, _ = strings.Cut(resourceType, "/")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.calls == nil {
+		c.calls = map[string]int{}
+	}
+
+	c.calls[resourceType]++
+}
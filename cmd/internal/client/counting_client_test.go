@@ -0,0 +1,24 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCountingClient(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+
+	counter := NewCountingOctopusClient(OctopusApiClient{Url: server.URL, Space: "Spaces-1"})
+	counter.GetResourceById(context.Background(), "Projects", "Projects-1", &map[string]any{})
+	counter.GetAllResources(context.Background(), "Projects/Projects-1/runbooks", &map[string]any{})
+	counter.GetAllResources(context.Background(), "Environments", &map[string]any{})
+
+	calls := counter.Calls()
+
+	if calls["Projects"] != 2 || calls["Environments"] != 1 || len(calls) != 2 {
+		t.Errorf("expected 2 calls for projects and 1 for environments, got %v", calls)
+	}
+}
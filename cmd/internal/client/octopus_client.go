@@ -1,75 +1,163 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/logger"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
-type OctopusClient struct {
+// OctopusClient retrieves resources from the Octopus API. OctopusApiClient queries a live server,
+// RecordingOctopusClient saves the responses of another client to a snapshot, and ReplayOctopusClient
+// serves the responses from a snapshot without any network access.
+type OctopusClient interface {
+	// GetSpaceBaseUrl returns the URL of the space API, e.g. https://myinstance.octopus.app/api/Spaces-1
+	GetSpaceBaseUrl(ctx context.Context) (string, error)
+	GetSpace(ctx context.Context, resources *octopus2.Space) error
+	// GetResourceById returns false if the resource was not found.
+	GetResourceById(ctx context.Context, resourceType string, id string, resources any) (bool, error)
+	GetAllResources(ctx context.Context, resourceType string, resources any, queryParams ...[]string) error
+}
+
+// OctopusApiClient queries the Octopus API.
+type OctopusApiClient struct {
 	Url    string
 	ApiKey string
-	Space  string
+	// AccessToken is a bearer token, either generated by Octopus or exchanged from an OIDC token.
+	// It takes precedence over the ApiKey.
+	AccessToken string
+	Space       string
+	// HttpClient sends the requests, and is typically configured with NewHttpClient. A nil value means
+	// http.DefaultClient is used.
+	HttpClient *http.Client
+	// Headers are additional headers sent with every request.
+	Headers map[string]string
+	// RequestTimeout is the maximum time allowed for each individual request to the Octopus API,
+	// including reading the response body. Zero means requests are only limited by the context.
+	RequestTimeout time.Duration
+}
+
+// setHeaders adds the custom headers and then the access token or API key to the request.
+func (o OctopusApiClient) setHeaders(req *http.Request) {
+	for name, value := range o.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if o.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+o.AccessToken)
+	} else if o.ApiKey != "" {
+		req.Header.Set("X-Octopus-ApiKey", o.ApiKey)
+	}
+}
+
+// cancelOnClose releases the per-request timeout once the response body has been closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// httpClient returns the configured client, or the default client.
+func (o OctopusApiClient) httpClient() *http.Client {
+	if o.HttpClient != nil {
+		return o.HttpClient
+	}
+	return http.DefaultClient
+}
+
+// do sends the request with the custom and authentication headers, applying the per-request timeout.
+// Callers must close the response body.
+func (o OctopusApiClient) do(req *http.Request) (*http.Response, error) {
+	o.setHeaders(req)
+
+	if o.RequestTimeout <= 0 {
+		return o.send(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), o.RequestTimeout)
+	res, err := o.send(req.WithContext(ctx))
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	res.Body = cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
 }
 
-func (o OctopusClient) lookupSpaceAsId() (bool, error) {
+// send sends the request, logging the URL, status code and time taken at the debug level.
+func (o OctopusApiClient) send(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := o.httpClient().Do(req)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		logger.Debug("request failed", "method", req.Method, "url", req.URL.String(), "duration", elapsed, "error", err)
+		return nil, err
+	}
+
+	logger.Debug("request", "method", req.Method, "url", req.URL.String(), "status", res.StatusCode, "duration", elapsed)
+	return res, nil
+}
+
+func (o OctopusApiClient) lookupSpaceAsId(ctx context.Context) (bool, error) {
 	if len(strings.TrimSpace(o.Space)) == 0 {
 		return false, errors.New("space can not be empty")
 	}
 
 	requestURL := fmt.Sprintf("%s/api/Spaces/%s", o.Url, o.Space)
-	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 
 	if err != nil {
 		return false, err
 	}
 
-	if o.ApiKey != "" {
-		req.Header.Set("X-Octopus-ApiKey", o.ApiKey)
-	}
-
-	res, err := http.DefaultClient.Do(req)
+	res, err := o.do(req)
 
 	if err != nil {
 		return false, err
 	}
+	defer res.Body.Close()
 
 	return res.StatusCode != 404, nil
 }
 
-func (o OctopusClient) lookupSpaceAsName() (string, error) {
+func (o OctopusApiClient) lookupSpaceAsName(ctx context.Context) (string, error) {
 	if len(strings.TrimSpace(o.Space)) == 0 {
 		return "", errors.New("space can not be empty")
 	}
 
 	requestURL := fmt.Sprintf("%s/api/Spaces?take=1000&partialName=%s", o.Url, url.QueryEscape(o.Space))
 
-	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 
 	if err != nil {
 		return "", err
 	}
 
-	if o.ApiKey != "" {
-		req.Header.Set("X-Octopus-ApiKey", o.ApiKey)
-	}
-
-	res, err := http.DefaultClient.Do(req)
+	res, err := o.do(req)
 
 	if err != nil {
-		fmt.Println(err.Error())
 		return "", err
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode != 200 {
 		return "", nil
 	}
-	defer res.Body.Close()
 
 	collection := octopus2.GeneralCollection[octopus2.Space]{}
 	err = json.NewDecoder(res.Body).Decode(&collection)
@@ -87,64 +175,70 @@ func (o OctopusClient) lookupSpaceAsName() (string, error) {
 	return "", errors.New("did not find space with name " + o.Space)
 }
 
-func (o OctopusClient) getSpaceUrl() (string, error) {
+func (o OctopusApiClient) getSpaceUrl(ctx context.Context) (string, error) {
 	if len(strings.TrimSpace(o.Space)) == 0 {
 		return "", errors.New("space can not be empty")
 	}
 
-	spaceId, err := o.lookupSpaceAsName()
+	spaceId, err := o.lookupSpaceAsName(ctx)
 	if err == nil {
 		return fmt.Sprintf("%s/api/Spaces/%s", o.Url, spaceId), nil
 	}
 
-	spaceIdValid, err := o.lookupSpaceAsId()
+	spaceIdValid, err := o.lookupSpaceAsId(ctx)
 	if spaceIdValid && err == nil {
 		return fmt.Sprintf("%s/api/Spaces/%s", o.Url, o.Space), nil
 	}
 
+	// Report a cancelled or timed out export rather than a missing space
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
 	return "", errors.New("did not find space with name or id '" + o.Space + "'")
 }
 
-func (o OctopusClient) GetSpaceBaseUrl() (string, error) {
+func (o OctopusApiClient) GetSpaceBaseUrl(ctx context.Context) (string, error) {
 	if len(strings.TrimSpace(o.Space)) == 0 {
 		return "", errors.New("space can not be empty")
 	}
 
-	spaceId, err := o.lookupSpaceAsName()
+	spaceId, err := o.lookupSpaceAsName(ctx)
 	if err == nil {
 		return fmt.Sprintf("%s/api/%s", o.Url, spaceId), nil
 	}
 
-	spaceIdValid, err := o.lookupSpaceAsId()
+	spaceIdValid, err := o.lookupSpaceAsId(ctx)
 	if spaceIdValid && err == nil {
 		return fmt.Sprintf("%s/api/%s", o.Url, o.Space), nil
 	}
 
+	// Report a cancelled or timed out export rather than a missing space
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
 	return "", errors.New("did not find space with name or id '" + o.Space + "'")
 }
 
-func (o OctopusClient) getSpaceRequest() (*http.Request, error) {
-	spaceUrl, err := o.getSpaceUrl()
+func (o OctopusApiClient) getSpaceRequest(ctx context.Context) (*http.Request, error) {
+	spaceUrl, err := o.getSpaceUrl(ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodGet, spaceUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, spaceUrl, nil)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if o.ApiKey != "" {
-		req.Header.Set("X-Octopus-ApiKey", o.ApiKey)
-	}
-
 	return req, nil
 }
 
-func (o OctopusClient) getRequest(resourceType string, id string) (*http.Request, error) {
-	spaceUrl, err := o.GetSpaceBaseUrl()
+func (o OctopusApiClient) getRequest(ctx context.Context, resourceType string, id string) (*http.Request, error) {
+	spaceUrl, err := o.GetSpaceBaseUrl(ctx)
 
 	if err != nil {
 		return nil, err
@@ -152,23 +246,17 @@ func (o OctopusClient) getRequest(resourceType string, id string) (*http.Request
 
 	requestURL := spaceUrl + "/" + resourceType + "/" + id
 
-	fmt.Println(requestURL)
-
-	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if o.ApiKey != "" {
-		req.Header.Set("X-Octopus-ApiKey", o.ApiKey)
-	}
-
 	return req, nil
 }
 
-func (o OctopusClient) getCollectionRequest(resourceType string, queryParams ...[]string) (*http.Request, error) {
-	spaceUrl, err := o.GetSpaceBaseUrl()
+func (o OctopusApiClient) getCollectionRequest(ctx context.Context, resourceType string, queryParams ...[]string) (*http.Request, error) {
+	spaceUrl, err := o.GetSpaceBaseUrl(ctx)
 
 	if err != nil {
 		return nil, err
@@ -187,52 +275,49 @@ func (o OctopusClient) getCollectionRequest(resourceType string, queryParams ...
 		}
 	}
 
-	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if o.ApiKey != "" {
-		req.Header.Set("X-Octopus-ApiKey", o.ApiKey)
-	}
-
 	return req, nil
 }
 
-func (o OctopusClient) GetSpace(resources *octopus2.Space) error {
-	req, err := o.getSpaceRequest()
+func (o OctopusApiClient) GetSpace(ctx context.Context, resources *octopus2.Space) error {
+	req, err := o.getSpaceRequest(ctx)
 
 	if err != nil {
 		return err
 	}
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := o.do(req)
 
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode != 200 {
-		return nil
+		return errors.New("failed to read the space: returned status " + res.Status)
 	}
-	defer res.Body.Close()
 
 	return json.NewDecoder(res.Body).Decode(resources)
 }
 
-func (o OctopusClient) GetResourceById(resourceType string, id string, resources any) (bool, error) {
-	req, err := o.getRequest(resourceType, id)
+func (o OctopusApiClient) GetResourceById(ctx context.Context, resourceType string, id string, resources any) (bool, error) {
+	req, err := o.getRequest(ctx, resourceType, id)
 
 	if err != nil {
 		return false, err
 	}
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := o.do(req)
 
 	if err != nil {
 		return false, err
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode == 404 {
 		return false, nil
@@ -241,7 +326,6 @@ func (o OctopusClient) GetResourceById(resourceType string, id string, resources
 	if res.StatusCode != 200 {
 		return false, errors.New("did not find the requested resource: " + resourceType + " " + id)
 	}
-	defer res.Body.Close()
 
 	body, err := io.ReadAll(res.Body)
 
@@ -252,34 +336,39 @@ func (o OctopusClient) GetResourceById(resourceType string, id string, resources
 	err = json.Unmarshal(body, resources)
 
 	if err != nil {
-		fmt.Println(string(body))
-		return false, err
+		logger.Debug("failed to parse the response", "url", req.URL.String(), "body", string(body))
+		return false, errors.New("failed to parse the response for " + resourceType + " " + id + ": " + err.Error())
 	}
 
 	return true, nil
 }
 
-func (o OctopusClient) GetResourceByName(resourceType string, names string) {
+func (o OctopusApiClient) GetResourceByName(ctx context.Context, resourceType string, names string) {
 
 }
 
-func (o OctopusClient) GetAllResources(resourceType string, resources any, queryParams ...[]string) error {
-	req, err := o.getCollectionRequest(resourceType, queryParams...)
+func (o OctopusApiClient) GetAllResources(ctx context.Context, resourceType string, resources any, queryParams ...[]string) error {
+	req, err := o.getCollectionRequest(ctx, resourceType, queryParams...)
 
 	if err != nil {
 		return err
 	}
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := o.do(req)
 
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
 
-	if res.StatusCode != 200 {
+	// A collection that does not exist has no resources to export
+	if res.StatusCode == 404 {
 		return nil
 	}
-	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return errors.New("failed to read the resources: " + resourceType + " returned status " + res.Status)
+	}
 
 	return json.NewDecoder(res.Body).Decode(resources)
 }
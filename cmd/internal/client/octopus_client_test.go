@@ -0,0 +1,155 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/logger"
+)
+
+// createSlowServer returns a server that takes a second to answer each request.
+func createSlowServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+			w.Write([]byte(`{"Items": []}`))
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRequestTimeout(t *testing.T) {
+	server := createSlowServer(t)
+
+	client := OctopusApiClient{Url: server.URL, Space: "Spaces-1", RequestTimeout: 50 * time.Millisecond}
+	start := time.Now()
+	_, err := client.GetSpaceBaseUrl(context.Background())
+
+	if err == nil {
+		t.Fatal("expected the request to time out")
+	}
+
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("the request was not abandoned after the timeout, took %v", time.Since(start))
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	server := createSlowServer(t)
+
+	client := OctopusApiClient{Url: server.URL, Space: "Spaces-1"}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetSpaceBaseUrl(ctx)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context deadline to be reported, got %v", err)
+	}
+}
+
+func TestRequestTimeoutCoversBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "Spaces-1", "Name": "Default"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := OctopusApiClient{Url: server.URL, Space: "Spaces-1", RequestTimeout: time.Second}
+	found, err := client.GetResourceById(context.Background(), "Environments", "Environments-1", &map[string]any{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !found {
+		t.Fatal("expected the resource to be found")
+	}
+}
+
+func TestExchangeOidcToken(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			w.Write([]byte(`{"token_endpoint": "` + server.URL + `/token/v1"}`))
+		case "/token/v1":
+			body := map[string]string{}
+			json.NewDecoder(r.Body).Decode(&body)
+
+			if body["audience"] != "service-account" || body["subject_token"] != "id-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			w.Write([]byte(`{"access_token": "access-token"}`))
+		case "/api/Spaces":
+			if r.Header.Get("Authorization") != "Bearer access-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			w.Write([]byte(`{"Items": [{"Id": "Spaces-1", "Name": "Default"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	token, err := OctopusApiClient{Url: server.URL, RequestTimeout: time.Second}.ExchangeOidcToken(context.Background(), "service-account", "id-token")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := OctopusApiClient{Url: server.URL, Space: "Default", AccessToken: token}
+	baseUrl, err := client.GetSpaceBaseUrl(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if baseUrl != server.URL+"/api/Spaces-1" {
+		t.Fatalf("unexpected base url %s", baseUrl)
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	buffer := &bytes.Buffer{}
+	previous := logger.Default()
+	logger.SetDefault(logger.New(buffer, logger.LevelDebug, true))
+	t.Cleanup(func() { logger.SetDefault(previous) })
+
+	client := OctopusApiClient{Url: server.URL, Space: "Spaces-1"}
+	client.GetResourceById(context.Background(), "Projects", "Projects-1", &map[string]any{})
+
+	var entry map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if strings.Contains(line, "Projects-1") {
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if entry == nil {
+		t.Fatalf("expected the request for the project to be logged, got:\n%s", buffer.String())
+	}
+
+	if entry["level"] != "DEBUG" || entry["method"] != "GET" || entry["status"] != float64(404) || entry["duration"] == nil {
+		t.Errorf("expected the request to be logged with the method, status and duration, got %v", entry)
+	}
+}
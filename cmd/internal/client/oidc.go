@@ -0,0 +1,108 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ExchangeOidcToken exchanges an ID token issued by an external identity provider, such as a CI
+// platform, for a short-lived Octopus access token. The serviceAccountId identifies the Octopus
+// service account whose OIDC identity trusts the issuer of the ID token.
+func (o OctopusApiClient) ExchangeOidcToken(ctx context.Context, serviceAccountId string, idToken string) (string, error) {
+	if strings.TrimSpace(serviceAccountId) == "" {
+		return "", errors.New("the service account ID is required to exchange an OIDC token")
+	}
+
+	tokenEndpoint, err := o.getTokenEndpoint(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"grant_type":         "urn:ietf:params:oauth:grant-type:token-exchange",
+		"audience":           serviceAccountId,
+		"subject_token":      idToken,
+		"subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, bytes.NewReader(body))
+
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := o.do(req)
+
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return "", fmt.Errorf("failed to exchange the OIDC token, the server returned status %d", res.StatusCode)
+	}
+
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+
+	err = json.NewDecoder(res.Body).Decode(&token)
+
+	if err != nil {
+		return "", err
+	}
+
+	if token.AccessToken == "" {
+		return "", errors.New("the server did not return an access token")
+	}
+
+	return token.AccessToken, nil
+}
+
+// getTokenEndpoint reads the token endpoint from the server's OpenID configuration.
+func (o OctopusApiClient) getTokenEndpoint(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(o.Url, "/")+"/.well-known/openid-configuration", nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	res, err := o.do(req)
+
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return "", fmt.Errorf("failed to read the OpenID configuration, the server returned status %d", res.StatusCode)
+	}
+
+	configuration := struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}{}
+
+	err = json.NewDecoder(res.Body).Decode(&configuration)
+
+	if err != nil {
+		return "", err
+	}
+
+	if configuration.TokenEndpoint == "" {
+		return "", errors.New("the OpenID configuration did not include a token endpoint")
+	}
+
+	return configuration.TokenEndpoint, nil
+}
@@ -0,0 +1,389 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SnapshotVersion is incremented whenever the layout of a snapshot directory changes. A snapshot is only
+// replayed if it was recorded with the same version.
+//
+// A snapshot directory contains:
+//
+//	snapshot.json                        the version, and the server, space and space URL that were recorded
+//	space.json                           the response to GetSpace
+//	resources/<type>/<id>.json           the responses to GetResourceById, including whether the resource was found
+//	collections/<type>/<query>.json      the responses to GetAllResources
+//
+// Which resources are requested by ID depends on the order the export discovers them in, so a replay answers
+// a request for a resource that was not recorded by ID with the matching item of a recorded collection.
+const SnapshotVersion = 1
+
+type snapshotMetadata struct {
+	Version      int
+	Url          string
+	Space        string
+	SpaceBaseUrl string
+}
+
+type snapshotResource struct {
+	Found bool
+	Body  json.RawMessage
+}
+
+// RecordingOctopusClient passes requests to another client, and saves each response to a snapshot directory.
+type RecordingOctopusClient struct {
+	Client    OctopusClient
+	Directory string
+	Url       string
+	Space     string
+	// metadataOnce ensures the snapshot metadata is only updated with the space URL once
+	metadataOnce sync.Once
+	metadataErr  error
+}
+
+// NewRecordingOctopusClient creates a client that records the responses of the supplied client to the directory.
+// The snapshot metadata is written immediately, so the snapshot can be replayed even if the recording makes no
+// requests.
+func NewRecordingOctopusClient(client OctopusClient, directory string, url string, space string) (*RecordingOctopusClient, error) {
+	recorder := &RecordingOctopusClient{Client: client, Directory: directory, Url: url, Space: space}
+	err := recorder.writeMetadata("")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return recorder, nil
+}
+
+func (c *RecordingOctopusClient) GetSpaceBaseUrl(ctx context.Context) (string, error) {
+	spaceBaseUrl, err := c.Client.GetSpaceBaseUrl(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	c.metadataOnce.Do(func() {
+		c.metadataErr = c.writeMetadata(spaceBaseUrl)
+	})
+
+	return spaceBaseUrl, c.metadataErr
+}
+
+func (c *RecordingOctopusClient) writeMetadata(spaceBaseUrl string) error {
+	return writeSnapshotFile(filepath.Join(c.Directory, "snapshot.json"), snapshotMetadata{
+		Version:      SnapshotVersion,
+		Url:          c.Url,
+		Space:        c.Space,
+		SpaceBaseUrl: spaceBaseUrl,
+	})
+}
+
+func (c *RecordingOctopusClient) GetSpace(ctx context.Context, resources *octopus2.Space) error {
+	// GetSpace requires a typed destination, so the space is recorded from the decoded value
+	err := c.Client.GetSpace(ctx, resources)
+
+	if err != nil {
+		return err
+	}
+
+	return writeSnapshotFile(filepath.Join(c.Directory, "space.json"), resources)
+}
+
+func (c *RecordingOctopusClient) GetResourceById(ctx context.Context, resourceType string, id string, resources any) (bool, error) {
+	path, err := snapshotPath(c.Directory, "resources", resourceType, id)
+
+	if err != nil {
+		return false, err
+	}
+
+	var body json.RawMessage
+	found, err := c.Client.GetResourceById(ctx, resourceType, id, &body)
+
+	if err != nil {
+		return false, err
+	}
+
+	if found {
+		err = json.Unmarshal(body, resources)
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return found, writeSnapshotFile(path, snapshotResource{Found: found, Body: body})
+}
+
+func (c *RecordingOctopusClient) GetAllResources(ctx context.Context, resourceType string, resources any, queryParams ...[]string) error {
+	path, err := snapshotPath(c.Directory, "collections", resourceType, queryName(queryParams))
+
+	if err != nil {
+		return err
+	}
+
+	var body json.RawMessage
+	err = c.Client.GetAllResources(ctx, resourceType, &body, queryParams...)
+
+	if err != nil {
+		return err
+	}
+
+	if len(body) != 0 {
+		err = json.Unmarshal(body, resources)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeSnapshotFile(path, body)
+}
+
+// ReplayOctopusClient serves the responses saved by a RecordingOctopusClient without any network access.
+type ReplayOctopusClient struct {
+	Directory string
+	metadata  snapshotMetadata
+	// collectionItems caches the items of the recorded collections of each resource type by their ID
+	collectionItems map[string]map[string]json.RawMessage
+	mu              sync.Mutex
+}
+
+// NewReplayOctopusClient opens the snapshot in the directory, checking it was recorded with a compatible version.
+func NewReplayOctopusClient(directory string) (*ReplayOctopusClient, error) {
+	metadata := snapshotMetadata{}
+	err := readSnapshotFile(filepath.Join(directory, "snapshot.json"), &metadata)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Version != SnapshotVersion {
+		return nil, fmt.Errorf("the snapshot in %s was recorded with version %d, but version %d is required", directory, metadata.Version, SnapshotVersion)
+	}
+
+	return &ReplayOctopusClient{Directory: directory, metadata: metadata, collectionItems: map[string]map[string]json.RawMessage{}}, nil
+}
+
+func (c *ReplayOctopusClient) GetSpaceBaseUrl(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if c.metadata.SpaceBaseUrl == "" {
+		return "", errors.New("the space URL was not recorded in the snapshot: " + c.Directory)
+	}
+
+	return c.metadata.SpaceBaseUrl, nil
+}
+
+func (c *ReplayOctopusClient) GetSpace(ctx context.Context, resources *octopus2.Space) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return readSnapshotFile(filepath.Join(c.Directory, "space.json"), resources)
+}
+
+func (c *ReplayOctopusClient) GetResourceById(ctx context.Context, resourceType string, id string, resources any) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	path, err := snapshotPath(c.Directory, "resources", resourceType, id)
+
+	if err != nil {
+		return false, err
+	}
+
+	if _, statErr := os.Stat(path); errors.Is(statErr, os.ErrNotExist) {
+		items, err := c.getCollectionItems(resourceType)
+
+		if err != nil {
+			return false, err
+		}
+
+		if body, ok := items[id]; ok {
+			return true, json.Unmarshal(body, resources)
+		}
+	}
+
+	resource := snapshotResource{}
+	err = readSnapshotFile(path, &resource)
+
+	if err != nil {
+		return false, err
+	}
+
+	if !resource.Found {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(resource.Body, resources)
+}
+
+func (c *ReplayOctopusClient) GetAllResources(ctx context.Context, resourceType string, resources any, queryParams ...[]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	path, err := snapshotPath(c.Directory, "collections", resourceType, queryName(queryParams))
+
+	if err != nil {
+		return err
+	}
+
+	return readSnapshotFile(path, resources)
+}
+
+// getCollectionItems returns the items of every recorded collection of the resource type by their ID.
+func (c *ReplayOctopusClient) getCollectionItems(resourceType string) (map[string]json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if items, ok := c.collectionItems[resourceType]; ok {
+		return items, nil
+	}
+
+	path, err := snapshotPath(c.Directory, "collections", resourceType, "all")
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	items := map[string]json.RawMessage{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		var body json.RawMessage
+		err = readSnapshotFile(filepath.Join(filepath.Dir(path), entry.Name()), &body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Most collections are a page of items, but some endpoints return a plain array
+		collection := struct {
+			Items []json.RawMessage
+		}{}
+		if json.Unmarshal(body, &collection) != nil {
+			if json.Unmarshal(body, &collection.Items) != nil {
+				continue
+			}
+		}
+
+		for _, item := range collection.Items {
+			resource := struct {
+				Id string
+			}{}
+
+			if json.Unmarshal(item, &resource) == nil && resource.Id != "" {
+				items[resource.Id] = item
+			}
+		}
+	}
+
+	c.collectionItems[resourceType] = items
+	return items, nil
+}
+
+// snapshotPath builds the path of a response file. Resource types like Projects/Projects-1/channels
+// become nested directories.
+func snapshotPath(directory string, kind string, resourceType string, name string) (string, error) {
+	segments := []string{directory, kind}
+
+	for _, segment := range append(strings.Split(resourceType, "/"), name) {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", errors.New("can not build a snapshot path for " + resourceType + " " + name)
+		}
+
+		segments = append(segments, url.PathEscape(segment))
+	}
+
+	return filepath.Join(segments...) + ".json", nil
+}
+
+// queryName builds a file name from the query parameters of a collection request.
+func queryName(queryParams [][]string) string {
+	if len(queryParams) == 0 {
+		return "all"
+	}
+
+	values := make([]string, 0, len(queryParams))
+	for _, q := range queryParams {
+		values = append(values, strings.Join(q, "="))
+	}
+
+	return strings.Join(values, "&")
+}
+
+// writeSnapshotFile saves the value as indented JSON. The file is written to a temporary file and renamed,
+// so concurrent requests for the same resource never leave a partially written file.
+func writeSnapshotFile(path string, value any) error {
+	body, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	indented := bytes.Buffer{}
+	if json.Indent(&indented, body, "", "  ") == nil {
+		body = indented.Bytes()
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(body)
+	closeErr := file.Close()
+
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func readSnapshotFile(path string, value any) error {
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return errors.New("the response was not recorded in the snapshot: " + path)
+		}
+		return err
+	}
+
+	return json.Unmarshal(body, value)
+}
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
+)
+
+// createSpaceServer returns a server with a single space holding two environments.
+func createSpaceServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/Spaces":
+			w.Write([]byte(`{"Items": [{"Id": "Spaces-1", "Name": "Default"}]}`))
+		case "/api/Spaces/Spaces-1":
+			w.Write([]byte(`{"Id": "Spaces-1", "Name": "Default"}`))
+		case "/api/Spaces-1/Environments":
+			w.Write([]byte(`{"Items": [{"Id": "Environments-1", "Name": "Dev"}, {"Id": "Environments-2", "Name": "Test"}]}`))
+		case "/api/Spaces-1/Environments/Environments-1":
+			w.Write([]byte(`{"Id": "Environments-1", "Name": "Dev"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// queryClient makes the same requests a converter would, returning a summary of the responses.
+func queryClient(t *testing.T, client OctopusClient) []string {
+	ctx := context.Background()
+	results := []string{}
+
+	baseUrl, err := client.GetSpaceBaseUrl(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results = append(results, baseUrl)
+
+	space := octopus2.Space{}
+	if err := client.GetSpace(ctx, &space); err != nil {
+		t.Fatal(err)
+	}
+	results = append(results, space.Id)
+
+	collection := octopus2.GeneralCollection[octopus2.Environment]{}
+	if err := client.GetAllResources(ctx, "Environments", &collection); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range collection.Items {
+		results = append(results, e.Id+" "+e.Name)
+	}
+
+	environment := octopus2.Environment{}
+	found, err := client.GetResourceById(ctx, "Environments", "Environments-1", &environment)
+	if err != nil || !found {
+		t.Fatalf("expected to find Environments-1, found %v, error %v", found, err)
+	}
+	results = append(results, environment.Name)
+
+	found, err = client.GetResourceById(ctx, "Environments", "Environments-3", &environment)
+	if err != nil || found {
+		t.Fatalf("expected Environments-3 to be missing, found %v, error %v", found, err)
+	}
+
+	return results
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server := createSpaceServer(t)
+	directory := t.TempDir()
+
+	live := OctopusApiClient{Url: server.URL, Space: "Spaces-1"}
+	recorder, err := NewRecordingOctopusClient(live, directory, server.URL, "Spaces-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := queryClient(t, recorder)
+
+	// The replay must not need the server
+	server.Close()
+
+	replay, err := NewReplayOctopusClient(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := queryClient(t, replay)
+
+	if len(recorded) != len(replayed) {
+		t.Fatalf("recorded %v but replayed %v", recorded, replayed)
+	}
+
+	for i := range recorded {
+		if recorded[i] != replayed[i] {
+			t.Fatalf("recorded %v but replayed %v", recorded, replayed)
+		}
+	}
+
+	collection := octopus2.GeneralCollection[octopus2.Environment]{}
+	if err := replay.GetAllResources(context.Background(), "Projects", &collection); err == nil {
+		t.Fatal("expected an error for a request that was not recorded")
+	}
+}
+
+func TestReplayWithoutSpaceUrl(t *testing.T) {
+	server := createSpaceServer(t)
+	directory := t.TempDir()
+
+	// A recording that never asks for the space URL, like a dry run, must still be replayable
+	live := OctopusApiClient{Url: server.URL, Space: "Spaces-1"}
+	recorder, err := NewRecordingOctopusClient(live, directory, server.URL, "Spaces-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collection := octopus2.GeneralCollection[octopus2.Environment]{}
+	if err := recorder.GetAllResources(context.Background(), "Environments", &collection); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Close()
+
+	replay, err := NewReplayOctopusClient(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := octopus2.GeneralCollection[octopus2.Environment]{}
+	if err := replay.GetAllResources(context.Background(), "Environments", &replayed); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(replayed.Items) != len(collection.Items) {
+		t.Fatalf("recorded %v but replayed %v", collection.Items, replayed.Items)
+	}
+
+	if _, err := replay.GetSpaceBaseUrl(context.Background()); err == nil {
+		t.Fatal("expected an error for a space URL that was not recorded")
+	}
+}
+
+func TestReplayResourceFromCollection(t *testing.T) {
+	server := createSpaceServer(t)
+	directory := t.TempDir()
+
+	// The environments are only recorded as a collection, like an export that found them before asking for them by ID
+	live := OctopusApiClient{Url: server.URL, Space: "Spaces-1"}
+	recorder, err := NewRecordingOctopusClient(live, directory, server.URL, "Spaces-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collection := octopus2.GeneralCollection[octopus2.Environment]{}
+	if err := recorder.GetAllResources(context.Background(), "Environments", &collection); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Close()
+
+	replay, err := NewReplayOctopusClient(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	environment := octopus2.Environment{}
+	found, err := replay.GetResourceById(context.Background(), "Environments", "Environments-2", &environment)
+	if err != nil || !found || environment.Name != "Test" {
+		t.Fatalf("expected to replay Environments-2 from the collection, found %v, name %s, error %v", found, environment.Name, err)
+	}
+
+	if _, err := replay.GetResourceById(context.Background(), "Environments", "Environments-3", &environment); err == nil {
+		t.Fatal("expected an error for a resource that was not recorded")
+	}
+}
+
+func TestSnapshotPath(t *testing.T) {
+	path, err := snapshotPath("snapshot", "collections", "Projects/Projects-1/channels", queryName([][]string{{"partialName", "a b"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "snapshot/collections/Projects/Projects-1/channels/partialName=a%20b.json" {
+		t.Fatalf("unexpected path %s", path)
+	}
+
+	if _, err := snapshotPath("snapshot", "resources", "Projects", ".."); err == nil {
+		t.Fatal("expected an error for a path that escapes the snapshot")
+	}
+}
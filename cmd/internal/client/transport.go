@@ -0,0 +1,85 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// TransportOptions configures how the client connects to Octopus from locked down networks.
+type TransportOptions struct {
+	// CaBundle is a PEM file with additional certificate authorities trusted alongside the system roots.
+	CaBundle string
+	// ClientCertificate and ClientKey are PEM files presented to the server for mutual TLS.
+	ClientCertificate string
+	ClientKey         string
+	// Proxy is the URL of the HTTP(S) proxy. When empty the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// environment variables are used.
+	Proxy string
+	// InsecureSkipVerify disables the verification of the server certificate.
+	InsecureSkipVerify bool
+}
+
+// NewHttpClient builds a client whose transport applies the supplied options.
+func NewHttpClient(options TransportOptions) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+
+	if options.CaBundle != "" {
+		pool, err := x509.SystemCertPool()
+
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		pem, err := os.ReadFile(options.CaBundle)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("did not find any certificates in " + options.CaBundle)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if options.ClientCertificate != "" || options.ClientKey != "" {
+		if options.ClientCertificate == "" || options.ClientKey == "" {
+			return nil, errors.New("both a client certificate and a client key are required for mutual TLS")
+		}
+
+		certificate, err := tls.LoadX509KeyPair(options.ClientCertificate, options.ClientKey)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	if options.Proxy != "" {
+		proxyUrl, err := url.Parse(options.Proxy)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if proxyUrl.Scheme == "" || proxyUrl.Host == "" {
+			return nil, errors.New("the proxy must be a URL like http://proxy:3128, but was " + options.Proxy)
+		}
+
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
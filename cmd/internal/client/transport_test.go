@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// createTlsServer returns a TLS server that answers the space lookup, recording the custom header.
+func createTlsServer(t *testing.T, header *string) *httptest.Server {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*header = r.Header.Get("X-Custom")
+		w.Write([]byte(`{"Items": [{"Id": "Spaces-1", "Name": "Default"}]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCaBundle(t *testing.T) {
+	header := ""
+	server := createTlsServer(t, &header)
+
+	caBundle := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caBundle, certificate, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	httpClient, err := NewHttpClient(TransportOptions{CaBundle: caBundle})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := OctopusApiClient{Url: server.URL, Space: "Default", HttpClient: httpClient, Headers: map[string]string{"X-Custom": "value"}}
+	if _, err := client.GetSpaceBaseUrl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if header != "value" {
+		t.Fatalf("expected the custom header to be sent, got %q", header)
+	}
+}
+
+func TestUntrustedCertificate(t *testing.T) {
+	header := ""
+	server := createTlsServer(t, &header)
+
+	httpClient, err := NewHttpClient(TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := OctopusApiClient{Url: server.URL, Space: "Default", HttpClient: httpClient}
+	if _, err := client.GetSpaceBaseUrl(context.Background()); err == nil {
+		t.Fatal("expected the self signed certificate to be rejected")
+	}
+
+	httpClient, err = NewHttpClient(TransportOptions{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.HttpClient = httpClient
+	if _, err := client.GetSpaceBaseUrl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	proxied := ""
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Write([]byte(`{"Items": [{"Id": "Spaces-1", "Name": "Default"}]}`))
+	}))
+	t.Cleanup(proxy.Close)
+
+	httpClient, err := NewHttpClient(TransportOptions{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := OctopusApiClient{Url: "http://octopus.example", Space: "Default", HttpClient: httpClient}
+	if _, err := client.GetSpaceBaseUrl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if proxied != "http://octopus.example/api/Spaces?take=1000&partialName=Default" {
+		t.Fatalf("expected the request to be sent through the proxy, got %q", proxied)
+	}
+}
+
+func TestInvalidTransportOptions(t *testing.T) {
+	if _, err := NewHttpClient(TransportOptions{ClientCertificate: "cert.pem"}); err == nil {
+		t.Fatal("expected an error when the client key is missing")
+	}
+
+	if _, err := NewHttpClient(TransportOptions{Proxy: "proxy:3128"}); err == nil {
+		t.Fatal("expected an error for a proxy without a scheme")
+	}
+}
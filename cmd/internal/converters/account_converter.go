@@ -1,15 +1,12 @@
 package converters
 
 import (
+	"context"
 	"errors"
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
 )
 
@@ -19,55 +16,63 @@ type AccountConverter struct {
 	TenantConverter      ConverterById
 }
 
-func (c AccountConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c AccountConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.Account]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, resource := range collection.Items {
-		err = c.toHcl(resource, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		resource := resource
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), resource.Id, c.toHcl(ctx, resource, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c AccountConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c AccountConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	resource := octopus2.Account{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &resource)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &resource)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.toHcl(resource, true, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, resource, true, dependencies))
 }
 
-func (c AccountConverter) toHcl(resource octopus2.Account, recursive bool, dependencies *ResourceDetailsCollection) error {
+func (c AccountConverter) toHcl(ctx context.Context, resource octopus2.Account, recursive bool, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, resource.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
 	if recursive {
-		c.exportDependencies(resource, dependencies)
+		c.exportDependencies(ctx, resource, dependencies)
 	}
 
-	resourceName := "account_" + sanitizer.SanitizeName(resource.Name)
+	resourceName := dependencies.ResourceName("account_", c.GetResourceType(), resource.Id, resource.Name)
 
 	thisResource := ResourceDetails{}
 
 	thisResource.FileName = "space_population/" + resourceName + ".tf"
 	thisResource.Id = resource.Id
+	thisResource.Name = resource.Name
 	thisResource.ResourceType = c.GetResourceType()
 	if resource.AccountType == "AmazonWebServicesAccount" {
 		thisResource.Lookup = "${octopusdeploy_aws_account." + resourceName + ".id}"
@@ -110,24 +115,20 @@ func (c AccountConverter) toHcl(resource octopus2.Account, recursive bool, depen
 				Description: "The AWS secret key associated with the account " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_aws_account." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_aws_account." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if resource.AccountType == "AzureServicePrincipal" {
@@ -157,24 +158,20 @@ func (c AccountConverter) toHcl(resource octopus2.Account, recursive bool, depen
 				Description: "The Azure secret associated with the account " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_azure_service_principal." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_azure_service_principal." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if resource.AccountType == "AzureSubscription" {
@@ -203,24 +200,20 @@ func (c AccountConverter) toHcl(resource octopus2.Account, recursive bool, depen
 				Description: "The Azure certificate associated with the account " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_azure_subscription_account." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_azure_subscription_account." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if resource.AccountType == "GoogleCloudAccount" {
@@ -245,24 +238,20 @@ func (c AccountConverter) toHcl(resource octopus2.Account, recursive bool, depen
 				Description: "The GCP JSON key associated with the account " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_gcp_account." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_gcp_account." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if resource.AccountType == "Token" {
@@ -287,24 +276,20 @@ func (c AccountConverter) toHcl(resource octopus2.Account, recursive bool, depen
 				Description: "The token associated with the account " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_token_account." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_token_account." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if resource.AccountType == "UsernamePassword" {
@@ -330,24 +315,20 @@ func (c AccountConverter) toHcl(resource octopus2.Account, recursive bool, depen
 				Description: "The password associated with the account " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_username_password_account." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_username_password_account." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if resource.AccountType == "SshKeyPair" {
@@ -383,28 +364,24 @@ func (c AccountConverter) toHcl(resource octopus2.Account, recursive bool, depen
 				Description: "The certificate file for account " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_ssh_key_account." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_ssh_key_account." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			certFileVariableResourceBlock := gohcl.EncodeAsBlock(certFileVariableResource, "variable")
+			certFileVariableResourceBlock := hcl.EncodeAsBlock(certFileVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(certFileVariableResourceBlock, "type", "string")
-			file.Body().AppendBlock(certFileVariableResourceBlock)
+			file.AppendBlock(certFileVariableResourceBlock)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		return "", errors.New("found unsupported account type")
@@ -418,11 +395,11 @@ func (c AccountConverter) GetResourceType() string {
 	return "Accounts"
 }
 
-func (c AccountConverter) exportDependencies(target octopus2.Account, dependencies *ResourceDetailsCollection) error {
+func (c AccountConverter) exportDependencies(ctx context.Context, target octopus2.Account, dependencies *ResourceDetailsCollection) error {
 
 	// Export the environments
 	for _, e := range target.EnvironmentIds {
-		err := c.EnvironmentConverter.ToHclById(e, dependencies)
+		err := c.EnvironmentConverter.ToHclById(ctx, e, dependencies)
 
 		if err != nil {
 			return err
@@ -431,7 +408,7 @@ func (c AccountConverter) exportDependencies(target octopus2.Account, dependenci
 
 	// Export the tenants
 	for _, e := range target.TenantIds {
-		err := c.TenantConverter.ToHclById(e, dependencies)
+		err := c.TenantConverter.ToHclById(ctx, e, dependencies)
 
 		if err != nil {
 			return err
@@ -1,14 +1,11 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 )
 
 type AzureServiceFabricTargetConverter struct {
@@ -17,61 +14,68 @@ type AzureServiceFabricTargetConverter struct {
 	EnvironmentConverter   ConverterById
 }
 
-func (c AzureServiceFabricTargetConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c AzureServiceFabricTargetConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.AzureServiceFabricResource]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, resource := range collection.Items {
-		err = c.toHcl(resource, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		resource := resource
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), resource.Id, c.toHcl(ctx, resource, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c AzureServiceFabricTargetConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c AzureServiceFabricTargetConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	resource := octopus2.AzureServiceFabricResource{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &resource)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &resource)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.toHcl(resource, true, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, resource, true, dependencies))
 }
 
-func (c AzureServiceFabricTargetConverter) toHcl(target octopus2.AzureServiceFabricResource, recursive bool, dependencies *ResourceDetailsCollection) error {
+func (c AzureServiceFabricTargetConverter) toHcl(ctx context.Context, target octopus2.AzureServiceFabricResource, recursive bool, dependencies *ResourceDetailsCollection) error {
 
 	if target.Endpoint.CommunicationStyle == "AzureServiceFabricCluster" {
+		ctx, claimed := dependencies.ClaimResource(ctx, target.Id, c.GetResourceType())
+		if !claimed {
+			return nil
+		}
 
 		if recursive {
-			err := c.exportDependencies(target, dependencies)
+			err := c.exportDependencies(ctx, target, dependencies)
 
 			if err != nil {
 				return err
 			}
 		}
 
-		targetName := "target_" + sanitizer.SanitizeName(target.Name)
+		targetName := dependencies.ResourceName("target_", c.GetResourceType(), target.Id, target.Name)
 
 		thisResource := ResourceDetails{}
 		thisResource.FileName = "space_population/" + targetName + ".tf"
 		thisResource.Id = target.Id
+		thisResource.Name = target.Name
 		thisResource.ResourceType = c.GetResourceType()
 		thisResource.Lookup = "${octopusdeploy_azure_service_fabric_cluster_deployment_target." + targetName + ".id}"
 		thisResource.ToHcl = func() (string, error) {
@@ -110,19 +114,15 @@ func (c AzureServiceFabricTargetConverter) toHcl(target octopus2.AzureServiceFab
 				Uri:                             nil,
 				Endpoint:                        nil,
 			}
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + target.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_azure_service_fabric_cluster_deployment_target." + targetName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + target.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_azure_service_fabric_cluster_deployment_target." + targetName + " ${RESOURCE_ID}\n")
 
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
 
 			secretVariableResource := terraform2.TerraformVariable{
 				Name:        targetName,
@@ -132,11 +132,11 @@ func (c AzureServiceFabricTargetConverter) toHcl(target octopus2.AzureServiceFab
 				Description: "The aad_user_credential_password value associated with the target \"" + target.Name + "\"",
 			}
 
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		dependencies.AddResource(thisResource)
@@ -175,10 +175,10 @@ func (c AzureServiceFabricTargetConverter) getWorkerPool(pool string, dependenci
 	return &machineLookup
 }
 
-func (c AzureServiceFabricTargetConverter) exportDependencies(target octopus2.AzureServiceFabricResource, dependencies *ResourceDetailsCollection) error {
+func (c AzureServiceFabricTargetConverter) exportDependencies(ctx context.Context, target octopus2.AzureServiceFabricResource, dependencies *ResourceDetailsCollection) error {
 
 	// The machine policies need to be exported
-	err := c.MachinePolicyConverter.ToHclById(target.MachinePolicyId, dependencies)
+	err := c.MachinePolicyConverter.ToHclById(ctx, target.MachinePolicyId, dependencies)
 
 	if err != nil {
 		return err
@@ -186,7 +186,7 @@ func (c AzureServiceFabricTargetConverter) exportDependencies(target octopus2.Az
 
 	// Export the environments
 	for _, e := range target.EnvironmentIds {
-		err = c.EnvironmentConverter.ToHclById(e, dependencies)
+		err = c.EnvironmentConverter.ToHclById(ctx, e, dependencies)
 
 		if err != nil {
 			return err
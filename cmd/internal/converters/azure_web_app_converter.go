@@ -1,13 +1,11 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 )
 
 type AzureWebAppTargetConverter struct {
@@ -17,61 +15,68 @@ type AzureWebAppTargetConverter struct {
 	EnvironmentConverter   ConverterById
 }
 
-func (c AzureWebAppTargetConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c AzureWebAppTargetConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.AzureWebAppResource]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, resource := range collection.Items {
-		err = c.toHcl(resource, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		resource := resource
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), resource.Id, c.toHcl(ctx, resource, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c AzureWebAppTargetConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c AzureWebAppTargetConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	resource := octopus2.AzureWebAppResource{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &resource)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &resource)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.toHcl(resource, true, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, resource, true, dependencies))
 }
 
-func (c AzureWebAppTargetConverter) toHcl(target octopus2.AzureWebAppResource, recursive bool, dependencies *ResourceDetailsCollection) error {
+func (c AzureWebAppTargetConverter) toHcl(ctx context.Context, target octopus2.AzureWebAppResource, recursive bool, dependencies *ResourceDetailsCollection) error {
 
 	if target.Endpoint.CommunicationStyle == "AzureWebApp" {
+		ctx, claimed := dependencies.ClaimResource(ctx, target.Id, c.GetResourceType())
+		if !claimed {
+			return nil
+		}
 
 		if recursive {
-			err := c.exportDependencies(target, dependencies)
+			err := c.exportDependencies(ctx, target, dependencies)
 
 			if err != nil {
 				return err
 			}
 		}
 
-		targetName := "target_" + sanitizer.SanitizeName(target.Name)
+		targetName := dependencies.ResourceName("target_", c.GetResourceType(), target.Id, target.Name)
 
 		thisResource := ResourceDetails{}
 		thisResource.FileName = "space_population/" + targetName + ".tf"
 		thisResource.Id = target.Id
+		thisResource.Name = target.Name
 		thisResource.ResourceType = c.GetResourceType()
 		thisResource.Lookup = "${octopusdeploy_azure_web_app_deployment_target." + targetName + ".id}"
 		thisResource.ToHcl = func() (string, error) {
@@ -105,21 +110,17 @@ func (c AzureWebAppTargetConverter) toHcl(target octopus2.AzureWebAppResource, r
 					CommunicationStyle:  "AzureWebApp",
 				},
 			}
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + target.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_azure_web_app_deployment_target." + targetName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-			return string(file.Bytes()), nil
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + target.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_azure_web_app_deployment_target." + targetName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+			return file.Render(dependencies.Format)
 		}
 
 		dependencies.AddResource(thisResource)
@@ -167,17 +168,17 @@ func (c AzureWebAppTargetConverter) getWorkerPool(pool string, dependencies *Res
 	return &machineLookup
 }
 
-func (c AzureWebAppTargetConverter) exportDependencies(target octopus2.AzureWebAppResource, dependencies *ResourceDetailsCollection) error {
+func (c AzureWebAppTargetConverter) exportDependencies(ctx context.Context, target octopus2.AzureWebAppResource, dependencies *ResourceDetailsCollection) error {
 
 	// The machine policies need to be exported
-	err := c.MachinePolicyConverter.ToHclById(target.MachinePolicyId, dependencies)
+	err := c.MachinePolicyConverter.ToHclById(ctx, target.MachinePolicyId, dependencies)
 
 	if err != nil {
 		return err
 	}
 
 	// Export the accounts
-	err = c.AccountConverter.ToHclById(target.Endpoint.AccountId, dependencies)
+	err = c.AccountConverter.ToHclById(ctx, target.Endpoint.AccountId, dependencies)
 
 	if err != nil {
 		return err
@@ -185,7 +186,7 @@ func (c AzureWebAppTargetConverter) exportDependencies(target octopus2.AzureWebA
 
 	// Export the environments
 	for _, e := range target.EnvironmentIds {
-		err = c.EnvironmentConverter.ToHclById(e, dependencies)
+		err = c.EnvironmentConverter.ToHclById(ctx, e, dependencies)
 
 		if err != nil {
 			return err
@@ -1,70 +1,75 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 )
 
 type CertificateConverter struct {
 	Client client.OctopusClient
 }
 
-func (c CertificateConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c CertificateConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.Certificate]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, resource := range collection.Items {
-		err = c.toHcl(resource, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		resource := resource
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), resource.Id, c.toHcl(ctx, resource, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c CertificateConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c CertificateConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	certificate := octopus2.Certificate{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &certificate)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &certificate)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.toHcl(certificate, true, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, certificate, true, dependencies))
 }
 
-func (c CertificateConverter) toHcl(certificate octopus2.Certificate, recursive bool, dependencies *ResourceDetailsCollection) error {
+func (c CertificateConverter) toHcl(ctx context.Context, certificate octopus2.Certificate, recursive bool, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, certificate.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
 	/*
 		Note we don't export the tenants or environments that this certificate might be exposed to.
 		It is assumed the exported project links up all required environments, and the certificate
 		will link itself to any available environments or tenants.
 	*/
 
-	certificateName := "certificate_" + sanitizer.SanitizeName(certificate.Name)
+	certificateName := dependencies.ResourceName("certificate_", c.GetResourceType(), certificate.Id, certificate.Name)
 
 	thisResource := ResourceDetails{}
 	thisResource.FileName = "space_population/" + certificateName + ".tf"
 	thisResource.Id = certificate.Id
+	thisResource.Name = certificate.Name
 	thisResource.ResourceType = c.GetResourceType()
 	thisResource.Lookup = "${octopusdeploy_certificate." + certificateName + ".id}"
 	thisResource.ToHcl = func() (string, error) {
@@ -100,19 +105,15 @@ func (c CertificateConverter) toHcl(certificate octopus2.Certificate, recursive
 			//Thumbprint:                      certificate.Thumbprint,
 			//Version:                         certificate.Version,
 		}
-		file := hclwrite.NewEmptyFile()
+		file := hcl.NewFile()
 
 		// Add a comment with the import command
-		baseUrl, _ := c.Client.GetSpaceBaseUrl()
-		file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-			Type: hclsyntax.TokenComment,
-			Bytes: []byte("# Import existing resources with the following commands:\n" +
-				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + certificate.Name + "\") | .Id')\n" +
-				"# terraform import octopusdeploy_certificate." + certificateName + " ${RESOURCE_ID}\n"),
-			SpacesBefore: 0,
-		}})
+		baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+		file.AppendComment("# Import existing resources with the following commands:\n" +
+			"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + certificate.Name + "\") | .Id')\n" +
+			"# terraform import octopusdeploy_certificate." + certificateName + " ${RESOURCE_ID}\n")
 
-		file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
+		file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
 
 		defaultPassword := ""
 		certificatePassword := terraform2.TerraformVariable{
@@ -124,9 +125,9 @@ func (c CertificateConverter) toHcl(certificate octopus2.Certificate, recursive
 			Default:     &defaultPassword,
 		}
 
-		block := gohcl.EncodeAsBlock(certificatePassword, "variable")
+		block := hcl.EncodeAsBlock(certificatePassword, "variable")
 		hcl.WriteUnquotedAttribute(block, "type", "string")
-		file.Body().AppendBlock(block)
+		file.AppendBlock(block)
 
 		certificateData := terraform2.TerraformVariable{
 			Name:        certificateName + "_data",
@@ -136,11 +137,11 @@ func (c CertificateConverter) toHcl(certificate octopus2.Certificate, recursive
 			Description: "The certificate data used by the certificate " + certificate.Name,
 		}
 
-		block = gohcl.EncodeAsBlock(certificateData, "variable")
+		block = hcl.EncodeAsBlock(certificateData, "variable")
 		hcl.WriteUnquotedAttribute(block, "type", "string")
-		file.Body().AppendBlock(block)
+		file.AppendBlock(block)
 
-		return string(file.Bytes()), nil
+		return file.Render(dependencies.Format)
 	}
 
 	dependencies.AddResource(thisResource)
@@ -1,14 +1,12 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
+	"sort"
 	"strings"
 )
 
@@ -17,16 +15,16 @@ type ChannelConverter struct {
 	LifecycleConverter ConverterById
 }
 
-func (c ChannelConverter) ToHclByProjectIdWithTerraDependencies(projectId string, terraformDependencies map[string]string, dependencies *ResourceDetailsCollection) error {
+func (c ChannelConverter) ToHclByProjectIdWithTerraDependencies(ctx context.Context, projectId string, terraformDependencies map[string]string, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.Channel]{}
-	err := c.Client.GetAllResources(c.GetGroupResourceType(projectId), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetGroupResourceType(projectId), &collection)
 
 	if err != nil {
 		return err
 	}
 
 	for _, channel := range collection.Items {
-		err = c.toHcl(channel, true, terraformDependencies, dependencies)
+		err = c.toHcl(ctx, channel, true, terraformDependencies, dependencies)
 
 		if err != nil {
 			return err
@@ -36,10 +34,15 @@ func (c ChannelConverter) ToHclByProjectIdWithTerraDependencies(projectId string
 	return nil
 }
 
-func (c ChannelConverter) toHcl(channel octopus2.Channel, recursive bool, terraformDependencies map[string]string, dependencies *ResourceDetailsCollection) error {
+func (c ChannelConverter) toHcl(ctx context.Context, channel octopus2.Channel, recursive bool, terraformDependencies map[string]string, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, channel.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
 	if recursive && channel.LifecycleId != "" {
 		// The lifecycle is a dependency that we need to lookup
-		err := c.LifecycleConverter.ToHclById(channel.LifecycleId, dependencies)
+		err := c.LifecycleConverter.ToHclById(ctx, channel.LifecycleId, dependencies)
 
 		if err != nil {
 			return err
@@ -47,9 +50,10 @@ func (c ChannelConverter) toHcl(channel octopus2.Channel, recursive bool, terraf
 	}
 
 	thisResource := ResourceDetails{}
-	resourceName := "channel_" + sanitizer.SanitizeNamePointer(&channel.Name)
+	resourceName := dependencies.ResourceName("channel_", c.GetResourceType(), channel.Id, channel.Name)
 	thisResource.FileName = "space_population/" + resourceName + ".tf"
 	thisResource.Id = channel.Id
+	thisResource.Name = channel.Name
 	thisResource.ResourceType = c.GetResourceType()
 
 	if channel.Name == "Default" {
@@ -64,10 +68,10 @@ func (c ChannelConverter) toHcl(channel octopus2.Channel, recursive bool, terraf
 				Skip:        0,
 				Take:        1,
 			}
-			file := hclwrite.NewEmptyFile()
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(data, "data"))
+			file := hcl.NewFile()
+			file.AppendBlock(hcl.EncodeAsBlock(data, "data"))
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 	} else {
 		thisResource.Lookup = "${octopusdeploy_channel." + resourceName + ".id}"
@@ -83,19 +87,15 @@ func (c ChannelConverter) toHcl(channel octopus2.Channel, recursive bool, terraf
 				Rule:         c.convertRules(channel.Rules),
 				TenantTags:   channel.TenantTags,
 			}
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + channel.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_channel." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + channel.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_channel." + resourceName + " ${RESOURCE_ID}\n")
 
-			block := gohcl.EncodeAsBlock(terraformResource, "resource")
+			block := hcl.EncodeAsBlock(terraformResource, "resource")
 
 			/* Channels reference steps and packages by text without terraform understanding
 			there is any relationship. In order for the channel to be created after the deployment process,
@@ -109,10 +109,11 @@ func (c ChannelConverter) toHcl(channel octopus2.Channel, recursive bool, terraf
 					manualDependencies = append(manualDependencies, dependency)
 				}
 			}
+			sort.Strings(manualDependencies)
 			hcl.WriteUnquotedAttribute(block, "depends_on", "["+strings.Join(manualDependencies[:], ",")+"]")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 	}
 	dependencies.AddResource(thisResource)
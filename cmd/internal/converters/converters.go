@@ -1,46 +1,48 @@
 package converters
 
+import "context"
+
 // ConverterById converts an individual resource by its ID
 type ConverterById interface {
-	ToHclById(id string, dependencies *ResourceDetailsCollection) error
+	ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error
 }
 
 // ConverterByIdWithName converts an individual resource by its ID, and uses the supplied name for the Terraform resource
 type ConverterByIdWithName interface {
-	ToHclByIdAndName(id string, name string, dependencies *ResourceDetailsCollection) error
+	ToHclByIdAndName(ctx context.Context, id string, name string, dependencies *ResourceDetailsCollection) error
 }
 
 // ConverterByIdWithNameAndParent converts a resource by its ID, uses the supplied name, and has a reference to its parent
 type ConverterByIdWithNameAndParent interface {
-	ToHclByIdAndName(id string, name string, parentLookup string, dependencies *ResourceDetailsCollection) error
+	ToHclByIdAndName(ctx context.Context, id string, name string, parentLookup string, dependencies *ResourceDetailsCollection) error
 }
 
 // ConverterByProjectIdWithName converts objects based on their relationship to a project, and uses the supplied name for the Terraform resource
 type ConverterByProjectIdWithName interface {
-	ToHclByProjectIdAndName(id string, name string, dependencies *ResourceDetailsCollection) error
+	ToHclByProjectIdAndName(ctx context.Context, id string, name string, dependencies *ResourceDetailsCollection) error
 }
 
 // ConverterByProjectId converts objects based on their relationship to a project
 type ConverterByProjectId interface {
-	ToHclByProjectId(projectId string, dependencies *ResourceDetailsCollection) error
+	ToHclByProjectId(ctx context.Context, projectId string, dependencies *ResourceDetailsCollection) error
 }
 
 // ConverterByProjectIdWithTerraDependencies converts objects based on their relationship to a project, with manual terraform dependencies
 type ConverterByProjectIdWithTerraDependencies interface {
-	ToHclByProjectIdWithTerraDependencies(projectId string, terraformDependencies map[string]string, dependencies *ResourceDetailsCollection) error
+	ToHclByProjectIdWithTerraDependencies(ctx context.Context, projectId string, terraformDependencies map[string]string, dependencies *ResourceDetailsCollection) error
 }
 
 // ConverterByTenantId converts objects based on the relationship to a tenant
 type ConverterByTenantId interface {
-	ToHclByTenantId(projectId string, dependencies *ResourceDetailsCollection) error
+	ToHclByTenantId(ctx context.Context, projectId string, dependencies *ResourceDetailsCollection) error
 }
 
 // ConvertToHclByResource converts objects directly
 type ConvertToHclByResource[C any] interface {
-	ToHclByResource(resource C, dependencies *ResourceDetailsCollection) error
+	ToHclByResource(ctx context.Context, resource C, dependencies *ResourceDetailsCollection) error
 }
 
 // Converter converts all objects in bulk
 type Converter interface {
-	ToHcl(dependencies *ResourceDetailsCollection) error
+	ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error
 }
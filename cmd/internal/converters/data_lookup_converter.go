@@ -0,0 +1,155 @@
+package converters
+
+import (
+	"context"
+	"errors"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
+	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
+)
+
+// dataSources maps the resource types that can be looked up by name to their data source, and the
+// attribute of the data source holding the matching resources.
+var dataSources = map[string]struct {
+	dataType  string
+	attribute string
+}{
+	"Accounts":            {"octopusdeploy_accounts", "accounts"},
+	"Certificates":        {"octopusdeploy_certificates", "certificates"},
+	"Channels":            {"octopusdeploy_channels", "channels"},
+	"Environments":        {"octopusdeploy_environments", "environments"},
+	"Feeds":               {"octopusdeploy_feeds", "feeds"},
+	"LibraryVariableSets": {"octopusdeploy_library_variable_sets", "library_variable_sets"},
+	"Lifecycles":          {"octopusdeploy_lifecycles", "lifecycles"},
+	"MachinePolicies":     {"octopusdeploy_machine_policies", "machine_policies"},
+	"ProjectGroups":       {"octopusdeploy_project_groups", "project_groups"},
+	"Projects":            {"octopusdeploy_projects", "projects"},
+	"Tenants":             {"octopusdeploy_tenants", "tenants"},
+	"WorkerPools":         {"octopusdeploy_worker_pools", "worker_pools"},
+}
+
+// lookupTake is the maximum number of resources returned by a data source that finds a resource by name. The
+// partial name can match other resources, like Pre-Production for Production, so every match is returned and
+// the results are filtered to the exact name.
+const lookupTake = 10000
+
+// DataLookupConverter replaces dependencies that were not exported with data sources that find the
+// existing resource by name. This allows the exported resources to reference resources that are managed
+// outside of the exported Terraform configuration. When ResourceType is set, the converter can also be used
+// in place of the converter for that resource type, so the dependencies are looked up rather than exported.
+type DataLookupConverter struct {
+	Client       client.OctopusClient
+	ResourceType string
+}
+
+// ToHclById adds a data source that looks up the resource of the ResourceType by name.
+func (c DataLookupConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
+	if id == "" {
+		return nil
+	}
+
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
+	if dependencies.HasResource(id, c.GetResourceType()) {
+		return nil
+	}
+
+	if _, ok := dataSources[c.GetResourceType()]; !ok {
+		return dependencies.RecordError(c.GetResourceType(), id,
+			errors.New("the resource type "+c.GetResourceType()+" can not be looked up by name"))
+	}
+
+	resource := octopus2.NamedResource{}
+	found, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &resource)
+
+	if err != nil {
+		return dependencies.RecordError(c.GetResourceType(), id, err)
+	}
+
+	if !found {
+		return nil
+	}
+
+	if _, claimed := dependencies.ClaimResource(ctx, resource.Id, c.GetResourceType()); !claimed {
+		return nil
+	}
+
+	c.toHcl(c.GetResourceType(), resource, dependencies)
+	return nil
+}
+
+func (c DataLookupConverter) GetResourceType() string {
+	return c.ResourceType
+}
+
+// ToHclByUnresolvedReferences adds a data source for each resource that was referenced but not exported.
+// References to resources that can not be looked up by name, or that no longer exist, are ignored and
+// remain unresolved.
+func (c DataLookupConverter) ToHclByUnresolvedReferences(ctx context.Context, references []UnresolvedReference, dependencies *ResourceDetailsCollection) error {
+	for _, reference := range references {
+		if _, ok := dataSources[reference.ResourceType]; !ok {
+			continue
+		}
+
+		if dependencies.HasResource(reference.Id, reference.ResourceType) {
+			continue
+		}
+
+		resource := octopus2.NamedResource{}
+		found, err := c.Client.GetResourceById(ctx, reference.ResourceType, reference.Id, &resource)
+
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			continue
+		}
+
+		c.toHcl(reference.ResourceType, resource, dependencies)
+	}
+
+	return nil
+}
+
+func (c DataLookupConverter) toHcl(resourceType string, resource octopus2.NamedResource, dependencies *ResourceDetailsCollection) {
+	resourceName := dependencies.ResourceName("lookup_", resourceType, resource.Id, resourceType+"_"+resource.Name)
+
+	thisResource := ResourceDetails{}
+	thisResource.FileName = "space_population/" + resourceName + ".tf"
+	thisResource.Id = resource.Id
+	thisResource.Name = resource.Name
+	thisResource.ResourceType = resourceType
+	thisResource.Lookup = "${local." + resourceName + "_id}"
+	thisResource.ToHcl = func() (string, error) {
+		data, id := exactNameLookup(resourceType, resourceName, resource.Name)
+		locals := hcl.EncodeAsBlock(terraform.TerraformLocals{}, "locals")
+		hcl.WriteUnquotedAttribute(locals, resourceName+"_id", id)
+
+		file := hcl.NewFile()
+		file.AppendBlock(data)
+		file.AppendBlock(locals)
+
+		return file.Render(dependencies.Format)
+	}
+
+	dependencies.AddResource(thisResource)
+}
+
+// exactNameLookup returns the data source that finds the resources of the type whose names contain the name, and
+// the expression holding the ID of the resource with the exact name, which fails if no resource has the exact name.
+func exactNameLookup(resourceType string, dataName string, name string) (*hcl.Block, string) {
+	dataSource := dataSources[resourceType]
+
+	data := hcl.EncodeAsBlock(terraform.TerraformLookupData{
+		Type:        dataSource.dataType,
+		Name:        dataName,
+		Ids:         nil,
+		PartialName: name,
+		Skip:        0,
+		Take:        lookupTake,
+	}, "data")
+
+	return data, "[for r in data." + dataSource.dataType + "." + dataName + "." + dataSource.attribute + " : r.id if r.name == " + hcl.QuoteString(name) + "][0]"
+}
@@ -0,0 +1,164 @@
+package converters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDataLookupConverter(t *testing.T) {
+	dependencies := newTestCollection()
+	dependencies.AddResource(ResourceDetails{
+		Id:           "Projects-1",
+		ResourceType: "Projects",
+		FileName:     "space_population/project.tf",
+		ToHcl: func() (string, error) {
+			return "lifecycle_id = \"" + dependencies.GetResource("Lifecycles", "Lifecycles-1") + "\"\n" +
+				"unsupported = \"" + dependencies.GetResource("ScriptModules", "LibraryVariableSets-2") + "\"\n" +
+				"missing = \"" + dependencies.GetResource("Environments", "Environments-99") + "\"\n", nil
+		},
+	})
+
+	resources := dependencies.SortedResources()
+	_, unresolved, err := dependencies.RenderResource(resources[0])
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unresolved) != 3 {
+		t.Fatalf("expected 3 unresolved references, found %v", unresolved)
+	}
+
+	err = DataLookupConverter{Client: createFakeClient(t)}.ToHclByUnresolvedReferences(context.Background(), unresolved, dependencies)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hcl, unresolved, err := dependencies.RenderResource(resources[0])
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unresolved) != 2 || unresolved[0].ResourceType != "ScriptModules" || unresolved[1].Id != "Environments-99" {
+		t.Fatalf("expected the unsupported and missing references to remain unresolved, found %v", unresolved)
+	}
+
+	files := map[string]string{"space_population/project.tf": hcl}
+	for _, r := range dependencies.SortedResources() {
+		if r.ResourceType == "Lifecycles" {
+			files[r.FileName], _, _ = dependencies.RenderResource(r)
+		}
+	}
+
+	assertFileContains(t, files, "space_population/project.tf",
+		"lifecycle_id = \"${local.lookup_lifecycles_default_lifecycle_id}\"")
+	assertFileContains(t, files, "space_population/lookup_lifecycles_default_lifecycle.tf",
+		"data \"octopusdeploy_lifecycles\" \"lookup_lifecycles_default_lifecycle\"",
+		"partial_name = \"Default Lifecycle\"",
+		"lookup_lifecycles_default_lifecycle_id = [for r in data.octopusdeploy_lifecycles.lookup_lifecycles_default_lifecycle.lifecycles : r.id if r.name == \"Default Lifecycle\"][0]")
+}
+
+func TestDataLookupConverterById(t *testing.T) {
+	octopusClient := createFakeClient(t)
+	_, projectConverter := createConverters(octopusClient)
+	projectConverter.LifecycleConverter = DataLookupConverter{Client: octopusClient, ResourceType: "Lifecycles"}
+	projectConverter.LibraryVariableSetConverter = DataLookupConverter{Client: octopusClient, ResourceType: "LibraryVariableSets"}
+	dependencies := newTestCollection()
+
+	err := projectConverter.ToHclById(context.Background(), "Projects-1", dependencies)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := toFileMap(t, dependencies)
+
+	assertFileContains(t, files, "space_population/project_project_web_app.tf",
+		`lifecycle_id                         = "${local.lookup_lifecycles_default_lifecycle_id}"`,
+		`included_library_variable_sets       = ["${local.lookup_libraryvariablesets_shared_settings_id}"]`)
+	assertFileContains(t, files, "space_population/lookup_libraryvariablesets_shared_settings.tf",
+		`data "octopusdeploy_library_variable_sets" "lookup_libraryvariablesets_shared_settings"`,
+		`partial_name = "Shared Settings"`)
+
+	if _, ok := files["space_population/library_variable_set_shared_settings.tf"]; ok {
+		t.Error("did not expect the library variable set to be exported")
+	}
+
+	err = DataLookupConverter{Client: octopusClient, ResourceType: "Spaces"}.ToHclById(context.Background(), "Spaces-1", dependencies)
+
+	if err == nil {
+		t.Error("expected an error for a resource type that can not be looked up")
+	}
+}
+
+// TestDataLookupConverterExactName looks up two environments whose names overlap, and evaluates the locals against
+// a data source that returns both environments for each partial name.
+func TestDataLookupConverterExactName(t *testing.T) {
+	dependencies := newTestCollection()
+	environments := []struct {
+		resource     octopus.NamedResource
+		resourceName string
+	}{
+		{octopus.NamedResource{Id: "Environments-1", Name: "Pre-Production"}, "lookup_environments_pre_production"},
+		{octopus.NamedResource{Id: "Environments-2", Name: "Production"}, "lookup_environments_production"},
+	}
+
+	matches := []cty.Value{}
+	for _, environment := range environments {
+		DataLookupConverter{}.toHcl("Environments", environment.resource, dependencies)
+		matches = append(matches, cty.ObjectVal(map[string]cty.Value{
+			"id":   cty.StringVal(environment.resource.Id),
+			"name": cty.StringVal(environment.resource.Name),
+		}))
+	}
+
+	files := toFileMap(t, dependencies)
+
+	for _, environment := range environments {
+		fileName := "space_population/" + environment.resourceName + ".tf"
+		file, diags := hclsyntax.ParseConfig([]byte(files[fileName]), fileName, hcl.Pos{Line: 1, Column: 1})
+
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+
+		ctx := &hcl.EvalContext{Variables: map[string]cty.Value{
+			"data": cty.ObjectVal(map[string]cty.Value{
+				"octopusdeploy_environments": cty.ObjectVal(map[string]cty.Value{
+					environment.resourceName: cty.ObjectVal(map[string]cty.Value{"environments": cty.TupleVal(matches)}),
+				}),
+			}),
+		}}
+
+		found := false
+		for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+			attribute, ok := block.Body.Attributes[environment.resourceName+"_id"]
+
+			if block.Type != "locals" || !ok {
+				continue
+			}
+
+			found = true
+			value, diags := attribute.Expr.Value(ctx)
+
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			if value.AsString() != environment.resource.Id {
+				t.Errorf("expected the lookup of %s to find %s, found %s", environment.resource.Name, environment.resource.Id, value.AsString())
+			}
+		}
+
+		if !found {
+			t.Errorf("expected %s to hold the local %s_id", fileName, environment.resourceName)
+		}
+	}
+}
@@ -1,9 +1,8 @@
 package converters
 
 import (
+	"context"
 	"fmt"
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hclwrite"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
@@ -15,6 +14,9 @@ import (
 	"strings"
 )
 
+// customScript matches the name of a custom deployment script property, capturing the phase and the extension
+var customScript = regexp.MustCompile(`^Octopus\.Action\.CustomScripts\.([A-Za-z]+)(\.(?:ps1|sh|py|csx|fsx))$`)
+
 type DeploymentProcessConverter struct {
 	Client              client.OctopusClient
 	FeedConverter       ConverterById
@@ -22,20 +24,22 @@ type DeploymentProcessConverter struct {
 	WorkerPoolConverter ConverterById
 }
 
-func (c DeploymentProcessConverter) ToHclByIdAndName(id string, projectName string, dependencies *ResourceDetailsCollection) error {
+func (c DeploymentProcessConverter) ToHclByIdAndName(ctx context.Context, id string, projectName string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	resource := octopus.DeploymentProcess{}
-	found, err := c.Client.GetResourceById(c.GetResourceType(), id, &resource)
+	found, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &resource)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
 	// Projects with no deployment process will not have a deployment process resources.
@@ -44,38 +48,64 @@ func (c DeploymentProcessConverter) ToHclByIdAndName(id string, projectName stri
 		return nil
 	}
 
-	return c.toHcl(resource, true, projectName, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, resource, true, projectName, dependencies))
 }
 
-func (c DeploymentProcessConverter) toHcl(resource octopus.DeploymentProcess, recursive bool, projectName string, dependencies *ResourceDetailsCollection) error {
-	resourceName := "deployment_process_" + sanitizer2.SanitizeName(projectName)
+func (c DeploymentProcessConverter) toHcl(ctx context.Context, resource octopus.DeploymentProcess, recursive bool, projectName string, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, resource.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
+	resourceName := dependencies.ResourceName("deployment_process_project_", c.GetResourceType(), resource.Id, projectName)
 
 	thisResource := ResourceDetails{}
 
 	if recursive {
 		// Export linked accounts
-		err := c.exportAccounts(resource, dependencies)
+		err := c.exportAccounts(ctx, resource, dependencies)
 		if err != nil {
 			return err
 		}
 
 		// Export linked feeds
-		err = c.exportFeeds(resource, dependencies)
+		err = c.exportFeeds(ctx, resource, dependencies)
 		if err != nil {
 			return err
 		}
 
 		// Export linked worker pools
-		err = c.exportWorkerPools(resource, dependencies)
+		err = c.exportWorkerPools(ctx, resource, dependencies)
 		if err != nil {
 			return err
 		}
 	}
 
 	thisResource.FileName = "space_population/" + resourceName + ".tf"
+
+	scriptFiles := c.addScriptFiles(resource, resourceName, thisResource.FileName, dependencies)
+
 	thisResource.Id = resource.Id
+	thisResource.Name = projectName
 	thisResource.ResourceType = c.GetResourceType()
 	thisResource.Lookup = "${octopusdeploy_deployment_process." + resourceName + ".id}"
+
+	c.addSensitivePropertyWarnings(resource, thisResource.Name, dependencies)
+
+	// The actions are referenced by other resources, like the variables scoped to them. They are added here, rather
+	// than when the process is rendered, so rendering the process again doesn't add them twice.
+	for i, s := range resource.Steps {
+		for j, a := range s.Actions {
+			actionResource := ResourceDetails{}
+			actionResource.FileName = ""
+			actionResource.Id = a.Id
+			actionResource.Name = strutil.EmptyIfNil(a.Name)
+			actionResource.ResourceType = "Actions"
+			actionResource.Lookup = "${octopusdeploy_deployment_process." + resourceName + ".step[" + fmt.Sprint(i) + "].action[" + fmt.Sprint(j) + "].id}"
+			dependencies.AddResource(actionResource)
+		}
+	}
+
 	thisResource.ToHcl = func() (string, error) {
 
 		terraformResource := terraform.TerraformDeploymentProcess{
@@ -97,14 +127,6 @@ func (c DeploymentProcessConverter) toHcl(resource octopus.DeploymentProcess, re
 			}
 
 			for j, a := range s.Actions {
-
-				actionResource := ResourceDetails{}
-				actionResource.FileName = ""
-				actionResource.Id = a.Id
-				actionResource.ResourceType = "Actions"
-				actionResource.Lookup = "${octopusdeploy_deployment_process." + resourceName + ".step[" + fmt.Sprint(i) + "].action[" + fmt.Sprint(j) + "].id}"
-				dependencies.AddResource(actionResource)
-
 				terraformResource.Step[i].Action[j] = terraform.TerraformAction{
 					Name:                          a.Name,
 					ActionType:                    a.ActionType,
@@ -154,8 +176,8 @@ func (c DeploymentProcessConverter) toHcl(resource octopus.DeploymentProcess, re
 			}
 		}
 
-		file := hclwrite.NewEmptyFile()
-		block := gohcl.EncodeAsBlock(terraformResource, "resource")
+		file := hcl.NewFile()
+		block := hcl.EncodeAsBlock(terraformResource, "resource")
 
 		for _, s := range resource.Steps {
 			for _, a := range s.Actions {
@@ -165,12 +187,15 @@ func (c DeploymentProcessConverter) toHcl(resource octopus.DeploymentProcess, re
 				sanitizedProperties = c.escapePercents(sanitizedProperties)
 				sanitizedProperties = c.replaceIds(sanitizedProperties, dependencies)
 				sanitizedProperties = c.removeUnnecessaryActionFields(sanitizedProperties)
+				for k, v := range scriptFiles[a.Id] {
+					sanitizedProperties[k] = v
+				}
 				hcl.WriteActionProperties(block, *s.Name, *a.Name, sanitizedProperties)
 			}
 		}
 
-		file.Body().AppendBlock(block)
-		return string(file.Bytes()), nil
+		file.AppendBlock(block)
+		return file.Render(dependencies.Format)
 	}
 
 	dependencies.AddResource(thisResource)
@@ -181,18 +206,76 @@ func (c DeploymentProcessConverter) GetResourceType() string {
 	return "DeploymentProcesses"
 }
 
-func (c DeploymentProcessConverter) exportFeeds(resource octopus.DeploymentProcess, dependencies *ResourceDetailsCollection) error {
+// addScriptFiles writes the inline scripts of the actions to their own files when ScriptFiles is set, returning the
+// references to the files that replace the script properties, mapped to the action ID and the property name.
+func (c DeploymentProcessConverter) addScriptFiles(resource octopus.DeploymentProcess, resourceName string, resourceFileName string, dependencies *ResourceDetailsCollection) map[string]map[string]string {
+	scriptFiles := map[string]map[string]string{}
+
+	for _, s := range resource.Steps {
+		for _, a := range s.Actions {
+			properties := sanitizer2.SanitizeMap(a.Properties)
+			name := dependencies.ResourceName(resourceName+"_", "Actions", a.Id, strutil.EmptyIfNil(a.Name))
+			references := map[string]string{}
+
+			if fileName, ok := addScriptFile(resourceFileName, name, scriptExtensions[properties["Octopus.Action.Script.Syntax"]],
+				properties["Octopus.Action.Script.ScriptBody"], dependencies); ok {
+				references["Octopus.Action.Script.ScriptBody"] = hcl.FileReference(fileName)
+			}
+
+			// The custom deployment scripts, like Octopus.Action.CustomScripts.PreDeploy.ps1, include the extension
+			for _, k := range sliceutil.SortedKeys(properties) {
+				if match := customScript.FindStringSubmatch(k); match != nil {
+					if fileName, ok := addScriptFile(resourceFileName, name+"_"+strings.ToLower(match[1]), match[2], properties[k], dependencies); ok {
+						references[k] = hcl.FileReference(fileName)
+					}
+				}
+			}
+
+			scriptFiles[a.Id] = references
+		}
+	}
+
+	return scriptFiles
+}
+
+// addSensitivePropertyWarnings records a warning for each action property that is replaced with a placeholder
+// because it holds a sensitive value, which is not returned by the API.
+func (c DeploymentProcessConverter) addSensitivePropertyWarnings(resource octopus.DeploymentProcess, name string, dependencies *ResourceDetailsCollection) {
+	for _, s := range resource.Steps {
+		for _, a := range s.Actions {
+			for _, k := range sliceutil.SortedKeys(a.Properties) {
+				if _, ok := a.Properties[k].(string); ok {
+					continue
+				}
+
+				dependencies.AddWarning(ResourceWarning{
+					ResourceType: c.GetResourceType(),
+					Id:           resource.Id,
+					Name:         name,
+					Message: "The sensitive property " + k + " of the action " + strutil.EmptyIfNil(a.Name) + " in the step " +
+						strutil.EmptyIfNil(s.Name) + " was replaced with \"" + sanitizer2.SensitivePlaceholder + "\"",
+				})
+			}
+		}
+	}
+}
+
+func (c DeploymentProcessConverter) exportFeeds(ctx context.Context, resource octopus.DeploymentProcess, dependencies *ResourceDetailsCollection) error {
 	feedRegex, _ := regexp.Compile("Feeds-\\d+")
 	for _, step := range resource.Steps {
 		for _, action := range step.Actions {
 
 			if strutil.NilIfEmptyPointer(action.Container.FeedId) != nil {
-				c.FeedConverter.ToHclById(strutil.EmptyIfNil(action.Container.FeedId), dependencies)
+				err := c.FeedConverter.ToHclById(ctx, strutil.EmptyIfNil(action.Container.FeedId), dependencies)
+
+				if err != nil {
+					return err
+				}
 			}
 
 			for _, pack := range action.Packages {
 				if pack.FeedId != nil {
-					err := c.FeedConverter.ToHclById(strutil.EmptyIfNil(pack.FeedId), dependencies)
+					err := c.FeedConverter.ToHclById(ctx, strutil.EmptyIfNil(pack.FeedId), dependencies)
 
 					if err != nil {
 						return err
@@ -202,7 +285,7 @@ func (c DeploymentProcessConverter) exportFeeds(resource octopus.DeploymentProce
 
 			for _, prop := range action.Properties {
 				for _, feed := range feedRegex.FindAllString(fmt.Sprint(prop), -1) {
-					err := c.FeedConverter.ToHclById(feed, dependencies)
+					err := c.FeedConverter.ToHclById(ctx, feed, dependencies)
 
 					if err != nil {
 						return err
@@ -215,13 +298,13 @@ func (c DeploymentProcessConverter) exportFeeds(resource octopus.DeploymentProce
 	return nil
 }
 
-func (c DeploymentProcessConverter) exportAccounts(resource octopus.DeploymentProcess, dependencies *ResourceDetailsCollection) error {
+func (c DeploymentProcessConverter) exportAccounts(ctx context.Context, resource octopus.DeploymentProcess, dependencies *ResourceDetailsCollection) error {
 	accountRegex, _ := regexp.Compile("Accounts-\\d+")
 	for _, step := range resource.Steps {
 		for _, action := range step.Actions {
 			for _, prop := range action.Properties {
 				for _, account := range accountRegex.FindAllString(fmt.Sprint(prop), -1) {
-					err := c.AccountConverter.ToHclById(account, dependencies)
+					err := c.AccountConverter.ToHclById(ctx, account, dependencies)
 
 					if err != nil {
 						return err
@@ -234,11 +317,11 @@ func (c DeploymentProcessConverter) exportAccounts(resource octopus.DeploymentPr
 	return nil
 }
 
-func (c DeploymentProcessConverter) exportWorkerPools(resource octopus.DeploymentProcess, dependencies *ResourceDetailsCollection) error {
+func (c DeploymentProcessConverter) exportWorkerPools(ctx context.Context, resource octopus.DeploymentProcess, dependencies *ResourceDetailsCollection) error {
 	for _, step := range resource.Steps {
 		for _, action := range step.Actions {
 			if action.WorkerPoolId != "" {
-				err := c.WorkerPoolConverter.ToHclById(action.WorkerPoolId, dependencies)
+				err := c.WorkerPoolConverter.ToHclById(ctx, action.WorkerPoolId, dependencies)
 
 				if err != nil {
 					return err
@@ -322,26 +405,39 @@ func (c DeploymentProcessConverter) getRunOnServer(properties map[string]any) bo
 // replaceFeedIds looks for any property value that is a valid feed ID and replaces it with a resource ID lookup.
 // This also looks in the property values, for instance when you export a JSON blob that has feed references.
 func (c DeploymentProcessConverter) replaceFeedIds(properties map[string]string, dependencies *ResourceDetailsCollection) map[string]string {
-	for k, v := range properties {
-		for _, v2 := range dependencies.GetAllResource("Feeds") {
-			if strings.Contains(v, v2.Id) {
-				properties[k] = strings.ReplaceAll(v, v2.Id, v2.Lookup)
-			}
-		}
-	}
-
-	return properties
+	return c.replaceResourceIds(properties, "Feeds", regexp.MustCompile(`Feeds-\d+`), dependencies)
 }
 
 // replaceAccountIds looks for any property value that is a valid account ID and replaces it with a resource ID lookup.
 // This also looks in the property values, for instance when you export a JSON blob that has feed references.
 func (c DeploymentProcessConverter) replaceAccountIds(properties map[string]string, dependencies *ResourceDetailsCollection) map[string]string {
+	return c.replaceResourceIds(properties, "Accounts", regexp.MustCompile(`Accounts-\d+`), dependencies)
+}
+
+// replaceResourceIds replaces any exported resource ID found in the property values with the resource lookup.
+// IDs matching idRegex are resolved through the collection's index. Only the few exported resources whose IDs
+// don't match the regex, like the built-in feed, need to be searched for in every property value.
+func (c DeploymentProcessConverter) replaceResourceIds(properties map[string]string, resourceType string, idRegex *regexp.Regexp, dependencies *ResourceDetailsCollection) map[string]string {
+	irregularResources := []ResourceDetails{}
+	for _, r := range dependencies.GetAllResource(resourceType) {
+		if idRegex.FindString(r.Id) != r.Id {
+			irregularResources = append(irregularResources, r)
+		}
+	}
+
 	for k, v := range properties {
-		for _, v2 := range dependencies.GetAllResource("Accounts") {
-			if strings.Contains(v, v2.Id) {
-				properties[k] = strings.ReplaceAll(v, v2.Id, v2.Lookup)
+		value := idRegex.ReplaceAllStringFunc(v, func(id string) string {
+			if lookup, found := dependencies.LookupResource(resourceType, id); found {
+				return lookup
 			}
+			return id
+		})
+
+		for _, r := range irregularResources {
+			value = strings.ReplaceAll(value, r.Id, r.Lookup)
 		}
+
+		properties[k] = value
 	}
 
 	return properties
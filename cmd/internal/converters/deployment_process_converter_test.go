@@ -0,0 +1,82 @@
+package converters
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
+)
+
+// copiedProcessClient returns a copy of the deployment process of Projects-1 for any deployment process ID, like
+// deploymentprocess-Projects-2.
+type copiedProcessClient struct {
+	client.OctopusClient
+}
+
+func (c copiedProcessClient) GetResourceById(ctx context.Context, resourceType string, id string, resources any) (bool, error) {
+	if resourceType != "DeploymentProcesses" {
+		return c.OctopusClient.GetResourceById(ctx, resourceType, id, resources)
+	}
+
+	found, err := c.OctopusClient.GetResourceById(ctx, resourceType, "deploymentprocess-Projects-1", resources)
+	resources.(*octopus.DeploymentProcess).Id = id
+	resources.(*octopus.DeploymentProcess).ProjectId = strings.TrimPrefix(id, "deploymentprocess-")
+	return found, err
+}
+
+// TestDeploymentProcessConverterUniqueNames exports the deployment processes of two projects whose names are the
+// same once sanitized, and expects each process and script to be written to its own file.
+func TestDeploymentProcessConverterUniqueNames(t *testing.T) {
+	_, projectConverter := createConverters(createFakeClient(t))
+	converter := projectConverter.DeploymentProcessConverter.(DeploymentProcessConverter)
+	converter.Client = copiedProcessClient{OctopusClient: converter.Client}
+	dependencies := newTestCollection()
+	dependencies.ScriptFiles = true
+
+	for _, project := range [][]string{{"Projects-1", "Web App"}, {"Projects-2", "Web-App"}} {
+		projectName := dependencies.ResourceName("project_", "Projects", project[0], project[1])
+		dependencies.AddResource(ResourceDetails{
+			Id:           project[0],
+			ResourceType: "Projects",
+			Lookup:       "${octopusdeploy_project." + projectName + ".id}",
+		})
+
+		if err := converter.ToHclByIdAndName(context.Background(), "deploymentprocess-"+project[0], project[1], dependencies); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := toFileMap(t, dependencies)
+
+	assertFileContains(t, files, "space_population/deployment_process_project_web_app.tf",
+		`resource "octopusdeploy_deployment_process" "deployment_process_project_web_app"`)
+	assertFileContains(t, files, "space_population/deployment_process_project_web_app_2.tf",
+		`resource "octopusdeploy_deployment_process" "deployment_process_project_web_app_2"`)
+
+	scripts := []string{}
+	for fileName, content := range files {
+		if strings.HasPrefix(fileName, "space_population/scripts/") {
+			scripts = append(scripts, fileName)
+
+			if strings.Count(content, "echo") != 1 {
+				t.Errorf("expected %s to hold one script, but it was:\n%s", fileName, content)
+			}
+		}
+	}
+
+	if len(scripts) != 2 {
+		t.Fatalf("expected a script file for each deployment process, found %v", scripts)
+	}
+
+	// The processes are listed, like in the dry run, by the name of their project
+	for _, r := range dependencies.SortedResources() {
+		if r.ResourceType == "DeploymentProcesses" && r.Name != map[string]string{
+			"deploymentprocess-Projects-1": "Web App",
+			"deploymentprocess-Projects-2": "Web-App",
+		}[r.Id] {
+			t.Errorf("expected %s to be named after its project, found %s", r.Id, r.Name)
+		}
+	}
+}
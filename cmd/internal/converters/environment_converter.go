@@ -1,78 +1,80 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 )
 
 type EnvironmentConverter struct {
 	Client client.OctopusClient
 }
 
-func (c EnvironmentConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c EnvironmentConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.Environment]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, resource := range collection.Items {
-		err = c.toHcl(resource, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		resource := resource
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), resource.Id, c.toHcl(ctx, resource, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c EnvironmentConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c EnvironmentConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	environment := octopus2.Environment{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &environment)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &environment)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.toHcl(environment, true, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, environment, true, dependencies))
 }
 
-func (c EnvironmentConverter) toHcl(environment octopus2.Environment, recursive bool, dependencies *ResourceDetailsCollection) error {
-	resourceName := "environment_" + sanitizer.SanitizeName(environment.Name)
+func (c EnvironmentConverter) toHcl(ctx context.Context, environment octopus2.Environment, recursive bool, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, environment.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
+	resourceName := dependencies.ResourceName("environment_", c.GetResourceType(), environment.Id, environment.Name)
 
 	thisResource := ResourceDetails{}
 	thisResource.FileName = "space_population/" + resourceName + ".tf"
 	thisResource.Id = environment.Id
+	thisResource.Name = environment.Name
 	thisResource.ResourceType = c.GetResourceType()
 	thisResource.Lookup = "${octopusdeploy_environment." + resourceName + ".id}"
 	thisResource.ToHcl = func() (string, error) {
 
-		file := hclwrite.NewEmptyFile()
+		file := hcl.NewFile()
 
 		// Add a comment with the import command
-		baseUrl, _ := c.Client.GetSpaceBaseUrl()
-		file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-			Type: hclsyntax.TokenComment,
-			Bytes: []byte("# Import existing resources with the following commands:\n" +
-				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + environment.Name + "\") | .Id')\n" +
-				"# terraform import octopusdeploy_environment." + resourceName + " ${RESOURCE_ID}\n"),
-			SpacesBefore: 0,
-		}})
+		baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+		file.AppendComment("# Import existing resources with the following commands:\n" +
+			"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + environment.Name + "\") | .Id')\n" +
+			"# terraform import octopusdeploy_environment." + resourceName + " ${RESOURCE_ID}\n")
 
 		terraformResource := terraform.TerraformEnvironment{
 			Type:                       "octopusdeploy_environment",
@@ -93,15 +95,11 @@ func (c EnvironmentConverter) toHcl(environment octopus2.Environment, recursive
 				IsEnabled: c.getServiceNowChangeControlled(environment),
 			},
 		}
-		file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
+		file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
 
 		// Add a data lookup to allow projects to quickly switch to using existing environments
-		file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-			Type: hclsyntax.TokenComment,
-			Bytes: []byte("# To use an existing environment, delete the resource above and use the following lookup instead:\n" +
-				"# data.octopusdeploy_environments." + resourceName + ".environments[0].id\n"),
-			SpacesBefore: 0,
-		}})
+		file.AppendComment("# To use an existing environment, delete the resource above and use the following lookup instead:\n" +
+			"# data.octopusdeploy_environments." + resourceName + ".environments[0].id\n")
 		terraformDataResource := terraform.TerraformEnvironmentData{
 			Type:        "octopusdeploy_environments",
 			Name:        resourceName,
@@ -110,9 +108,9 @@ func (c EnvironmentConverter) toHcl(environment octopus2.Environment, recursive
 			Skip:        0,
 			Take:        1,
 		}
-		file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformDataResource, "data"))
+		file.AppendBlock(hcl.EncodeAsBlock(terraformDataResource, "data"))
 
-		return string(file.Bytes()), nil
+		return file.Render(dependencies.Format)
 	}
 
 	dependencies.AddResource(thisResource)
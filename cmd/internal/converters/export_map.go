@@ -1,5 +1,13 @@
 package converters
 
+import (
+	"context"
+	"errors"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/workerpool"
+	"sort"
+	"sync"
+)
+
 type ToHcl func() (string, error)
 
 // ResourceDetails is used to capture the dependencies required by the root resources that was
@@ -11,62 +19,487 @@ type ToHcl func() (string, error)
 // 5. ToHcl converts the object to HCL, and uses the Lookup field in the appropriate ResourceDetails to reference a dependency.
 type ResourceDetails struct {
 	Id           string
+	Name         string
 	ResourceType string
 	Lookup       string
 	FileName     string
 	ToHcl        ToHcl
 }
 
+// resourceKey identifies a resource by its type and ID.
+type resourceKey struct {
+	resourceType string
+	id           string
+}
+
+// ResourceDetailsCollection captures the resources discovered during an export. It is safe for
+// concurrent use by multiple converters.
+//
+// Resources are indexed by their type and ID, and by their type, so lookups do not need to scan the
+// entire collection. The Resources slice retains the order in which resources were added.
 type ResourceDetailsCollection struct {
 	Resources []ResourceDetails
+	// ContinueOnError records the errors exporting individual resources with RecordError, rather than failing
+	// the export on the first error.
+	ContinueOnError bool
+	// WorkerPool limits the number of goroutines used to discover dependencies. A nil pool
+	// discovers dependencies serially.
+	WorkerPool *workerpool.WorkerPool
+	// Format is the syntax the resources are rendered in. One of hcl.FormatHcl or hcl.FormatTfJson, or empty
+	// for hcl.FormatHcl.
+	Format string
+	// Layout is how the resources are grouped into files. One of LayoutResource, LayoutType, LayoutProject or
+	// LayoutSingle, or empty for LayoutResource.
+	Layout string
+	// ScriptFiles writes the bodies of scripts to their own files, which are read by the configuration with the
+	// file function, rather than writing them as strings in the configuration.
+	ScriptFiles bool
+	// names gives each resource a unique Terraform identifier
+	names   NameRegistry
+	mu      sync.RWMutex
+	claimed map[resourceKey]bool
+	// parents maps a claimed resource to the resource whose discovery claimed it
+	parents map[resourceKey]resourceKey
+	// edges maps a resource to the resources that were discovered as its dependencies
+	edges map[resourceKey]map[resourceKey]bool
+	// byKey maps a resource type and ID to the position of the first matching resource in Resources
+	byKey map[resourceKey]int
+	// byType maps a resource type to the positions of the matching resources in Resources
+	byType map[string][]int
+	// warnings describes resources that were not exported, or were exported with changes
+	warnings []ResourceWarning
+	// errors describes the resources that failed to export when ContinueOnError is set
+	errors []ResourceError
+	// renderMu guards rendering, which records the lookups that could not be resolved while a resource is rendered
+	renderMu  sync.Mutex
+	rendering *renderState
+}
+
+// ResourceWarning describes a resource that could not be exported, or that was exported with changes.
+type ResourceWarning struct {
+	ResourceType string
+	Id           string
+	Name         string
+	Message      string
+}
+
+// UnresolvedReference is a lookup of a resource that was not exported. The requesting resource is
+// written with an empty value in place of the lookup, which fails when the configuration is applied.
+type UnresolvedReference struct {
+	RequesterType string
+	RequesterId   string
+	FileName      string
+	ResourceType  string
+	Id            string
+}
+
+func (r UnresolvedReference) String() string {
+	return r.FileName + ": " + r.RequesterType + " " + r.RequesterId + " references " + r.ResourceType + " " +
+		r.Id + ", which was not exported"
+}
+
+// Dependency is an edge in the dependency graph, recording that discovering a resource found another resource
+// it depends on.
+type Dependency struct {
+	ResourceType   string
+	Id             string
+	DependencyType string
+	DependencyId   string
+}
+
+// ResourceError is an error exporting a resource.
+type ResourceError struct {
+	ResourceType string
+	Id           string
+	Err          error
+}
+
+func (e ResourceError) Error() string {
+	return e.ResourceType + " " + e.Id + ": " + e.Err.Error()
+}
+
+func (e ResourceError) Unwrap() error {
+	return e.Err
 }
 
+type renderState struct {
+	resource   ResourceDetails
+	unresolved []UnresolvedReference
+	// references are the resources the rendered resource refers to
+	references []resourceKey
+}
+
+// HasResource returns true if the resource has been added to the collection, or if a converter has
+// claimed the resource and is in the process of exporting it.
 func (c *ResourceDetailsCollection) HasResource(id string, resourceType string) bool {
-	for _, r := range c.Resources {
-		if r.Id == id && r.ResourceType == resourceType {
-			return true
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	key := resourceKey{resourceType: resourceType, id: id}
+	_, found := c.byKey[key]
+	return found || c.claimed[key]
+}
+
+// parentContextKey is the context key holding the resource whose dependencies are being discovered.
+type parentContextKey struct{}
+
+// withParent returns a context that records the resources discovered with it as dependencies of the resource.
+func withParent(ctx context.Context, resourceType string, id string) context.Context {
+	return context.WithValue(ctx, parentContextKey{}, resourceKey{resourceType: resourceType, id: id})
+}
+
+// addEdge records the resource as a dependency of the resource that claimed the context. The caller must hold
+// the lock.
+func (c *ResourceDetailsCollection) addEdge(ctx context.Context, key resourceKey) (resourceKey, bool) {
+	parent, ok := ctx.Value(parentContextKey{}).(resourceKey)
+
+	if !ok || key.id == "" || key == parent {
+		return parent, false
+	}
+
+	if c.edges == nil {
+		c.edges = map[resourceKey]map[resourceKey]bool{}
+	}
+
+	if c.edges[parent] == nil {
+		c.edges[parent] = map[resourceKey]bool{}
+	}
+
+	c.edges[parent][key] = true
+
+	return parent, true
+}
+
+// AddDependency records the resource as a dependency of the resource that claimed the context. Converters call
+// this before checking whether a dependency has already been exported, so every resource that depends on it
+// is recorded, and not just the first.
+func (c *ResourceDetailsCollection) AddDependency(ctx context.Context, resourceType string, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.addEdge(ctx, resourceKey{resourceType: resourceType, id: id})
+}
+
+// Dependencies returns the dependency edges ordered by the resource type and ID, and then the dependency type
+// and ID.
+func (c *ResourceDetailsCollection) Dependencies() []Dependency {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	dependencies := []Dependency{}
+	for from, to := range c.edges {
+		for key := range to {
+			dependencies = append(dependencies, Dependency{
+				ResourceType:   from.resourceType,
+				Id:             from.id,
+				DependencyType: key.resourceType,
+				DependencyId:   key.id,
+			})
 		}
 	}
 
-	return false
+	sort.Slice(dependencies, func(i, j int) bool {
+		a, b := dependencies[i], dependencies[j]
+		if a.ResourceType != b.ResourceType {
+			return a.ResourceType < b.ResourceType
+		}
+
+		if a.Id != b.Id {
+			return a.Id < b.Id
+		}
+
+		if a.DependencyType != b.DependencyType {
+			return a.DependencyType < b.DependencyType
+		}
+
+		return a.DependencyId < b.DependencyId
+	})
+
+	return dependencies
+}
+
+// ClaimResource atomically checks that a resource has not already been added or claimed, and marks it as claimed.
+// Converters call this before exporting a resource, and only continue if it returns true. This ensures
+// that two goroutines do not both export the same resource.
+//
+// The resource is recorded as a dependency of the resource that claimed the context, if any. Converters
+// discover the dependencies of the claimed resource with the returned context.
+func (c *ResourceDetailsCollection) ClaimResource(ctx context.Context, id string, resourceType string) (context.Context, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := resourceKey{resourceType: resourceType, id: id}
+	parent, hasParent := c.addEdge(ctx, key)
+
+	if _, found := c.byKey[key]; found || c.claimed[key] {
+		return ctx, false
+	}
+
+	if c.claimed == nil {
+		c.claimed = map[resourceKey]bool{}
+	}
+
+	c.claimed[key] = true
+
+	if hasParent {
+		if c.parents == nil {
+			c.parents = map[resourceKey]resourceKey{}
+		}
+
+		c.parents[key] = parent
+	}
+
+	return withParent(ctx, resourceType, id), true
+}
+
+// GetParent returns the type and ID of the resource whose discovery claimed the resource, and false if the
+// resource was not claimed as the dependency of another resource, for example because it was the resource
+// being exported.
+func (c *ResourceDetailsCollection) GetParent(resourceType string, id string) (string, string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	parent, found := c.parents[resourceKey{resourceType: resourceType, id: id}]
+	return parent.resourceType, parent.id, found
 }
 
 func (c *ResourceDetailsCollection) AddResource(resource ...ResourceDetails) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.Resources == nil {
 		c.Resources = []ResourceDetails{}
 	}
 
-	c.Resources = append(c.Resources, resource...)
+	if c.byKey == nil {
+		c.byKey = map[resourceKey]int{}
+	}
+
+	if c.byType == nil {
+		c.byType = map[string][]int{}
+	}
+
+	for _, r := range resource {
+		position := len(c.Resources)
+		c.Resources = append(c.Resources, r)
+
+		key := resourceKey{resourceType: r.ResourceType, id: r.Id}
+		if _, found := c.byKey[key]; !found {
+			c.byKey[key] = position
+		}
+
+		c.byType[r.ResourceType] = append(c.byType[r.ResourceType], position)
+	}
 }
 
-func (c *ResourceDetailsCollection) GetAllResource(resourceType string) []ResourceDetails {
-	resources := make([]ResourceDetails, 0)
-	for _, r := range c.Resources {
-		if r.ResourceType == resourceType {
-			resources = append(resources, r)
+// AddChildResource adds resources that were discovered as part of the resource that claimed the context, like the
+// tags of a tag set, recording the claimed resource as their parent.
+func (c *ResourceDetailsCollection) AddChildResource(ctx context.Context, resource ...ResourceDetails) {
+	c.mu.Lock()
+	for _, r := range resource {
+		key := resourceKey{resourceType: r.ResourceType, id: r.Id}
+		parent, hasParent := c.addEdge(ctx, key)
+
+		if _, found := c.parents[key]; hasParent && !found {
+			if c.parents == nil {
+				c.parents = map[resourceKey]resourceKey{}
+			}
+
+			c.parents[key] = parent
 		}
 	}
+	c.mu.Unlock()
+
+	c.AddResource(resource...)
+}
+
+// AddWarning records a warning about a resource.
+func (c *ResourceDetailsCollection) AddWarning(warning ResourceWarning) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.warnings = append(c.warnings, warning)
+}
+
+// Warnings returns a copy of the warnings ordered by resource type, ID and message.
+func (c *ResourceDetailsCollection) Warnings() []ResourceWarning {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	sorted := make([]ResourceWarning, len(c.warnings))
+	copy(sorted, c.warnings)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ResourceType != sorted[j].ResourceType {
+			return sorted[i].ResourceType < sorted[j].ResourceType
+		}
+
+		if sorted[i].Id != sorted[j].Id {
+			return sorted[i].Id < sorted[j].Id
+		}
+
+		return sorted[i].Message < sorted[j].Message
+	})
+
+	return sorted
+}
+
+// RecordError is called with the result of exporting a resource. If ContinueOnError is set, the error is recorded
+// and nil is returned, so the export continues with the other resources. Otherwise, the error is returned.
+// Cancellation always stops the export.
+func (c *ResourceDetailsCollection) RecordError(resourceType string, id string, err error) error {
+	if err == nil || !c.ContinueOnError || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.errors = append(c.errors, ResourceError{ResourceType: resourceType, Id: id, Err: err})
+
+	return nil
+}
+
+// Errors returns a copy of the recorded errors ordered by resource type and ID.
+func (c *ResourceDetailsCollection) Errors() []ResourceError {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	sorted := make([]ResourceError, len(c.errors))
+	copy(sorted, c.errors)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ResourceType != sorted[j].ResourceType {
+			return sorted[i].ResourceType < sorted[j].ResourceType
+		}
+
+		return sorted[i].Id < sorted[j].Id
+	})
+
+	return sorted
+}
+
+// SortedResources returns a copy of the resources ordered by file name, resource type and ID. Resources
+// are added in whatever order the converters complete, so this ordering is used to ensure the output is
+// the same regardless of how discovery was scheduled. The names returned by ResourceName are resolved in the
+// file names, lookups and names of the copies.
+func (c *ResourceDetailsCollection) SortedResources() []ResourceDetails {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	sorted := make([]ResourceDetails, len(c.Resources))
+	copy(sorted, c.Resources)
+
+	for i := range sorted {
+		sorted[i].FileName = c.names.Resolve(sorted[i].FileName)
+		sorted[i].Lookup = c.names.Resolve(sorted[i].Lookup)
+		sorted[i].Name = c.names.Resolve(sorted[i].Name)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].FileName != sorted[j].FileName {
+			return sorted[i].FileName < sorted[j].FileName
+		}
+
+		if sorted[i].ResourceType != sorted[j].ResourceType {
+			return sorted[i].ResourceType < sorted[j].ResourceType
+		}
+
+		return sorted[i].Id < sorted[j].Id
+	})
+
+	return sorted
+}
+
+// GetAllResource returns the resources of the given type in the order they were added.
+func (c *ResourceDetailsCollection) GetAllResource(resourceType string) []ResourceDetails {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	positions := c.byType[resourceType]
+	resources := make([]ResourceDetails, len(positions))
+	for i, position := range positions {
+		resources[i] = c.Resources[position]
+	}
 
 	return resources
 }
 
-func (c *ResourceDetailsCollection) GetResource(resourceType string, id string) string {
-	for _, r := range c.Resources {
-		if r.Id == id && r.ResourceType == resourceType {
-			return r.Lookup
+// ResourceName returns the placeholder of a unique Terraform identifier for the resource, built from the prefix and
+// the sanitized name. See NameRegistry for how the identifiers are assigned.
+func (c *ResourceDetailsCollection) ResourceName(prefix string, resourceType string, id string, name string) string {
+	return c.names.ResourceName(prefix, resourceType, id, name)
+}
+
+// ResolveNames replaces the placeholders returned by ResourceName in the value with the unique identifiers. Every
+// resource must have been discovered before the names are resolved.
+func (c *ResourceDetailsCollection) ResolveNames(value string) string {
+	return c.names.Resolve(value)
+}
+
+// RenderResource calls ToHcl on the resource, returning the HCL and the lookups of any resources that
+// were not exported. Resources must be rendered one at a time.
+func (c *ResourceDetailsCollection) RenderResource(resource ResourceDetails) (string, []UnresolvedReference, error) {
+	c.renderMu.Lock()
+	c.rendering = &renderState{resource: resource}
+	c.renderMu.Unlock()
+
+	hcl, err := resource.ToHcl()
+
+	c.renderMu.Lock()
+	unresolved := c.rendering.unresolved
+	references := c.rendering.references
+	c.rendering = nil
+	c.renderMu.Unlock()
+
+	// Space exports discover resources without following their dependencies, so the references found while
+	// rendering complete the dependency graph
+	if resource.Id != "" {
+		c.mu.Lock()
+		ctx := withParent(context.Background(), resource.ResourceType, resource.Id)
+		for _, r := range references {
+			c.addEdge(ctx, r)
 		}
+		c.mu.Unlock()
 	}
 
-	return ""
+	return c.names.Resolve(hcl), unresolved, err
+}
+
+func (c *ResourceDetailsCollection) GetResource(resourceType string, id string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.getLookup(resourceType, id)
+}
+
+// LookupResource returns the lookup of the resource, and false if the resource was not exported. Unlike
+// GetResource, a missing resource is not recorded as an unresolved reference.
+func (c *ResourceDetailsCollection) LookupResource(resourceType string, id string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if position, found := c.byKey[resourceKey{resourceType: resourceType, id: id}]; found {
+		return c.Resources[position].Lookup, true
+	}
+
+	return "", false
 }
 
+// GetResources returns the lookups for the resources with the supplied IDs.
 func (c *ResourceDetailsCollection) GetResources(resourceType string, ids ...string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// Lookups are returned in the same order as the supplied IDs, as the order the resources were
+	// added to the collection depends on how discovery was scheduled.
 	lookups := []string{}
-	for _, r := range c.Resources {
-		for _, i := range ids {
-			if r.Id == i && r.ResourceType == resourceType {
-				lookups = append(lookups, r.Lookup)
-			}
+	for _, i := range ids {
+		if position, found := c.byKey[resourceKey{resourceType: resourceType, id: i}]; found {
+			c.addReference(resourceType, i)
+			lookups = append(lookups, c.Resources[position].Lookup)
+		} else {
+			c.addUnresolved(resourceType, i)
 		}
 	}
 
@@ -74,14 +507,67 @@ func (c *ResourceDetailsCollection) GetResources(resourceType string, ids ...str
 }
 
 func (c *ResourceDetailsCollection) GetResourcePointer(resourceType string, id *string) *string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	lookup := ""
 	if id != nil {
-		for _, r := range c.Resources {
-			if r.Id == *id && r.ResourceType == resourceType {
-				return &r.Lookup
-			}
+		lookup = c.getLookup(resourceType, *id)
+	}
+
+	return &lookup
+}
+
+// getLookup returns the lookup of the first resource matching the type and ID, or an empty string if the
+// resource was not exported. The caller must hold the lock.
+func (c *ResourceDetailsCollection) getLookup(resourceType string, id string) string {
+	if position, found := c.byKey[resourceKey{resourceType: resourceType, id: id}]; found {
+		c.addReference(resourceType, id)
+		return c.Resources[position].Lookup
+	}
+
+	c.addUnresolved(resourceType, id)
+
+	return ""
+}
+
+// addUnresolved records a lookup of a resource that was not exported against the resource being rendered.
+// An empty ID means there is no dependency, and lookups made while discovering resources are not recorded.
+func (c *ResourceDetailsCollection) addUnresolved(resourceType string, id string) {
+	if id == "" {
+		return
+	}
+
+	c.renderMu.Lock()
+	defer c.renderMu.Unlock()
+
+	if c.rendering == nil {
+		return
+	}
+
+	for _, u := range c.rendering.unresolved {
+		if u.ResourceType == resourceType && u.Id == id {
+			return
 		}
 	}
 
-	empty := ""
-	return &empty
+	c.rendering.unresolved = append(c.rendering.unresolved, UnresolvedReference{
+		RequesterType: c.rendering.resource.ResourceType,
+		RequesterId:   c.rendering.resource.Id,
+		FileName:      c.fileName(c.rendering.resource),
+		ResourceType:  resourceType,
+		Id:            id,
+	})
+}
+
+// addReference records a lookup of an exported resource against the resource being rendered.
+func (c *ResourceDetailsCollection) addReference(resourceType string, id string) {
+	c.renderMu.Lock()
+	defer c.renderMu.Unlock()
+
+	if c.rendering == nil {
+		return
+	}
+
+	c.rendering.references = append(c.rendering.references, resourceKey{resourceType: resourceType, id: id})
 }
@@ -0,0 +1,307 @@
+package converters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// resourceTypes are the types used to build large collections for the tests and benchmarks
+var resourceTypes = []string{"Projects", "Environments", "Feeds", "Accounts", "Variables"}
+
+// createCollection builds a collection with count resources spread evenly across resourceTypes
+func createCollection(count int) *ResourceDetailsCollection {
+	collection := ResourceDetailsCollection{}
+	for i := 0; i < count; i++ {
+		resourceType := resourceTypes[i%len(resourceTypes)]
+		collection.AddResource(ResourceDetails{
+			Id:           resourceType + "-" + fmt.Sprint(i),
+			ResourceType: resourceType,
+			Lookup:       "${octopusdeploy_resource.resource_" + fmt.Sprint(i) + ".id}",
+			FileName:     "space_population/resource_" + fmt.Sprint(i) + ".tf",
+		})
+	}
+	return &collection
+}
+
+// linearGetResource is the unindexed lookup the collection used to perform, and is retained
+// to compare against the indexed lookups in the benchmarks.
+func linearGetResource(c *ResourceDetailsCollection, resourceType string, id string) string {
+	for _, r := range c.Resources {
+		if r.Id == id && r.ResourceType == resourceType {
+			return r.Lookup
+		}
+	}
+
+	return ""
+}
+
+func TestResourceLookups(t *testing.T) {
+	collection := createCollection(100)
+
+	if collection.GetResource("Feeds", "Feeds-2") != "${octopusdeploy_resource.resource_2.id}" {
+		t.Fatal("Expected to find the feed lookup")
+	}
+
+	if collection.GetResource("Projects", "Feeds-2") != "" {
+		t.Fatal("Expected the lookup to match the resource type")
+	}
+
+	if !collection.HasResource("Accounts-3", "Accounts") || collection.HasResource("Accounts-4", "Accounts") {
+		t.Fatal("Expected HasResource to match the resource type and ID")
+	}
+
+	if *collection.GetResourcePointer("Environments", nil) != "" {
+		t.Fatal("Expected an empty lookup for a nil ID")
+	}
+
+	lookups := collection.GetResources("Projects", "Projects-10", "Projects-missing", "Projects-5")
+	if len(lookups) != 2 ||
+		lookups[0] != "${octopusdeploy_resource.resource_10.id}" ||
+		lookups[1] != "${octopusdeploy_resource.resource_5.id}" {
+		t.Fatalf("Expected the lookups to be returned in the order of the supplied IDs, found %v", lookups)
+	}
+
+	if len(collection.GetAllResource("Variables")) != 20 {
+		t.Fatal("Expected to find 20 variables")
+	}
+
+	// Resources retain the order they were added
+	for i, r := range collection.Resources {
+		if r.FileName != "space_population/resource_"+fmt.Sprint(i)+".tf" {
+			t.Fatal("Expected the resources to retain their insertion order")
+		}
+	}
+}
+
+func TestClaimResource(t *testing.T) {
+	collection := ResourceDetailsCollection{}
+	claims := make(chan bool, 100)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, claimed := collection.ClaimResource(context.Background(), "Projects-1", "Projects")
+			claims <- claimed
+		}()
+	}
+	wg.Wait()
+	close(claims)
+
+	successfulClaims := 0
+	for claim := range claims {
+		if claim {
+			successfulClaims++
+		}
+	}
+
+	if successfulClaims != 1 {
+		t.Fatalf("Expected exactly one successful claim, found %d", successfulClaims)
+	}
+
+	if !collection.HasResource("Projects-1", "Projects") {
+		t.Fatal("Expected a claimed resource to be reported as existing")
+	}
+
+	collection.AddResource(ResourceDetails{Id: "Projects-2", ResourceType: "Projects"})
+	if _, claimed := collection.ClaimResource(context.Background(), "Projects-2", "Projects"); claimed {
+		t.Fatal("Expected a resource that was already added to not be claimed")
+	}
+}
+
+func TestClaimResourceParent(t *testing.T) {
+	collection := ResourceDetailsCollection{}
+
+	projectCtx, _ := collection.ClaimResource(context.Background(), "Projects-1", "Projects")
+	lifecycleCtx, _ := collection.ClaimResource(projectCtx, "Lifecycles-1", "Lifecycles")
+	collection.ClaimResource(lifecycleCtx, "Environments-1", "Environments")
+
+	// A resource that was already claimed keeps the parent that claimed it first
+	collection.ClaimResource(projectCtx, "Environments-1", "Environments")
+
+	if _, _, found := collection.GetParent("Projects", "Projects-1"); found {
+		t.Error("Expected the root resource to have no parent")
+	}
+
+	if parentType, parentId, _ := collection.GetParent("Lifecycles", "Lifecycles-1"); parentType != "Projects" || parentId != "Projects-1" {
+		t.Errorf("Expected the lifecycle to be claimed by the project, got %s %s", parentType, parentId)
+	}
+
+	if parentType, parentId, _ := collection.GetParent("Environments", "Environments-1"); parentType != "Lifecycles" || parentId != "Lifecycles-1" {
+		t.Errorf("Expected the environment to be claimed by the lifecycle, got %s %s", parentType, parentId)
+	}
+}
+
+func TestRecordError(t *testing.T) {
+	collection := ResourceDetailsCollection{}
+	err := errors.New("failed")
+
+	if collection.RecordError("Projects", "Projects-1", err) != err || len(collection.Errors()) != 0 {
+		t.Fatal("Expected the error to be returned when ContinueOnError is not set")
+	}
+
+	collection.ContinueOnError = true
+
+	if collection.RecordError("Projects", "Projects-2", err) != nil || collection.RecordError("Feeds", "Feeds-1", err) != nil {
+		t.Fatal("Expected the error to be recorded when ContinueOnError is set")
+	}
+
+	if collection.RecordError("Projects", "Projects-3", nil) != nil {
+		t.Fatal("Expected no error to be recorded for a successful export")
+	}
+
+	if !errors.Is(collection.RecordError("Projects", "Projects-4", fmt.Errorf("request: %w", context.Canceled)), context.Canceled) {
+		t.Fatal("Expected cancellation to stop the export")
+	}
+
+	resourceErrors := collection.Errors()
+	if len(resourceErrors) != 2 || resourceErrors[0].Error() != "Feeds Feeds-1: failed" || resourceErrors[1].Id != "Projects-2" {
+		t.Fatalf("Expected the errors to be recorded in order, found %v", resourceErrors)
+	}
+}
+
+func TestRenderResourceUnresolvedReferences(t *testing.T) {
+	collection := createCollection(10)
+	project := ResourceDetails{
+		Id:           "Projects-100",
+		ResourceType: "Projects",
+		FileName:     "space_population/project.tf",
+		ToHcl: func() (string, error) {
+			return collection.GetResource("Environments", "Environments-1") +
+				collection.GetResource("Environments", "Environments-missing") +
+				collection.GetResource("Environments", "Environments-missing") +
+				collection.GetResource("WorkerPools", "") +
+				*collection.GetResourcePointer("Feeds", nil) +
+				strings.Join(collection.GetResources("Accounts", "Accounts-3", "Accounts-missing"), ""), nil
+		},
+	}
+
+	_, unresolved, err := collection.RenderResource(project)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"space_population/project.tf: Projects Projects-100 references Environments Environments-missing, which was not exported",
+		"space_population/project.tf: Projects Projects-100 references Accounts Accounts-missing, which was not exported",
+	}
+
+	if len(unresolved) != len(expected) {
+		t.Fatalf("Expected %d unresolved references, found %v", len(expected), unresolved)
+	}
+
+	for i, u := range unresolved {
+		if u.String() != expected[i] {
+			t.Errorf("Expected %q, found %q", expected[i], u.String())
+		}
+	}
+
+	if collection.GetResource("Environments", "Environments-missing") != "" {
+		t.Fatal("Expected an empty lookup for a missing resource")
+	}
+
+	if _, found := collection.LookupResource("Environments", "Environments-missing"); found {
+		t.Fatal("Expected LookupResource to report a missing resource")
+	}
+}
+
+func BenchmarkGetResource(b *testing.B) {
+	collection := createCollection(50000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		collection.GetResource("Feeds", "Feeds-49997")
+	}
+}
+
+func BenchmarkLinearGetResource(b *testing.B) {
+	collection := createCollection(50000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearGetResource(collection, "Feeds", "Feeds-49997")
+	}
+}
+
+func BenchmarkHasResource(b *testing.B) {
+	collection := createCollection(50000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		collection.HasResource("Projects-missing", "Projects")
+	}
+}
+
+func BenchmarkGetResources(b *testing.B) {
+	collection := createCollection(50000)
+	ids := []string{}
+	for i := 0; i < 50; i++ {
+		ids = append(ids, "Environments-"+fmt.Sprint(i*5+1))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		collection.GetResources("Environments", ids...)
+	}
+}
+
+func BenchmarkReplaceFeedIds(b *testing.B) {
+	collection := createCollection(50000)
+	properties := map[string]string{}
+	for i := 0; i < 200; i++ {
+		properties["Property"+fmt.Sprint(i)] = "A value referencing Feeds-" + fmt.Sprint(i*5+2)
+	}
+	converter := DeploymentProcessConverter{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		converter.replaceFeedIds(properties, collection)
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	collection := ResourceDetailsCollection{}
+
+	projectCtx, _ := collection.ClaimResource(context.Background(), "Projects-1", "Projects")
+	lifecycleCtx, _ := collection.ClaimResource(projectCtx, "Lifecycles-1", "Lifecycles")
+	collection.ClaimResource(lifecycleCtx, "Environments-1", "Environments")
+
+	// The project also depends on the environment, which was already claimed by the lifecycle
+	collection.AddDependency(projectCtx, "Environments", "Environments-1")
+	collection.ClaimResource(projectCtx, "Environments-1", "Environments")
+
+	// Resources discovered without a parent, and empty IDs, are not dependencies
+	collection.AddDependency(context.Background(), "Environments", "Environments-2")
+	collection.AddDependency(projectCtx, "Environments", "")
+
+	expected := []Dependency{
+		{ResourceType: "Lifecycles", Id: "Lifecycles-1", DependencyType: "Environments", DependencyId: "Environments-1"},
+		{ResourceType: "Projects", Id: "Projects-1", DependencyType: "Environments", DependencyId: "Environments-1"},
+		{ResourceType: "Projects", Id: "Projects-1", DependencyType: "Lifecycles", DependencyId: "Lifecycles-1"},
+	}
+
+	if actual := collection.Dependencies(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestRenderResourceDependencies(t *testing.T) {
+	collection := ResourceDetailsCollection{}
+	collection.AddResource(ResourceDetails{Id: "Lifecycles-1", ResourceType: "Lifecycles", Lookup: "${octopusdeploy_lifecycle.default.id}"})
+
+	project := ResourceDetails{Id: "Projects-1", ResourceType: "Projects"}
+	project.ToHcl = func() (string, error) {
+		return collection.GetResource("Lifecycles", "Lifecycles-1") + collection.GetResource("Environments", "Environments-9"), nil
+	}
+
+	if _, _, err := collection.RenderResource(project); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the resources that were exported are dependencies
+	expected := []Dependency{{ResourceType: "Projects", Id: "Projects-1", DependencyType: "Lifecycles", DependencyId: "Lifecycles-1"}}
+	if actual := collection.Dependencies(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
@@ -0,0 +1,184 @@
+package converters
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/fakeoctopus"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/workerpool"
+)
+
+// createFakeClient starts the fake Octopus API and returns a client for the Default space.
+func createFakeClient(t *testing.T) client.OctopusApiClient {
+	server := fakeoctopus.NewServer()
+	t.Cleanup(server.Close)
+
+	return client.OctopusApiClient{Url: server.URL, Space: "Spaces-1"}
+}
+
+// createFailingClient starts the fake Octopus API, which returns a server error for requests to the failing path,
+// and returns a client for the Default space.
+func createFailingClient(t *testing.T, failingPath string) client.OctopusApiClient {
+	fixtures, err := fs.Sub(fakeoctopus.Fixtures, "fixtures")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := fakeoctopus.NewHandler(fixtures)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == failingPath {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return client.OctopusApiClient{Url: server.URL, Space: "Spaces-1"}
+}
+
+// createConverters wires the converters the same way as the export, returning the converter used to
+// export a space and the converter used to export a single project.
+func createConverters(octopusClient client.OctopusClient) (SpaceConverter, ProjectConverter) {
+	environmentConverter := EnvironmentConverter{Client: octopusClient}
+	lifecycleConverter := LifecycleConverter{Client: octopusClient, EnvironmentConverter: environmentConverter}
+	gitCredentialsConverter := GitCredentialsConverter{Client: octopusClient}
+	tagsetConverter := TagSetConverter{Client: octopusClient}
+	channelConverter := ChannelConverter{Client: octopusClient, LifecycleConverter: lifecycleConverter}
+	projectGroupConverter := ProjectGroupConverter{Client: octopusClient}
+	tenantVariableConverter := TenantVariableConverter{Client: octopusClient}
+	tenantConverter := TenantConverter{
+		Client:                  octopusClient,
+		TenantVariableConverter: tenantVariableConverter,
+		EnvironmentConverter:    environmentConverter,
+		TagSetConverter:         tagsetConverter,
+	}
+	machinePolicyConverter := MachinePolicyConverter{Client: octopusClient}
+	accountConverter := AccountConverter{Client: octopusClient, EnvironmentConverter: environmentConverter, TenantConverter: tenantConverter}
+	certificateConverter := CertificateConverter{Client: octopusClient}
+	feedConverter := FeedConverter{Client: octopusClient}
+	workerPoolConverter := WorkerPoolConverter{Client: octopusClient}
+
+	kubernetesTargetConverter := KubernetesTargetConverter{Client: octopusClient, MachinePolicyConverter: machinePolicyConverter, AccountConverter: accountConverter, CertificateConverter: certificateConverter, EnvironmentConverter: environmentConverter}
+	sshTargetConverter := SshTargetConverter{Client: octopusClient, MachinePolicyConverter: machinePolicyConverter, AccountConverter: accountConverter, EnvironmentConverter: environmentConverter}
+	listeningTargetConverter := ListeningTargetConverter{Client: octopusClient, MachinePolicyConverter: machinePolicyConverter, EnvironmentConverter: environmentConverter}
+	pollingTargetConverter := PollingTargetConverter{Client: octopusClient, MachinePolicyConverter: machinePolicyConverter, EnvironmentConverter: environmentConverter}
+	cloudRegionTargetConverter := CloudRegionTargetConverter{Client: octopusClient, MachinePolicyConverter: machinePolicyConverter, EnvironmentConverter: environmentConverter}
+	offlineDropTargetConverter := OfflineDropTargetConverter{Client: octopusClient, MachinePolicyConverter: machinePolicyConverter, EnvironmentConverter: environmentConverter}
+	azureCloudServiceTargetConverter := AzureCloudServiceTargetConverter{Client: octopusClient, MachinePolicyConverter: machinePolicyConverter, AccountConverter: accountConverter, EnvironmentConverter: environmentConverter}
+	azureServiceFabricTargetConverter := AzureServiceFabricTargetConverter{Client: octopusClient, MachinePolicyConverter: machinePolicyConverter, EnvironmentConverter: environmentConverter}
+	azureWebAppTargetConverter := AzureWebAppTargetConverter{Client: octopusClient, MachinePolicyConverter: machinePolicyConverter, AccountConverter: accountConverter, EnvironmentConverter: environmentConverter}
+
+	variableSetConverter := VariableSetConverter{
+		Client:                            octopusClient,
+		ChannelConverter:                  channelConverter,
+		EnvironmentConverter:              environmentConverter,
+		TagSetConverter:                   tagsetConverter,
+		AzureCloudServiceTargetConverter:  azureCloudServiceTargetConverter,
+		AzureServiceFabricTargetConverter: azureServiceFabricTargetConverter,
+		AzureWebAppTargetConverter:        azureWebAppTargetConverter,
+		CloudRegionTargetConverter:        cloudRegionTargetConverter,
+		KubernetesTargetConverter:         kubernetesTargetConverter,
+		ListeningTargetConverter:          listeningTargetConverter,
+		OfflineDropTargetConverter:        offlineDropTargetConverter,
+		PollingTargetConverter:            pollingTargetConverter,
+		SshTargetConverter:                sshTargetConverter,
+		AccountConverter:                  accountConverter,
+		FeedConverter:                     feedConverter,
+		CertificateConverter:              certificateConverter,
+		WorkerPoolConverter:               workerPoolConverter,
+	}
+	libraryVariableSetConverter := LibraryVariableSetConverter{Client: octopusClient, VariableSetConverter: variableSetConverter}
+
+	projectConverter := ProjectConverter{
+		Client:                      octopusClient,
+		LifecycleConverter:          lifecycleConverter,
+		GitCredentialsConverter:     gitCredentialsConverter,
+		LibraryVariableSetConverter: libraryVariableSetConverter,
+		ProjectGroupConverter:       projectGroupConverter,
+		DeploymentProcessConverter: DeploymentProcessConverter{
+			Client:              octopusClient,
+			FeedConverter:       feedConverter,
+			AccountConverter:    accountConverter,
+			WorkerPoolConverter: workerPoolConverter,
+		},
+		TenantConverter:         tenantConverter,
+		ProjectTriggerConverter: ProjectTriggerConverter{Client: octopusClient},
+		VariableSetConverter:    variableSetConverter,
+		ChannelConverter:        channelConverter,
+	}
+
+	spaceConverter := SpaceConverter{
+		Client:                            octopusClient,
+		AccountConverter:                  accountConverter,
+		EnvironmentConverter:              environmentConverter,
+		LibraryVariableSetConverter:       libraryVariableSetConverter,
+		LifecycleConverter:                lifecycleConverter,
+		WorkerPoolConverter:               workerPoolConverter,
+		TagSetConverter:                   tagsetConverter,
+		GitCredentialsConverter:           gitCredentialsConverter,
+		ProjectGroupConverter:             projectGroupConverter,
+		ProjectConverter:                  projectConverter,
+		TenantConverter:                   tenantConverter,
+		CertificateConverter:              certificateConverter,
+		TenantVariableConverter:           tenantVariableConverter,
+		MachinePolicyConverter:            machinePolicyConverter,
+		KubernetesTargetConverter:         kubernetesTargetConverter,
+		SshTargetConverter:                sshTargetConverter,
+		ListeningTargetConverter:          listeningTargetConverter,
+		PollingTargetConverter:            pollingTargetConverter,
+		CloudRegionTargetConverter:        cloudRegionTargetConverter,
+		OfflineDropTargetConverter:        offlineDropTargetConverter,
+		AzureCloudServiceTargetConverter:  azureCloudServiceTargetConverter,
+		AzureServiceFabricTargetConverter: azureServiceFabricTargetConverter,
+		AzureWebAppTargetConverter:        azureWebAppTargetConverter,
+		FeedConverter:                     feedConverter,
+	}
+
+	return spaceConverter, projectConverter
+}
+
+// newTestCollection returns an empty collection that discovers resources concurrently.
+func newTestCollection() *ResourceDetailsCollection {
+	return &ResourceDetailsCollection{WorkerPool: workerpool.NewWorkerPool(4)}
+}
+
+// toFileMap renders the discovered resources with RenderFiles, like the export, returning file names mapped to
+// content. The fixtures hold every dependency, so any reference to a resource that was not exported fails the test.
+func toFileMap(t *testing.T, dependencies *ResourceDetailsCollection) map[string]string {
+	files, unresolved, err := dependencies.RenderFiles(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, u := range unresolved {
+		t.Errorf("unexpected unresolved reference: %s", u)
+	}
+
+	return files
+}
+
+// assertFileContains checks the file exists and contains each of the expected strings.
+func assertFileContains(t *testing.T, files map[string]string, fileName string, expected ...string) {
+	t.Helper()
+
+	content, ok := files[fileName]
+
+	if !ok {
+		t.Fatalf("expected the file %s to be exported", fileName)
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(content, e) {
+			t.Errorf("expected %s to contain %q, but it was:\n%s", fileName, e, content)
+		}
+	}
+}
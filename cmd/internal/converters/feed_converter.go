@@ -1,16 +1,12 @@
 package converters
 
 import (
-	"errors"
-	"fmt"
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/logger"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
 )
 
@@ -22,46 +18,53 @@ func (c FeedConverter) GetResourceType() string {
 	return "Feeds"
 }
 
-func (c FeedConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c FeedConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.Feed]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, resource := range collection.Items {
-		err = c.toHcl(resource, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		resource := resource
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), resource.Id, c.toHcl(ctx, resource, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c FeedConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c FeedConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	resource := octopus2.Feed{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &resource)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &resource)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.toHcl(resource, true, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, resource, true, dependencies))
 }
 
-func (c FeedConverter) toHcl(resource octopus2.Feed, recursive bool, dependencies *ResourceDetailsCollection) error {
-	resourceName := "feed_" + sanitizer.SanitizeName(resource.Name)
+func (c FeedConverter) toHcl(ctx context.Context, resource octopus2.Feed, recursive bool, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, resource.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
+	resourceName := dependencies.ResourceName("feed_", c.GetResourceType(), resource.Id, resource.Name)
 	passwordName := resourceName + "_password"
 	password := "${var." + passwordName + "}"
 
@@ -69,6 +72,7 @@ func (c FeedConverter) toHcl(resource octopus2.Feed, recursive bool, dependencie
 
 	thisResource.FileName = "space_population/" + resourceName + ".tf"
 	thisResource.Id = resource.Id
+	thisResource.Name = resource.Name
 	thisResource.ResourceType = c.GetResourceType()
 	if strutil.EmptyIfNil(resource.FeedType) == "BuiltIn" {
 		thisResource.Lookup = "${data.octopusdeploy_feeds.built_in_feed.feeds[0].id}"
@@ -84,6 +88,14 @@ func (c FeedConverter) toHcl(resource octopus2.Feed, recursive bool, dependencie
 		thisResource.Lookup = "${octopusdeploy_helm_feed." + resourceName + ".id}"
 	} else if strutil.EmptyIfNil(resource.FeedType) == "NuGet" {
 		thisResource.Lookup = "${octopusdeploy_nuget_feed." + resourceName + ".id}"
+	} else if strutil.EmptyIfNil(resource.FeedType) != "OctopusProject" {
+		logger.Debug("skipping a feed with an unsupported type", "id", resource.Id, "name", resource.Name, "feedType", strutil.EmptyIfNil(resource.FeedType))
+		dependencies.AddWarning(ResourceWarning{
+			ResourceType: c.GetResourceType(),
+			Id:           resource.Id,
+			Name:         resource.Name,
+			Message:      "The feed was not exported because the feed type " + strutil.EmptyIfNil(resource.FeedType) + " is not supported",
+		})
 	}
 	thisResource.ToHcl = func() (string, error) {
 
@@ -95,10 +107,10 @@ func (c FeedConverter) toHcl(resource octopus2.Feed, recursive bool, dependencie
 				Skip:     0,
 				Take:     1,
 			}
-			file := hclwrite.NewEmptyFile()
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "data"))
+			file := hcl.NewFile()
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "data"))
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if strutil.EmptyIfNil(resource.FeedType) == "Docker" {
@@ -122,25 +134,21 @@ func (c FeedConverter) toHcl(resource octopus2.Feed, recursive bool, dependencie
 				Description: "The password used by the feed " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_docker_container_registry." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_docker_container_registry." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if strutil.EmptyIfNil(resource.FeedType) == "AwsElasticContainerRegistry" {
@@ -162,25 +170,21 @@ func (c FeedConverter) toHcl(resource octopus2.Feed, recursive bool, dependencie
 				Description: "The password used by the feed " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_aws_elastic_container_registry." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_aws_elastic_container_registry." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if strutil.EmptyIfNil(resource.FeedType) == "Maven" {
@@ -204,25 +208,21 @@ func (c FeedConverter) toHcl(resource octopus2.Feed, recursive bool, dependencie
 				Description: "The password used by the feed " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_maven_feed." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_maven_feed." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if strutil.EmptyIfNil(resource.FeedType) == "GitHub" {
@@ -246,25 +246,21 @@ func (c FeedConverter) toHcl(resource octopus2.Feed, recursive bool, dependencie
 				Description: "The password used by the feed " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_github_repository_feed." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_github_repository_feed." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if strutil.EmptyIfNil(resource.FeedType) == "Helm" {
@@ -286,25 +282,21 @@ func (c FeedConverter) toHcl(resource octopus2.Feed, recursive bool, dependencie
 				Description: "The password used by the feed " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_helm_feed." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_helm_feed." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if strutil.EmptyIfNil(resource.FeedType) == "NuGet" {
@@ -329,25 +321,21 @@ func (c FeedConverter) toHcl(resource octopus2.Feed, recursive bool, dependencie
 				Description: "The password used by the feed " + resource.Name,
 			}
 
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_nuget_feed." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_nuget_feed." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
 
 		if strutil.EmptyIfNil(resource.FeedType) == "OctopusProject" {
@@ -355,8 +343,7 @@ func (c FeedConverter) toHcl(resource octopus2.Feed, recursive bool, dependencie
 			return "", nil
 		}
 
-		fmt.Println(errors.New("Found unexpected feed type \"" + strutil.EmptyIfNil(resource.FeedType) + "\" with name \"" + resource.Name + "\"."))
-
+		// Unexpected feed types are reported when the feed is discovered
 		return "", nil
 	}
 
@@ -1,14 +1,11 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
 )
 
@@ -17,51 +14,58 @@ type GitCredentialsConverter struct {
 	SpaceResourceName string
 }
 
-func (c GitCredentialsConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c GitCredentialsConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.GitCredentials]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, gitCredentials := range collection.Items {
-		err = c.toHcl(gitCredentials, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		gitCredentials := gitCredentials
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), gitCredentials.Id, c.toHcl(ctx, gitCredentials, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c GitCredentialsConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c GitCredentialsConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	gitCredentials := octopus2.GitCredentials{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &gitCredentials)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &gitCredentials)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.toHcl(gitCredentials, true, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, gitCredentials, true, dependencies))
 }
 
-func (c GitCredentialsConverter) toHcl(gitCredentials octopus2.GitCredentials, recursive bool, dependencies *ResourceDetailsCollection) error {
+func (c GitCredentialsConverter) toHcl(ctx context.Context, gitCredentials octopus2.GitCredentials, recursive bool, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, gitCredentials.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
 
-	gitCredentialsName := "gitcredential_" + sanitizer.SanitizeName(gitCredentials.Name)
+	gitCredentialsName := dependencies.ResourceName("gitcredential_", c.GetResourceType(), gitCredentials.Id, gitCredentials.Name)
 
 	thisResource := ResourceDetails{}
 	thisResource.FileName = "space_population/" + gitCredentialsName + ".tf"
 	thisResource.Id = gitCredentials.Id
+	thisResource.Name = gitCredentials.Name
 	thisResource.ResourceType = c.GetResourceType()
 	thisResource.Lookup = "${octopusdeploy_git_credential." + gitCredentialsName + ".id}"
 	thisResource.ToHcl = func() (string, error) {
@@ -75,19 +79,15 @@ func (c GitCredentialsConverter) toHcl(gitCredentials octopus2.GitCredentials, r
 			Username:     gitCredentials.Details.Username,
 			Password:     "${var." + gitCredentialsName + "}",
 		}
-		file := hclwrite.NewEmptyFile()
+		file := hcl.NewFile()
 
 		// Add a comment with the import command
-		baseUrl, _ := c.Client.GetSpaceBaseUrl()
-		file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-			Type: hclsyntax.TokenComment,
-			Bytes: []byte("# Import existing resources with the following commands:\n" +
-				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + gitCredentials.Name + "\") | .Id')\n" +
-				"# terraform import octopusdeploy_git_credential." + gitCredentialsName + " ${RESOURCE_ID}\n"),
-			SpacesBefore: 0,
-		}})
+		baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+		file.AppendComment("# Import existing resources with the following commands:\n" +
+			"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + gitCredentials.Name + "\") | .Id')\n" +
+			"# terraform import octopusdeploy_git_credential." + gitCredentialsName + " ${RESOURCE_ID}\n")
 
-		file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
+		file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
 
 		secretVariableResource := terraform2.TerraformVariable{
 			Name:        gitCredentialsName,
@@ -97,11 +97,11 @@ func (c GitCredentialsConverter) toHcl(gitCredentials octopus2.GitCredentials, r
 			Description: "The secret variable value associated with the git credential \"" + gitCredentials.Name + "\"",
 		}
 
-		block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+		block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 		hcl.WriteUnquotedAttribute(block, "type", "string")
-		file.Body().AppendBlock(block)
+		file.AppendBlock(block)
 
-		return string(file.Bytes()), nil
+		return file.Render(dependencies.Format)
 	}
 
 	dependencies.AddResource(thisResource)
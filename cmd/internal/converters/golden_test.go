@@ -0,0 +1,108 @@
+package converters
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/golden"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/validation"
+)
+
+// goldenUrl replaces the random address of the fake server in the generated files.
+const goldenUrl = "https://octopus.example"
+
+// TestGoldenFiles compares the exported files with the expected output in testdata/golden.
+// Run go test -run TestGoldenFiles -update to regenerate the expected output.
+func TestGoldenFiles(t *testing.T) {
+	testCases := []struct {
+		name   string
+		layout string
+		format string
+		export func(ctx context.Context, spaceConverter SpaceConverter, projectConverter ProjectConverter, dependencies *ResourceDetailsCollection) error
+	}{
+		{
+			name: "space",
+			export: func(ctx context.Context, spaceConverter SpaceConverter, projectConverter ProjectConverter, dependencies *ResourceDetailsCollection) error {
+				return spaceConverter.ToHcl(ctx, dependencies)
+			},
+		},
+		{
+			name: "project",
+			export: func(ctx context.Context, spaceConverter SpaceConverter, projectConverter ProjectConverter, dependencies *ResourceDetailsCollection) error {
+				TerraformProviderGenerator{}.ToHcl("space_population", dependencies)
+				return projectConverter.ToHclById(ctx, "Projects-1", dependencies)
+			},
+		},
+		{
+			// The layout and the Terraform JSON syntax change the file names and content of the same export
+			name:   "project_type_tfjson",
+			layout: LayoutType,
+			format: hcl.FormatTfJson,
+			export: func(ctx context.Context, spaceConverter SpaceConverter, projectConverter ProjectConverter, dependencies *ResourceDetailsCollection) error {
+				TerraformProviderGenerator{}.ToHcl("space_population", dependencies)
+				return projectConverter.ToHclById(ctx, "Projects-1", dependencies)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			octopusClient := createFakeClient(t)
+			spaceConverter, projectConverter := createConverters(octopusClient)
+			dependencies := newTestCollection()
+			dependencies.Layout = testCase.layout
+			dependencies.Format = testCase.format
+
+			if err := testCase.export(context.Background(), spaceConverter, projectConverter, dependencies); err != nil {
+				t.Fatal(err)
+			}
+
+			files := toFileMap(t, dependencies)
+			for name, content := range files {
+				files[name] = strings.ReplaceAll(content, octopusClient.Url, goldenUrl)
+			}
+
+			if err := validation.ValidateFiles(files); err != nil {
+				t.Error(err)
+			}
+
+			golden.AssertFiles(t, filepath.Join("testdata", "golden", testCase.name), files)
+		})
+	}
+}
+
+// TestExportIsDeterministic exports the same space twice, and expects the files to be identical, so re-exporting a
+// space that has not changed produces no diff.
+func TestExportIsDeterministic(t *testing.T) {
+	octopusClient := createFakeClient(t)
+
+	for _, format := range []string{hcl.FormatHcl, hcl.FormatTfJson} {
+		exports := []map[string]string{}
+
+		for i := 0; i < 2; i++ {
+			spaceConverter, _ := createConverters(octopusClient)
+			dependencies := newTestCollection()
+			dependencies.Format = format
+
+			if err := spaceConverter.ToHcl(context.Background(), dependencies); err != nil {
+				t.Fatal(err)
+			}
+
+			exports = append(exports, toFileMap(t, dependencies))
+		}
+
+		if len(exports[0]) != len(exports[1]) {
+			t.Fatalf("expected the %s exports to have the same files, but found %d and %d files", format, len(exports[0]), len(exports[1]))
+		}
+
+		for name, content := range exports[0] {
+			if exports[1][name] != content {
+				t.Errorf("expected %s to be identical in both %s exports, but it was:\n%s\nand:\n%s", name, format, content, exports[1][name])
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package converters
+
+import (
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	// LayoutResource writes each resource to its own file
+	LayoutResource = "resource"
+	// LayoutType writes the resources of each type to one file, like projects.tf and variables.tf
+	LayoutType = "type"
+	// LayoutProject writes each project to one file with its deployment process, channels, triggers and variables.
+	// The other resources are written to one file per type. Terraform only loads the files in one directory as a
+	// module, so a directory per project would have to be a child module, and every reference between a project and
+	// the shared resources would have to be passed in as a variable or out as an output. A file per project keeps
+	// the resources in the one module.
+	LayoutProject = "project"
+	// LayoutSingle writes all the resources in a directory to main.tf
+	LayoutSingle = "single"
+)
+
+// projectResourceTypes are the resources that are written with the project that owns them by LayoutProject
+var projectResourceTypes = map[string]bool{
+	"Channels":            true,
+	"DeploymentProcesses": true,
+	"Variables":           true,
+}
+
+// triggerResourceType matches the type of a project trigger, which includes the ID of the project
+var triggerResourceType = regexp.MustCompile(`^Projects/([^/]+)/Triggers$`)
+
+// wordBoundary matches the start of each word in a resource type like DeploymentProcesses or Git-Credentials
+var wordBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])|-`)
+
+// FileName returns the name of the file the resource is written to. The converters name each resource's file as
+// if it was written with LayoutResource, and the file name is changed here to match the Layout. The .tf extension
+// is replaced with .tf.json when the resources are rendered in the Terraform JSON syntax.
+func (c *ResourceDetailsCollection) FileName(resource ResourceDetails) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.fileName(resource)
+}
+
+// fileName implements FileName. The caller must hold the lock.
+func (c *ResourceDetailsCollection) fileName(resource ResourceDetails) string {
+	fileName := c.names.Resolve(c.layoutFileName(resource))
+
+	if c.Format != hcl.FormatTfJson || !strings.HasSuffix(fileName, ".tf") {
+		return fileName
+	}
+
+	return strings.TrimSuffix(fileName, ".tf") + hcl.FileExtension(c.Format)
+}
+
+func (c *ResourceDetailsCollection) layoutFileName(resource ResourceDetails) string {
+	// Resources without a type, like the provider configuration, keep their own files unless everything is
+	// written to main.tf. Files that are not Terraform configuration, like the backend settings, are never combined.
+	if resource.FileName == "" || (resource.ResourceType == "" && (c.Layout != LayoutSingle || path.Ext(resource.FileName) != ".tf")) {
+		return resource.FileName
+	}
+
+	directory := path.Dir(resource.FileName)
+
+	switch c.Layout {
+	case LayoutType:
+		return directory + "/" + typeFileName(resource.ResourceType)
+	case LayoutProject:
+		if resource.ResourceType == "Projects" {
+			return resource.FileName
+		}
+		if project, found := c.owningProject(resource); found {
+			return project.FileName
+		}
+		return directory + "/" + typeFileName(resource.ResourceType)
+	case LayoutSingle:
+		return directory + "/main.tf"
+	}
+
+	return resource.FileName
+}
+
+// typeFileName returns the file holding the resources of the type, like deployment_processes.tf for
+// DeploymentProcesses. Types that belong to another resource, like Projects/Projects-1/Triggers, are named after
+// the last segment, and types like TenantVariables/All are named after the first segment.
+func typeFileName(resourceType string) string {
+	segments := strings.Split(resourceType, "/")
+	name := segments[0]
+	if len(segments) == 3 {
+		name = segments[2]
+	}
+
+	return strings.ToLower(wordBoundary.ReplaceAllString(name, "${1}_${2}")) + ".tf"
+}
+
+// owningProject returns the project that a deployment process, channel, trigger or project variable belongs to.
+// Library variable set variables are not owned by a project. The caller must hold the lock.
+func (c *ResourceDetailsCollection) owningProject(resource ResourceDetails) (ResourceDetails, bool) {
+	if match := triggerResourceType.FindStringSubmatch(resource.ResourceType); match != nil {
+		return c.renderedResource(resourceKey{resourceType: "Projects", id: match[1]})
+	}
+
+	if !projectResourceTypes[resource.ResourceType] {
+		return ResourceDetails{}, false
+	}
+
+	// Walk up to the closest rendered resource, skipping resources like variable sets that are not rendered
+	key := resourceKey{resourceType: resource.ResourceType, id: resource.Id}
+	for {
+		parent, found := c.parents[key]
+		if !found {
+			return ResourceDetails{}, false
+		}
+
+		if rendered, found := c.renderedResource(parent); found {
+			return rendered, parent.resourceType == "Projects"
+		}
+
+		key = parent
+	}
+}
+
+// renderedResource returns the resource with the key, if it is rendered to its own block. The caller must hold
+// the lock.
+func (c *ResourceDetailsCollection) renderedResource(key resourceKey) (ResourceDetails, bool) {
+	if position, found := c.byKey[key]; found && c.Resources[position].ToHcl != nil {
+		return c.Resources[position], true
+	}
+
+	return ResourceDetails{}, false
+}
@@ -0,0 +1,102 @@
+package converters
+
+import (
+	"context"
+	"testing"
+)
+
+func newLayoutCollection(layout string, format string) (*ResourceDetailsCollection, map[string]ResourceDetails) {
+	collection := &ResourceDetailsCollection{Layout: layout, Format: format}
+	render := func() (string, error) { return "", nil }
+
+	resources := map[string]ResourceDetails{
+		"provider":        {FileName: "space_population/provider.tf", ToHcl: render},
+		"backend":         {FileName: "space_population/backend.tfbackend", ToHcl: render},
+		"project":         {Id: "Projects-1", ResourceType: "Projects", FileName: "space_population/project_web_app.tf", ToHcl: render},
+		"process":         {Id: "deploymentprocess-Projects-1", ResourceType: "DeploymentProcesses", FileName: "space_population/deployment_process_web_app.tf", ToHcl: render},
+		"trigger":         {Id: "ProjectTriggers-1", ResourceType: "Projects/Projects-1/Triggers", FileName: "space_population/projecttrigger_web_app.tf", ToHcl: render},
+		"projectVariable": {Id: "Variables-1", ResourceType: "Variables", FileName: "space_population/project_variable_web_app_name.tf", ToHcl: render},
+		"library":         {Id: "LibraryVariableSets-1", ResourceType: "LibraryVariableSets", FileName: "space_population/library_variable_set_shared.tf", ToHcl: render},
+		"libraryVariable": {Id: "Variables-2", ResourceType: "Variables", FileName: "space_population/project_variable_shared_url.tf", ToHcl: render},
+		"credentials":     {Id: "GitCredentials-1", ResourceType: "Git-Credentials", FileName: "space_population/gitcredential_github.tf", ToHcl: render},
+		"space":           {Id: "Spaces-1", ResourceType: "Spaces", FileName: "space_creation/space_default.tf", ToHcl: render},
+	}
+
+	projectCtx, _ := collection.ClaimResource(context.Background(), "Projects-1", "Projects")
+	collection.ClaimResource(projectCtx, "deploymentprocess-Projects-1", "DeploymentProcesses")
+	// Project variables are claimed through the project's variable set, which is not rendered
+	variableSetCtx, _ := collection.ClaimResource(projectCtx, "variableset-Projects-1", "VariableSets")
+	collection.AddChildResource(variableSetCtx, resources["projectVariable"])
+	libraryCtx, _ := collection.ClaimResource(projectCtx, "LibraryVariableSets-1", "LibraryVariableSets")
+	collection.AddChildResource(libraryCtx, resources["libraryVariable"])
+
+	for name, resource := range resources {
+		if name != "projectVariable" && name != "libraryVariable" {
+			collection.AddResource(resource)
+		}
+	}
+
+	return collection, resources
+}
+
+func TestFileNameLayouts(t *testing.T) {
+	tests := []struct {
+		layout   string
+		format   string
+		expected map[string]string
+	}{
+		{
+			layout: LayoutResource,
+			expected: map[string]string{
+				"provider":        "space_population/provider.tf",
+				"process":         "space_population/deployment_process_web_app.tf",
+				"libraryVariable": "space_population/project_variable_shared_url.tf",
+			},
+		},
+		{
+			layout: LayoutType,
+			expected: map[string]string{
+				"provider":        "space_population/provider.tf",
+				"project":         "space_population/projects.tf",
+				"process":         "space_population/deployment_processes.tf",
+				"trigger":         "space_population/triggers.tf",
+				"projectVariable": "space_population/variables.tf",
+				"libraryVariable": "space_population/variables.tf",
+				"credentials":     "space_population/git_credentials.tf",
+				"space":           "space_creation/spaces.tf",
+			},
+		},
+		{
+			layout: LayoutProject,
+			expected: map[string]string{
+				"provider":        "space_population/provider.tf",
+				"project":         "space_population/project_web_app.tf",
+				"process":         "space_population/project_web_app.tf",
+				"trigger":         "space_population/project_web_app.tf",
+				"projectVariable": "space_population/project_web_app.tf",
+				"library":         "space_population/library_variable_sets.tf",
+				"libraryVariable": "space_population/variables.tf",
+			},
+		},
+		{
+			layout: LayoutSingle,
+			format: "tfjson",
+			expected: map[string]string{
+				"provider": "space_population/main.tf.json",
+				"backend":  "space_population/backend.tfbackend",
+				"project":  "space_population/main.tf.json",
+				"space":    "space_creation/main.tf.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		collection, resources := newLayoutCollection(test.layout, test.format)
+
+		for name, expected := range test.expected {
+			if actual := collection.FileName(resources[name]); actual != expected {
+				t.Errorf("expected the %s to be written to %s with the %s layout, got %s", name, expected, test.layout, actual)
+			}
+		}
+	}
+}
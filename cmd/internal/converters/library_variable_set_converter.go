@@ -1,14 +1,12 @@
 package converters
 
 import (
+	"context"
 	"fmt"
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
 	"strings"
 )
@@ -18,56 +16,63 @@ type LibraryVariableSetConverter struct {
 	VariableSetConverter ConverterByIdWithNameAndParent
 }
 
-func (c LibraryVariableSetConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c LibraryVariableSetConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.LibraryVariableSet]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, resource := range collection.Items {
-		err = c.toHcl(resource, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		resource := resource
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), resource.Id, c.toHcl(ctx, resource, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c LibraryVariableSetConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c LibraryVariableSetConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	resource := octopus2.LibraryVariableSet{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &resource)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &resource)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.toHcl(resource, true, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, resource, true, dependencies))
 }
 
-func (c LibraryVariableSetConverter) toHcl(resource octopus2.LibraryVariableSet, recursive bool, dependencies *ResourceDetailsCollection) error {
+func (c LibraryVariableSetConverter) toHcl(ctx context.Context, resource octopus2.LibraryVariableSet, recursive bool, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, resource.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
 	thisResource := ResourceDetails{}
 
-	resourceName := "library_variable_set_" + sanitizer.SanitizeName(resource.Name)
+	resourceName := dependencies.ResourceName("library_variable_set_", c.GetResourceType(), resource.Id, resource.Name)
 
 	// The templates are dependencies that we export as part of the project
 	projectTemplates, projectTemplateMap := c.convertTemplates(resource.Templates, resourceName)
-	dependencies.AddResource(projectTemplateMap...)
+	dependencies.AddChildResource(ctx, projectTemplateMap...)
 
 	// The project group is a dependency that we need to lookup regardless of whether recursive is set
 	if strutil.EmptyIfNil(resource.ContentType) == "Variables" {
-		err := c.VariableSetConverter.ToHclByIdAndName(resource.VariableSetId, resourceName, "${octopusdeploy_library_variable_set."+resourceName+".id}", dependencies)
+		err := c.VariableSetConverter.ToHclByIdAndName(ctx, resource.VariableSetId, resourceName, "${octopusdeploy_library_variable_set."+resourceName+".id}", dependencies)
 
 		if err != nil {
 			return err
@@ -75,12 +80,42 @@ func (c LibraryVariableSetConverter) toHcl(resource octopus2.LibraryVariableSet,
 	}
 
 	thisResource.FileName = "space_population/" + resourceName + ".tf"
+
+	// The script of a script module is held in the variables of the library variable set
+	script := ""
+	scriptLanguage := ""
+	scriptFile := ""
+	if strutil.EmptyIfNil(resource.ContentType) == "ScriptModule" {
+		variable := octopus2.VariableSet{}
+		_, err := c.Client.GetResourceById(ctx, "Variables", resource.VariableSetId, &variable)
+
+		if err != nil {
+			return err
+		}
+
+		for _, u := range variable.Variables {
+			if u.Name == "Octopus.Script.Module["+resource.Name+"]" {
+				script = strings.Clone(*u.Value)
+			}
+
+			if u.Name == "Octopus.Script.Module.Language["+resource.Name+"]" {
+				scriptLanguage = strings.Clone(*u.Value)
+			}
+		}
+
+		scriptFile, _ = addScriptFile(thisResource.FileName, resourceName, scriptExtensions[scriptLanguage], script, dependencies)
+	}
+
 	thisResource.Id = resource.Id
+	thisResource.Name = resource.Name
 	thisResource.ResourceType = c.GetResourceType()
 	thisResource.Lookup = "${octopusdeploy_library_variable_set." + resourceName + ".id}"
+	if strutil.EmptyIfNil(resource.ContentType) == "ScriptModule" {
+		thisResource.Lookup = "${octopusdeploy_script_module." + resourceName + ".id}"
+	}
 	thisResource.ToHcl = func() (string, error) {
 
-		file := hclwrite.NewEmptyFile()
+		file := hcl.NewFile()
 
 		if strutil.EmptyIfNil(resource.ContentType) == "Variables" {
 			terraformResource := terraform2.TerraformLibraryVariableSet{
@@ -92,24 +127,16 @@ func (c LibraryVariableSetConverter) toHcl(resource octopus2.LibraryVariableSet,
 			}
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_library_variable_set." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_library_variable_set." + resourceName + " ${RESOURCE_ID}\n")
 
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
 
 			// Add a data lookup to allow projects to quickly switch to using existing environments
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# To use an existing environment, delete the resource above and use the following lookup instead:\n" +
-					"# data.octopusdeploy_library_variable_sets." + resourceName + ".library_variable_sets[0].id\n"),
-				SpacesBefore: 0,
-			}})
+			file.AppendComment("# To use an existing environment, delete the resource above and use the following lookup instead:\n" +
+				"# data.octopusdeploy_library_variable_sets." + resourceName + ".library_variable_sets[0].id\n")
 			terraformDataResource := terraform2.TerraformLibraryVariableSetData{
 				Type:        "octopusdeploy_library_variable_sets",
 				Name:        resourceName,
@@ -118,29 +145,10 @@ func (c LibraryVariableSetConverter) toHcl(resource octopus2.LibraryVariableSet,
 				Skip:        0,
 				Take:        1,
 			}
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformDataResource, "data"))
+			file.AppendBlock(hcl.EncodeAsBlock(terraformDataResource, "data"))
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		} else if strutil.EmptyIfNil(resource.ContentType) == "ScriptModule" {
-			variable := octopus2.VariableSet{}
-			_, err := c.Client.GetResourceById("Variables", resource.VariableSetId, &variable)
-
-			if err != nil {
-				return "", err
-			}
-
-			script := ""
-			scriptLanguage := ""
-			for _, u := range variable.Variables {
-				if u.Name == "Octopus.Script.Module["+resource.Name+"]" {
-					script = strings.Clone(*u.Value)
-				}
-
-				if u.Name == "Octopus.Script.Module.Language["+resource.Name+"]" {
-					scriptLanguage = strings.Clone(*u.Value)
-				}
-			}
-
 			terraformResource := terraform2.TerraformScriptModule{
 				Type:         "octopusdeploy_script_module",
 				Name:         resourceName,
@@ -153,17 +161,17 @@ func (c LibraryVariableSetConverter) toHcl(resource octopus2.LibraryVariableSet,
 			}
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_script_module." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-			return string(file.Bytes()), nil
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + resource.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_script_module." + resourceName + " ${RESOURCE_ID}\n")
+
+			block := hcl.EncodeAsBlock(terraformResource, "resource")
+			if scriptFile != "" {
+				hcl.WriteFileAttribute(block, "script", "body", scriptFile)
+			}
+			file.AppendBlock(block)
+			return file.Render(dependencies.Format)
 		}
 
 		return "", nil
@@ -1,13 +1,11 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 )
 
 type LifecycleConverter struct {
@@ -15,60 +13,66 @@ type LifecycleConverter struct {
 	EnvironmentConverter ConverterById
 }
 
-func (c LifecycleConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c LifecycleConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.Lifecycle]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, resource := range collection.Items {
-		err = c.toHcl(resource, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		resource := resource
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), resource.Id, c.toHcl(ctx, resource, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c LifecycleConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c LifecycleConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	// Channels can have empty strings for the lifecycle ID
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	lifecycle := octopus2.Lifecycle{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &lifecycle)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &lifecycle)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.toHcl(lifecycle, true, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, lifecycle, true, dependencies))
 
 }
 
-func (c LifecycleConverter) toHcl(lifecycle octopus2.Lifecycle, recursive bool, dependencies *ResourceDetailsCollection) error {
+func (c LifecycleConverter) toHcl(ctx context.Context, lifecycle octopus2.Lifecycle, recursive bool, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, lifecycle.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
 
 	if recursive {
 		// The environments are a dependency that we need to lookup
 		for _, phase := range lifecycle.Phases {
 			for _, auto := range phase.AutomaticDeploymentTargets {
-				err := c.EnvironmentConverter.ToHclById(auto, dependencies)
+				err := c.EnvironmentConverter.ToHclById(ctx, auto, dependencies)
 
 				if err != nil {
 					return err
 				}
 			}
 			for _, optional := range phase.OptionalDeploymentTargets {
-				err := c.EnvironmentConverter.ToHclById(optional, dependencies)
+				err := c.EnvironmentConverter.ToHclById(ctx, optional, dependencies)
 
 				if err != nil {
 					return err
@@ -77,11 +81,12 @@ func (c LifecycleConverter) toHcl(lifecycle octopus2.Lifecycle, recursive bool,
 		}
 	}
 
-	resourceName := "lifecycle_" + sanitizer.SanitizeName(lifecycle.Name)
+	resourceName := dependencies.ResourceName("lifecycle_", c.GetResourceType(), lifecycle.Id, lifecycle.Name)
 
 	thisResource := ResourceDetails{}
 	thisResource.FileName = "space_population/" + resourceName + ".tf"
 	thisResource.Id = lifecycle.Id
+	thisResource.Name = lifecycle.Name
 	thisResource.ResourceType = c.GetResourceType()
 	if lifecycle.Name == "Default Lifecycle" {
 		thisResource.Lookup = "${data.octopusdeploy_lifecycles." + resourceName + ".lifecycles[0].id}"
@@ -99,10 +104,10 @@ func (c LifecycleConverter) toHcl(lifecycle octopus2.Lifecycle, recursive bool,
 				Skip:        0,
 				Take:        1,
 			}
-			file := hclwrite.NewEmptyFile()
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(data, "data"))
+			file := hcl.NewFile()
+			file.AppendBlock(hcl.EncodeAsBlock(data, "data"))
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		} else {
 			terraformResource := terraform2.TerraformLifecycle{
 				Type:                    "octopusdeploy_lifecycle",
@@ -113,21 +118,17 @@ func (c LifecycleConverter) toHcl(lifecycle octopus2.Lifecycle, recursive bool,
 				ReleaseRetentionPolicy:  c.convertPolicy(lifecycle.ReleaseRetentionPolicy),
 				TentacleRetentionPolicy: c.convertPolicy(lifecycle.TentacleRetentionPolicy),
 			}
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + lifecycle.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_lifecycle." + resourceName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-			return string(file.Bytes()), nil
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + lifecycle.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_lifecycle." + resourceName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+			return file.Render(dependencies.Format)
 		}
 	}
 
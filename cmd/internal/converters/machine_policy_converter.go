@@ -1,13 +1,11 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 	"strconv"
 	"strings"
 	"time"
@@ -17,51 +15,58 @@ type MachinePolicyConverter struct {
 	Client client.OctopusClient
 }
 
-func (c MachinePolicyConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c MachinePolicyConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.MachinePolicy]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, machinePolicy := range collection.Items {
-		err = c.toHcl(machinePolicy, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		machinePolicy := machinePolicy
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), machinePolicy.Id, c.toHcl(ctx, machinePolicy, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c MachinePolicyConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c MachinePolicyConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	machinePolicy := octopus2.MachinePolicy{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &machinePolicy)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &machinePolicy)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.toHcl(machinePolicy, true, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, machinePolicy, true, dependencies))
 }
 
-func (c MachinePolicyConverter) toHcl(machinePolicy octopus2.MachinePolicy, recursive bool, dependencies *ResourceDetailsCollection) error {
+func (c MachinePolicyConverter) toHcl(ctx context.Context, machinePolicy octopus2.MachinePolicy, recursive bool, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, machinePolicy.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
 
-	policyName := "machinepolicy_" + sanitizer.SanitizeName(machinePolicy.Name)
+	policyName := dependencies.ResourceName("machinepolicy_", c.GetResourceType(), machinePolicy.Id, machinePolicy.Name)
 
 	thisResource := ResourceDetails{}
 	thisResource.FileName = "space_population/" + policyName + ".tf"
 	thisResource.Id = machinePolicy.Id
+	thisResource.Name = machinePolicy.Name
 	thisResource.ResourceType = c.GetResourceType()
 
 	if machinePolicy.Name == "Default Machine Policy" {
@@ -80,10 +85,10 @@ func (c MachinePolicyConverter) toHcl(machinePolicy octopus2.MachinePolicy, recu
 				Skip:        0,
 				Take:        1,
 			}
-			file := hclwrite.NewEmptyFile()
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(data, "data"))
+			file := hcl.NewFile()
+			file.AppendBlock(hcl.EncodeAsBlock(data, "data"))
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		} else {
 
 			terraformResource := terraform2.TerraformMachinePolicy{
@@ -124,21 +129,17 @@ func (c MachinePolicyConverter) toHcl(machinePolicy octopus2.MachinePolicy, recu
 					TentacleUpdateBehavior:  machinePolicy.MachineUpdatePolicy.TentacleUpdateBehavior,
 				},
 			}
-			file := hclwrite.NewEmptyFile()
+			file := hcl.NewFile()
 
 			// Add a comment with the import command
-			baseUrl, _ := c.Client.GetSpaceBaseUrl()
-			file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-				Type: hclsyntax.TokenComment,
-				Bytes: []byte("# Import existing resources with the following commands:\n" +
-					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + machinePolicy.Name + "\") | .Id')\n" +
-					"# terraform import octopusdeploy_machine_policy." + policyName + " ${RESOURCE_ID}\n"),
-				SpacesBefore: 0,
-			}})
-
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-			return string(file.Bytes()), nil
+			baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+			file.AppendComment("# Import existing resources with the following commands:\n" +
+				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + machinePolicy.Name + "\") | .Id')\n" +
+				"# terraform import octopusdeploy_machine_policy." + policyName + " ${RESOURCE_ID}\n")
+
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+			return file.Render(dependencies.Format)
 		}
 	}
 
@@ -0,0 +1,209 @@
+package converters
+
+import (
+	"context"
+	"errors"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
+	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// moduleVariablePrefixes maps the dependencies of a project that are passed to a project module as input variables
+// to the prefix of the variable names.
+var moduleVariablePrefixes = map[string]string{
+	"Accounts":      "account",
+	"Environments":  "environment",
+	"Feeds":         "feed",
+	"Lifecycles":    "lifecycle",
+	"ProjectGroups": "project_group",
+	"WorkerPools":   "worker_pool",
+}
+
+// IsModuleInput returns true if resources of the type are passed to a project module as input variables.
+func IsModuleInput(resourceType string) bool {
+	_, ok := moduleVariablePrefixes[resourceType]
+	return ok
+}
+
+// ModuleInput is a dependency of a project module that is passed to the module as an input variable.
+type ModuleInput struct {
+	Variable     string
+	ResourceType string
+	Id           string
+	Name         string
+}
+
+// ModuleInputs collects the input variables of a project module as the dependencies of the project are discovered.
+type ModuleInputs struct {
+	mu     sync.Mutex
+	inputs []ModuleInput
+}
+
+func (m *ModuleInputs) add(input ModuleInput) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inputs = append(m.inputs, input)
+}
+
+// Sorted returns the input variables ordered by name, with the names returned by ResourceName resolved.
+func (m *ModuleInputs) Sorted(dependencies *ResourceDetailsCollection) []ModuleInput {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sorted := append([]ModuleInput{}, m.inputs...)
+	for i := range sorted {
+		sorted[i].Variable = dependencies.ResolveNames(sorted[i].Variable)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Variable < sorted[j].Variable
+	})
+	return sorted
+}
+
+// ModuleVariableConverter replaces a dependency of a project module, like an environment or a feed, with an input
+// variable holding the ID of the dependency. This allows the module to be applied to spaces that hold their own
+// copies of the dependencies.
+type ModuleVariableConverter struct {
+	Client       client.OctopusClient
+	ResourceType string
+	Inputs       *ModuleInputs
+}
+
+func (c ModuleVariableConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
+	if id == "" {
+		return nil
+	}
+
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
+	if dependencies.HasResource(id, c.GetResourceType()) {
+		return nil
+	}
+
+	resource := octopus2.NamedResource{}
+	found, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &resource)
+
+	if err != nil {
+		return dependencies.RecordError(c.GetResourceType(), id, err)
+	}
+
+	if !found {
+		return nil
+	}
+
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, resource, dependencies))
+}
+
+func (c ModuleVariableConverter) toHcl(ctx context.Context, resource octopus2.NamedResource, dependencies *ResourceDetailsCollection) error {
+	_, claimed := dependencies.ClaimResource(ctx, resource.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
+	prefix, ok := moduleVariablePrefixes[c.GetResourceType()]
+	if !ok {
+		return errors.New("the resource type " + c.GetResourceType() + " can not be passed to a module")
+	}
+
+	variableName := dependencies.ResourceName(prefix+"_", c.GetResourceType(), resource.Id, resource.Name) + "_id"
+	description := "The ID of the " + strings.Replace(prefix, "_", " ", -1) + " " + resource.Name
+
+	c.Inputs.add(ModuleInput{
+		Variable:     variableName,
+		ResourceType: c.GetResourceType(),
+		Id:           resource.Id,
+		Name:         resource.Name,
+	})
+
+	thisResource := ResourceDetails{}
+	thisResource.FileName = "space_population/" + variableName + ".tf"
+	thisResource.Id = resource.Id
+	thisResource.Name = resource.Name
+	thisResource.ResourceType = c.GetResourceType()
+	thisResource.Lookup = "${var." + variableName + "}"
+	thisResource.ToHcl = func() (string, error) {
+		variable := terraform.TerraformVariable{
+			Name:        variableName,
+			Type:        "string",
+			Nullable:    false,
+			Sensitive:   false,
+			Description: description,
+		}
+
+		file := hcl.NewFile()
+		block := hcl.EncodeAsBlock(variable, "variable")
+		hcl.WriteUnquotedAttribute(block, "type", "string")
+		file.AppendBlock(block)
+
+		return file.Render(dependencies.Format)
+	}
+
+	dependencies.AddResource(thisResource)
+	return nil
+}
+
+func (c ModuleVariableConverter) GetResourceType() string {
+	return c.ResourceType
+}
+
+// ModuleRootGenerator creates a sample root module that calls a project module, looking up the dependencies that
+// are passed to the module by name in the space the root module is applied to.
+type ModuleRootGenerator struct {
+	Inputs *ModuleInputs
+	// ProviderGenerator creates the provider, backend and variables of the root module.
+	ProviderGenerator TerraformProviderGenerator
+}
+
+// ToHcl adds the root module to the directory. The module in moduleDirectory is called with a source relative to
+// the root module, and is named after the project.
+func (c ModuleRootGenerator) ToHcl(directory string, moduleDirectory string, projectId string, dependencies *ResourceDetailsCollection) {
+	c.ProviderGenerator.ToHcl(directory, dependencies)
+
+	thisResource := ResourceDetails{}
+	thisResource.FileName = directory + "/main.tf"
+	thisResource.Id = ""
+	thisResource.ResourceType = ""
+	thisResource.Lookup = ""
+	thisResource.ToHcl = func() (string, error) {
+		// The module has the same unique name as the project resource in the module
+		moduleName := "project"
+		for _, project := range dependencies.GetAllResource("Projects") {
+			if project.Id == projectId {
+				moduleName = dependencies.ResourceName("project_", project.ResourceType, project.Id, project.Name)
+			}
+		}
+
+		file := hcl.NewFile()
+		module := hcl.EncodeAsBlock(terraform.TerraformModule{
+			Name:   moduleName,
+			Source: "../" + moduleDirectory,
+		}, "module")
+
+		// The data sources search by partial name, so the locals hold the IDs of the resources with the exact names
+		locals := hcl.EncodeAsBlock(terraform.TerraformLocals{}, "locals")
+		inputs := c.Inputs.Sorted(dependencies)
+
+		for _, input := range inputs {
+			data, id := exactNameLookup(input.ResourceType, strings.TrimSuffix(input.Variable, "_id"), input.Name)
+			file.AppendBlock(data)
+
+			hcl.WriteUnquotedAttribute(locals, input.Variable, id)
+			hcl.WriteUnquotedAttribute(module, input.Variable, "local."+input.Variable)
+		}
+
+		if len(inputs) != 0 {
+			file.AppendBlock(locals)
+		}
+
+		file.AppendBlock(module)
+
+		return file.Render(dependencies.Format)
+	}
+
+	dependencies.AddResource(thisResource)
+}
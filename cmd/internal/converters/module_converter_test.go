@@ -0,0 +1,72 @@
+package converters
+
+import (
+	"context"
+	"testing"
+)
+
+func TestModuleVariableConverter(t *testing.T) {
+	octopusClient := createFakeClient(t)
+	_, projectConverter := createConverters(octopusClient)
+	inputs := &ModuleInputs{}
+	projectConverter.LifecycleConverter = ModuleVariableConverter{Client: octopusClient, ResourceType: "Lifecycles", Inputs: inputs}
+	projectConverter.ProjectGroupConverter = ModuleVariableConverter{Client: octopusClient, ResourceType: "ProjectGroups", Inputs: inputs}
+	dependencies := newTestCollection()
+
+	ModuleRootGenerator{Inputs: inputs}.ToHcl("root_module", "space_population", "Projects-1", dependencies)
+	err := projectConverter.ToHclById(context.Background(), "Projects-1", dependencies)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := toFileMap(t, dependencies)
+
+	assertFileContains(t, files, "space_population/project_project_web_app.tf",
+		`lifecycle_id                         = "${var.lifecycle_default_lifecycle_id}"`,
+		`project_group_id                     = "${var.project_group_default_project_group_id}"`)
+	assertFileContains(t, files, "space_population/lifecycle_default_lifecycle_id.tf",
+		`variable "lifecycle_default_lifecycle_id"`,
+		`type        = string`)
+	assertFileContains(t, files, "root_module/main.tf",
+		`data "octopusdeploy_lifecycles" "lifecycle_default_lifecycle"`,
+		`partial_name = "Default Lifecycle"`,
+		`module "project_web_app"`,
+		`source                                 = "../space_population"`,
+		`lifecycle_default_lifecycle_id         = [for r in data.octopusdeploy_lifecycles.lifecycle_default_lifecycle.lifecycles : r.id if r.name == "Default Lifecycle"][0]`,
+		`project_group_default_project_group_id = [for r in data.octopusdeploy_project_groups.project_group_default_project_group.project_groups : r.id if r.name == "Default Project Group"][0]`,
+		`lifecycle_default_lifecycle_id         = local.lifecycle_default_lifecycle_id`,
+		`project_group_default_project_group_id = local.project_group_default_project_group_id`)
+
+	if _, ok := files["space_population/lifecycle_default_lifecycle.tf"]; ok {
+		t.Error("did not expect the lifecycle to be created by the module")
+	}
+}
+
+// TestModuleRootGeneratorUniqueName exports a project whose name collides with another project, and expects the
+// module to have the same unique name as the project resource.
+func TestModuleRootGeneratorUniqueName(t *testing.T) {
+	octopusClient := createFakeClient(t)
+	_, projectConverter := createConverters(octopusClient)
+	inputs := &ModuleInputs{}
+	projectConverter.LifecycleConverter = ModuleVariableConverter{Client: octopusClient, ResourceType: "Lifecycles", Inputs: inputs}
+	projectConverter.ProjectGroupConverter = ModuleVariableConverter{Client: octopusClient, ResourceType: "ProjectGroups", Inputs: inputs}
+	dependencies := newTestCollection()
+
+	// A project with a lower ID keeps the name project_web_app
+	dependencies.ResourceName("project_", "Projects", "Projects-0", "Web-App")
+
+	ModuleRootGenerator{Inputs: inputs}.ToHcl("root_module", "space_population", "Projects-1", dependencies)
+	err := projectConverter.ToHclById(context.Background(), "Projects-1", dependencies)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := toFileMap(t, dependencies)
+
+	assertFileContains(t, files, "space_population/project_project_web_app_2.tf",
+		`resource "octopusdeploy_project" "project_web_app_2"`)
+	assertFileContains(t, files, "root_module/main.tf",
+		`module "project_web_app_2"`)
+}
@@ -0,0 +1,252 @@
+package converters
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
+)
+
+// namePlaceholder matches the placeholders returned by ResourceName. Placeholders only hold characters that are
+// valid in a Terraform identifier, and no placeholder is a substring of another.
+var namePlaceholder = regexp.MustCompile(`__octoterra_name_\d+__`)
+
+// nameKey identifies a resource that was given a name. A resource may be given several names with different
+// prefixes, like a resource and the input variable that replaces it in a module.
+type nameKey struct {
+	prefix       string
+	resourceType string
+	id           string
+}
+
+// registeredName is a name requested with ResourceName.
+type registeredName struct {
+	placeholder string
+	// base is the name before it is made unique, which may hold the placeholders of other names
+	base         string
+	name         string
+	resourceType string
+	id           string
+}
+
+// NameRegistry gives each resource in an export a unique Terraform identifier. Resources are discovered
+// concurrently, so the identifiers can't be assigned in the order they are requested without the result depending
+// on which resource was discovered first. Instead, each request returns a placeholder, and the placeholders are
+// replaced once every name is known. Names that are the same once sanitized, like "Web App" and "Web-App", are
+// ordered by the resource ID. The first keeps the sanitized name, and the others have a numeric suffix. Octopus
+// numbers the IDs of new resources in increasing order, so a resource added to the space is ordered after the
+// existing resources, and the names of the existing resources don't change.
+type NameRegistry struct {
+	mu       sync.Mutex
+	names    []*registeredName
+	byKey    map[nameKey]*registeredName
+	replacer *strings.Replacer
+}
+
+// ResourceName returns the placeholder of a unique identifier for the resource, built from the prefix and the
+// sanitized name, like environment_production. Requesting the name of the same resource with the same prefix
+// returns the same placeholder. The prefix may hold the placeholder of another name, like the resource that owns
+// the resource. The placeholder is replaced with the identifier when the resources are sorted and rendered.
+func (r *NameRegistry) ResourceName(prefix string, resourceType string, id string, name string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.byKey == nil {
+		r.byKey = map[nameKey]*registeredName{}
+	}
+
+	key := nameKey{prefix: prefix, resourceType: resourceType, id: id}
+	if id == "" {
+		// Resources without an ID are identified by their name
+		key.id = "name:" + name
+	}
+
+	if existing, ok := r.byKey[key]; ok {
+		return existing.placeholder
+	}
+
+	registered := &registeredName{
+		placeholder:  "__octoterra_name_" + strconv.Itoa(len(r.names)) + "__",
+		base:         prefix + sanitizer.SanitizeName(name),
+		name:         name,
+		resourceType: resourceType,
+		id:           id,
+	}
+	r.names = append(r.names, registered)
+	r.byKey[key] = registered
+	r.replacer = nil
+
+	return registered.placeholder
+}
+
+// Resolve replaces the placeholders in the value with the unique identifiers.
+func (r *NameRegistry) Resolve(value string) string {
+	if !strings.Contains(value, "__octoterra_name_") {
+		return value
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.replacer == nil {
+		r.replacer = r.buildReplacer()
+	}
+
+	return r.replacer.Replace(value)
+}
+
+// buildReplacer assigns the unique identifiers, and returns a replacer from the placeholders to the identifiers.
+// The caller must hold the lock.
+func (r *NameRegistry) buildReplacer() *strings.Replacer {
+	resolved := map[string]string{}
+	taken := map[string]bool{}
+	pending := append([]*registeredName{}, r.names...)
+
+	// Names that hold the placeholders of other names are assigned after the names they hold
+	for len(pending) != 0 {
+		ready := []*registeredName{}
+		waiting := []*registeredName{}
+
+		for _, n := range pending {
+			if r.isReady(n.base, resolved) {
+				ready = append(ready, n)
+			} else {
+				waiting = append(waiting, n)
+			}
+		}
+
+		// A placeholder that is never resolved can't be waited on
+		if len(ready) == 0 {
+			ready, waiting = waiting, nil
+		}
+
+		assignNames(ready, resolved, taken)
+		pending = waiting
+	}
+
+	pairs := []string{}
+	for _, n := range r.names {
+		pairs = append(pairs, n.placeholder, resolved[n.placeholder])
+	}
+
+	return strings.NewReplacer(pairs...)
+}
+
+// isReady returns true if every placeholder in the base has been resolved.
+func (r *NameRegistry) isReady(base string, resolved map[string]string) bool {
+	for _, placeholder := range namePlaceholder.FindAllString(base, -1) {
+		if _, ok := resolved[placeholder]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// assignNames assigns unique identifiers to the names, which are recorded in resolved and taken. The result only
+// depends on the names and IDs, and not on the order they were registered.
+func assignNames(names []*registeredName, resolved map[string]string, taken map[string]bool) {
+	groups := map[string][]*registeredName{}
+	for _, n := range names {
+		base := namePlaceholder.ReplaceAllStringFunc(n.base, func(placeholder string) string {
+			return resolved[placeholder]
+		})
+		base = validIdentifier(base)
+		groups[base] = append(groups[base], n)
+	}
+
+	bases := []string{}
+	for base, group := range groups {
+		sort.Slice(group, func(i, j int) bool {
+			if group[i].id != group[j].id {
+				return idLess(group[i].id, group[j].id)
+			}
+			if group[i].resourceType != group[j].resourceType {
+				return group[i].resourceType < group[j].resourceType
+			}
+			return group[i].name < group[j].name
+		})
+		bases = append(bases, base)
+	}
+	sort.Strings(bases)
+
+	// The first name in each group keeps the base, unless it was taken by an earlier group of names. Assigning
+	// every base before any suffix means a suffixed name never takes the base of another resource.
+	suffixed := map[string][]*registeredName{}
+	for _, base := range bases {
+		group := groups[base]
+		if !taken[base] {
+			resolved[group[0].placeholder] = base
+			taken[base] = true
+			group = group[1:]
+		}
+		suffixed[base] = group
+	}
+
+	for _, base := range bases {
+		index := 2
+		for _, n := range suffixed[base] {
+			for taken[fmt.Sprintf("%s_%d", base, index)] {
+				index++
+			}
+			resolved[n.placeholder] = fmt.Sprintf("%s_%d", base, index)
+			taken[resolved[n.placeholder]] = true
+		}
+	}
+}
+
+// idLess compares resource IDs like Projects-2 and Projects-10 by the value of their numbers, so the ID of a new
+// resource is ordered after the IDs of the existing resources.
+func idLess(a string, b string) bool {
+	for a != "" && b != "" {
+		aPart, aNumber := nextIdPart(a)
+		bPart, bNumber := nextIdPart(b)
+
+		if aPart != bPart {
+			if aNumber && bNumber {
+				// Leading zeros don't change the value, so the longer number without them is larger
+				aTrimmed, bTrimmed := strings.TrimLeft(aPart, "0"), strings.TrimLeft(bPart, "0")
+				if len(aTrimmed) != len(bTrimmed) {
+					return len(aTrimmed) < len(bTrimmed)
+				}
+				if aTrimmed != bTrimmed {
+					return aTrimmed < bTrimmed
+				}
+			}
+			return aPart < bPart
+		}
+
+		a, b = a[len(aPart):], b[len(bPart):]
+	}
+
+	return len(a) < len(b)
+}
+
+// nextIdPart returns the leading run of digits or non-digits of the ID, and true if it is a number.
+func nextIdPart(id string) (string, bool) {
+	number := isDigit(id[0])
+	end := 1
+	for end < len(id) && isDigit(id[end]) == number {
+		end++
+	}
+
+	return id[:end], number
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// validIdentifier returns the name as a valid Terraform identifier, which must start with a letter or an
+// underscore.
+func validIdentifier(name string) string {
+	if name == "" || isDigit(name[0]) {
+		return "_" + name
+	}
+
+	return name
+}
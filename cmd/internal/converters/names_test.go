@@ -0,0 +1,148 @@
+package converters
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestResourceNamesAreUnique(t *testing.T) {
+	for _, reversed := range []bool{false, true} {
+		registry := NameRegistry{}
+		requests := [][]string{
+			{"Projects-2", "Web-App"},
+			{"Projects-10", "Web App"},
+			{"Projects-3", "web_app_2"},
+		}
+		if reversed {
+			requests[0], requests[2] = requests[2], requests[0]
+		}
+
+		placeholders := map[string]string{}
+		for _, request := range requests {
+			placeholders[request[0]] = registry.ResourceName("project_", "Projects", request[0], request[1])
+		}
+
+		// The lowest ID keeps the name, and the suffix skips the name of Projects-3
+		expected := map[string]string{
+			"Projects-2":  "project_web_app",
+			"Projects-3":  "project_web_app_2",
+			"Projects-10": "project_web_app_3",
+		}
+
+		for id, name := range expected {
+			if resolved := registry.Resolve(placeholders[id]); resolved != name {
+				t.Fatalf("expected %s to be named %s, found %s (reversed: %v)", id, name, resolved, reversed)
+			}
+		}
+	}
+}
+
+// TestResourceNamesOfExistingResources adds a resource to a space, and expects the names of the existing resources
+// to be the same in the next export, even though the name of the new resource sorts first.
+func TestResourceNamesOfExistingResources(t *testing.T) {
+	existing := [][]string{
+		{"Projects-9", "Web-App"},
+		{"Projects-11", "Web_App"},
+	}
+
+	names := []map[string]string{}
+	for _, added := range [][]string{nil, {"Projects-12", "Web App"}} {
+		registry := NameRegistry{}
+		placeholders := map[string]string{}
+		for _, request := range append(append([][]string{}, existing...), added) {
+			if request != nil {
+				placeholders[request[0]] = registry.ResourceName("project_", "Projects", request[0], request[1])
+			}
+		}
+
+		resolved := map[string]string{}
+		for id, placeholder := range placeholders {
+			resolved[id] = registry.Resolve(placeholder)
+		}
+		names = append(names, resolved)
+	}
+
+	for _, request := range existing {
+		if names[0][request[0]] != names[1][request[0]] {
+			t.Errorf("expected %s to keep the name %s, found %s", request[0], names[0][request[0]], names[1][request[0]])
+		}
+	}
+
+	if names[0]["Projects-9"] != "project_web_app" || names[1]["Projects-12"] != "project_web_app_3" {
+		t.Errorf("expected the lowest ID to keep the name and the new resource to be suffixed, found %v", names[1])
+	}
+}
+
+func TestIdLess(t *testing.T) {
+	ordered := []string{"", "Projects-1", "Projects-2", "Projects-10", "Projects-10a", "Projects-11", "Tenants-1/Projects-2", "Tenants-1/Projects-10"}
+
+	for i := 0; i < len(ordered)-1; i++ {
+		if !idLess(ordered[i], ordered[i+1]) || idLess(ordered[i+1], ordered[i]) {
+			t.Errorf("expected %q to be ordered before %q", ordered[i], ordered[i+1])
+		}
+	}
+}
+
+// TestTenantProjectVariableNames exports a tenant that sets the same project template in two environments, and
+// expects each variable to be named after its environment rather than with a numeric suffix.
+func TestTenantProjectVariableNames(t *testing.T) {
+	dependencies := newTestCollection()
+
+	if err := (TenantVariableConverter{Client: createFakeClient(t)}).ToHcl(context.Background(), dependencies); err != nil {
+		t.Fatal(err)
+	}
+
+	fileNames := []string{}
+	for _, r := range dependencies.SortedResources() {
+		if r.ResourceType == "TenantVariables/All" {
+			fileNames = append(fileNames, dependencies.FileName(r))
+		}
+	}
+
+	sort.Strings(fileNames)
+
+	expected := []string{
+		"space_population/tenantprojectvariable_acme_web_app_development_tenant_database.tf",
+		"space_population/tenantprojectvariable_acme_web_app_production_tenant_database.tf",
+	}
+
+	if !reflect.DeepEqual(fileNames, expected) {
+		t.Fatalf("expected %v, got %v", expected, fileNames)
+	}
+}
+
+func TestResourceNamesAreStable(t *testing.T) {
+	registry := NameRegistry{}
+	first := registry.ResourceName("environment_", "Environments", "Environments-1", "Production")
+	second := registry.ResourceName("environment_", "Environments", "Environments-1", "Production")
+
+	if first != second {
+		t.Fatalf("expected the same placeholder for the same resource, found %s and %s", first, second)
+	}
+
+	if resolved := registry.Resolve("octopusdeploy_environment." + first + ".id"); resolved != "octopusdeploy_environment.environment_production.id" {
+		t.Fatalf("unexpected reference %s", resolved)
+	}
+}
+
+func TestResourceNamesAreValidIdentifiers(t *testing.T) {
+	registry := NameRegistry{}
+	name := registry.ResourceName("", "Projects", "Projects-1", "1st Project")
+
+	if resolved := registry.Resolve(name); resolved != "_1st_project" {
+		t.Fatalf("expected a name starting with an underscore, found %s", resolved)
+	}
+}
+
+func TestResourceNamesWithNestedPrefixes(t *testing.T) {
+	registry := NameRegistry{}
+	project := registry.ResourceName("project_", "Projects", "Projects-1", "Web App")
+	registry.ResourceName("project_", "Projects", "Projects-2", "Web-App")
+	trigger := registry.ResourceName(project+"_", "ProjectTriggers", "ProjectTriggers-1", "Nightly")
+
+	if resolved := registry.Resolve(trigger); resolved != "project_web_app_nightly" {
+		t.Fatalf("expected the trigger to use the project name, found %s", resolved)
+	}
+}
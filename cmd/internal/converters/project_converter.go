@@ -1,15 +1,13 @@
 package converters
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
 )
 
@@ -26,58 +24,69 @@ type ProjectConverter struct {
 	ChannelConverter            ConverterByProjectIdWithTerraDependencies
 }
 
-func (c ProjectConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c ProjectConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.Project]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, resource := range collection.Items {
-		err = c.toHcl(resource, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		resource := resource
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), resource.Id, c.toHcl(ctx, resource, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c ProjectConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c ProjectConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	project := octopus2.Project{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &project)
+	found, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &project)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
+	}
+
+	if !found {
+		return dependencies.RecordError(c.GetResourceType(), id, errors.New("the project "+id+" was not found"))
 	}
 
-	return c.toHcl(project, true, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, project, true, dependencies))
 }
 
-func (c ProjectConverter) toHcl(project octopus2.Project, recursive bool, dependencies *ResourceDetailsCollection) error {
+func (c ProjectConverter) toHcl(ctx context.Context, project octopus2.Project, recursive bool, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, project.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
 	thisResource := ResourceDetails{}
 
-	projectName := "project_" + sanitizer.SanitizeName(project.Name)
+	projectName := dependencies.ResourceName("project_", c.GetResourceType(), project.Id, project.Name)
 
 	if recursive {
-		err := c.exportDependencies(project, projectName, dependencies)
+		err := c.exportDependencies(ctx, project, projectName, dependencies)
 
 		if err != nil {
 			return err
 		}
 	}
 
-	err := c.exportChildDependencies(project, projectName, dependencies)
+	err := c.exportChildDependencies(ctx, project, projectName, dependencies)
 
 	if err != nil {
 		return err
@@ -85,10 +94,11 @@ func (c ProjectConverter) toHcl(project octopus2.Project, recursive bool, depend
 
 	// The templates are dependencies that we export as part of the project
 	projectTemplates, projectTemplateMap := c.convertTemplates(project.Templates, projectName)
-	dependencies.AddResource(projectTemplateMap...)
+	dependencies.AddChildResource(ctx, projectTemplateMap...)
 
 	thisResource.FileName = "space_population/project_" + projectName + ".tf"
 	thisResource.Id = project.Id
+	thisResource.Name = project.Name
 	thisResource.ResourceType = c.GetResourceType()
 	thisResource.Lookup = "${octopusdeploy_project." + projectName + ".id}"
 	thisResource.ToHcl = func() (string, error) {
@@ -118,19 +128,15 @@ func (c ProjectConverter) toHcl(project octopus2.Project, recursive bool, depend
 			GitAnonymousPersistenceSettings:        c.convertAnonymousGitPersistence(project, projectName),
 			GitUsernamePasswordPersistenceSettings: c.convertUsernamePasswordGitPersistence(project, projectName),
 		}
-		file := hclwrite.NewEmptyFile()
+		file := hcl.NewFile()
 
 		// Add a comment with the import command
-		baseUrl, _ := c.Client.GetSpaceBaseUrl()
-		file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-			Type: hclsyntax.TokenComment,
-			Bytes: []byte("# Import existing resources with the following commands:\n" +
-				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + project.Name + "\") | .Id')\n" +
-				"# terraform import octopusdeploy_project." + projectName + " ${RESOURCE_ID}\n"),
-			SpacesBefore: 0,
-		}})
+		baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+		file.AppendComment("# Import existing resources with the following commands:\n" +
+			"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + project.Name + "\") | .Id')\n" +
+			"# terraform import octopusdeploy_project." + projectName + " ${RESOURCE_ID}\n")
 
-		file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
+		file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
 
 		if terraformResource.GitUsernamePasswordPersistenceSettings != nil {
 			secretVariableResource := terraform2.TerraformVariable{
@@ -141,9 +147,9 @@ func (c ProjectConverter) toHcl(project octopus2.Project, recursive bool, depend
 				Description: "The git password for the project \"" + project.Name + "\"",
 			}
 
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 		}
 
 		if terraformResource.GitUsernamePasswordPersistenceSettings != nil ||
@@ -158,12 +164,12 @@ func (c ProjectConverter) toHcl(project octopus2.Project, recursive bool, depend
 				Default:     &project.PersistenceSettings.BasePath,
 			}
 
-			block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+			block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 			hcl.WriteUnquotedAttribute(block, "type", "string")
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 		}
 
-		return string(file.Bytes()), nil
+		return file.Render(dependencies.Format)
 	}
 	dependencies.AddResource(thisResource)
 
@@ -250,8 +256,8 @@ func (c ProjectConverter) convertUsernamePasswordGitPersistence(project octopus2
 // exportChildDependencies exports those dependencies that are always required regardless of the recursive flag.
 // These are resources that do not expose an API for bulk retrieval, or those whose resource names benefit
 // from the parent's name (i.e. a deployment process resource name will be "deployment_process_<projectname>").
-func (c ProjectConverter) exportChildDependencies(project octopus2.Project, projectName string, dependencies *ResourceDetailsCollection) error {
-	err := c.ChannelConverter.ToHclByProjectIdWithTerraDependencies(project.Id, map[string]string{
+func (c ProjectConverter) exportChildDependencies(ctx context.Context, project octopus2.Project, projectName string, dependencies *ResourceDetailsCollection) error {
+	err := c.ChannelConverter.ToHclByProjectIdWithTerraDependencies(ctx, project.Id, map[string]string{
 		"DeploymentProcesses": strutil.EmptyIfNil(project.DeploymentProcessId),
 	}, dependencies)
 
@@ -261,7 +267,7 @@ func (c ProjectConverter) exportChildDependencies(project octopus2.Project, proj
 
 	// Export the deployment process
 	if project.DeploymentProcessId != nil {
-		err = c.DeploymentProcessConverter.ToHclByIdAndName(*project.DeploymentProcessId, projectName, dependencies)
+		err = c.DeploymentProcessConverter.ToHclByIdAndName(ctx, *project.DeploymentProcessId, project.Name, dependencies)
 
 		if err != nil {
 			return err
@@ -270,7 +276,7 @@ func (c ProjectConverter) exportChildDependencies(project octopus2.Project, proj
 
 	// Export the variable set
 	if project.VariableSetId != nil {
-		err = c.VariableSetConverter.ToHclByIdAndName(*project.VariableSetId, project.Name, "${octopusdeploy_project."+projectName+".id}", dependencies)
+		err = c.VariableSetConverter.ToHclByIdAndName(ctx, *project.VariableSetId, project.Name, "${octopusdeploy_project."+projectName+".id}", dependencies)
 
 		if err != nil {
 			return err
@@ -278,7 +284,7 @@ func (c ProjectConverter) exportChildDependencies(project octopus2.Project, proj
 	}
 
 	// Export the triggers
-	err = c.ProjectTriggerConverter.ToHclByProjectIdAndName(project.Id, project.Name, dependencies)
+	err = c.ProjectTriggerConverter.ToHclByProjectIdAndName(ctx, project.Id, project.Name, dependencies)
 
 	if err != nil {
 		return err
@@ -287,9 +293,9 @@ func (c ProjectConverter) exportChildDependencies(project octopus2.Project, proj
 	return nil
 }
 
-func (c ProjectConverter) exportDependencies(project octopus2.Project, projectName string, dependencies *ResourceDetailsCollection) error {
+func (c ProjectConverter) exportDependencies(ctx context.Context, project octopus2.Project, projectName string, dependencies *ResourceDetailsCollection) error {
 	// Export the project group
-	err := c.ProjectGroupConverter.ToHclById(project.ProjectGroupId, dependencies)
+	err := c.ProjectGroupConverter.ToHclById(ctx, project.ProjectGroupId, dependencies)
 
 	if err != nil {
 		return err
@@ -297,7 +303,7 @@ func (c ProjectConverter) exportDependencies(project octopus2.Project, projectNa
 
 	// Export the library sets
 	for _, v := range project.IncludedLibraryVariableSetIds {
-		err := c.LibraryVariableSetConverter.ToHclById(v, dependencies)
+		err := c.LibraryVariableSetConverter.ToHclById(ctx, v, dependencies)
 
 		if err != nil {
 			return err
@@ -305,14 +311,14 @@ func (c ProjectConverter) exportDependencies(project octopus2.Project, projectNa
 	}
 
 	// Export the lifecycles
-	err = c.LifecycleConverter.ToHclById(project.LifecycleId, dependencies)
+	err = c.LifecycleConverter.ToHclById(ctx, project.LifecycleId, dependencies)
 
 	if err != nil {
 		return err
 	}
 
 	// Export the tenants
-	err = c.TenantConverter.ToHclByProjectId(project.Id, dependencies)
+	err = c.TenantConverter.ToHclByProjectId(ctx, project.Id, dependencies)
 
 	if err != nil {
 		return err
@@ -320,7 +326,7 @@ func (c ProjectConverter) exportDependencies(project octopus2.Project, projectNa
 
 	// Export the git credentials
 	if project.PersistenceSettings.Credentials.Type == "Reference" {
-		err = c.GitCredentialsConverter.ToHclById(project.PersistenceSettings.Credentials.Id, dependencies)
+		err = c.GitCredentialsConverter.ToHclById(ctx, project.PersistenceSettings.Credentials.Id, dependencies)
 
 		if err != nil {
 			return err
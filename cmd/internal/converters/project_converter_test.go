@@ -0,0 +1,156 @@
+package converters
+
+import (
+	"context"
+	"testing"
+)
+
+func TestProjectConverter(t *testing.T) {
+	_, projectConverter := createConverters(createFakeClient(t))
+	dependencies := newTestCollection()
+
+	err := projectConverter.ToHclById(context.Background(), "Projects-1", dependencies)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := toFileMap(t, dependencies)
+
+	assertFileContains(t, files, "space_population/project_project_web_app.tf",
+		`resource "octopusdeploy_project" "project_web_app"`,
+		`lifecycle_id                         = "${data.octopusdeploy_lifecycles.lifecycle_default_lifecycle.lifecycles[0].id}"`,
+		`project_group_id                     = "${data.octopusdeploy_project_groups.project_group_default_project_group.project_groups[0].id}"`,
+		`included_library_variable_sets       = ["${octopusdeploy_library_variable_set.library_variable_set_shared_settings.id}"]`,
+		`name             = "Tenant.Database"`)
+	assertFileContains(t, files, "space_population/deployment_process_project_web_app.tf",
+		`project_id = "${octopusdeploy_project.project_web_app.id}"`,
+		`action_type                        = "Octopus.Script"`,
+		`target_roles = ["web"]`)
+	assertFileContains(t, files, "space_population/channel_default.tf",
+		`data "octopusdeploy_channels" "channel_default"`)
+	assertFileContains(t, files, "space_population/project_variable_web_app_database_name.tf",
+		`environments = ["${octopusdeploy_environment.environment_development.id}"]`,
+		`default     = "webapp"`)
+	assertFileContains(t, files, "space_population/project_variable_web_app_database_password.tf",
+		`sensitive_value = "${var.web_app_database_password}"`)
+	assertFileContains(t, files, "space_population/project_variable_web_app_deployment_token.tf",
+		`value        = "${octopusdeploy_token_account.account_deployment_token.id}"`)
+	assertFileContains(t, files, "space_population/library_variable_set_shared_settings.tf",
+		`resource "octopusdeploy_library_variable_set" "library_variable_set_shared_settings"`)
+	assertFileContains(t, files, "space_population/tenant_acme.tf",
+		`resource "octopusdeploy_tenant" "tenant_acme"`)
+
+	// Resources that the project does not reference are not exported
+	for _, fileName := range []string{"space_population/target_web_server.tf", "space_population/feed_docker_hub.tf"} {
+		if _, ok := files[fileName]; ok {
+			t.Errorf("did not expect %s to be exported with the project", fileName)
+		}
+	}
+}
+
+// TestProjectConverterRenderTwice renders the project again, like the export does after adding data sources for
+// unresolved references, and expects the resources to be unchanged.
+func TestProjectConverterRenderTwice(t *testing.T) {
+	_, projectConverter := createConverters(createFakeClient(t))
+	dependencies := newTestCollection()
+
+	err := projectConverter.ToHclById(context.Background(), "Projects-1", dependencies)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources := len(dependencies.SortedResources())
+	actions := len(dependencies.GetAllResource("Actions"))
+
+	if actions == 0 {
+		t.Fatal("expected the actions of the deployment process to be added")
+	}
+
+	for i := 0; i < 2; i++ {
+		toFileMap(t, dependencies)
+	}
+
+	if len(dependencies.SortedResources()) != resources || len(dependencies.GetAllResource("Actions")) != actions {
+		t.Fatalf("expected %d resources and %d actions after rendering, found %d and %d", resources, actions,
+			len(dependencies.SortedResources()), len(dependencies.GetAllResource("Actions")))
+	}
+}
+
+func TestProjectConverterScriptFiles(t *testing.T) {
+	_, projectConverter := createConverters(createFakeClient(t))
+	dependencies := newTestCollection()
+	dependencies.ScriptFiles = true
+
+	err := projectConverter.ToHclById(context.Background(), "Projects-1", dependencies)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := toFileMap(t, dependencies)
+
+	assertFileContains(t, files, "space_population/deployment_process_project_web_app.tf",
+		`"Octopus.Action.Script.ScriptBody" = file("${path.module}/scripts/deployment_process_project_web_app_deploy_web_app.sh")`)
+
+	// The script is written as is, without the escaping of strings in the configuration
+	if script := files["space_population/scripts/deployment_process_project_web_app_deploy_web_app.sh"]; script != `echo "Deploying to #{Octopus.Environment.Name}"` {
+		t.Errorf("expected the script to be written to its own file, got %q", script)
+	}
+}
+
+func TestProjectConverterFailedDependency(t *testing.T) {
+	_, projectConverter := createConverters(createFailingClient(t, "/api/Spaces-1/Lifecycles/Lifecycles-1"))
+
+	err := projectConverter.ToHclById(context.Background(), "Projects-1", newTestCollection())
+
+	if err == nil {
+		t.Fatal("expected the export to fail when the lifecycle can not be read")
+	}
+}
+
+func TestProjectConverterFailedTenants(t *testing.T) {
+	_, projectConverter := createConverters(createFailingClient(t, "/api/Spaces-1/Tenants"))
+
+	err := projectConverter.ToHclById(context.Background(), "Projects-1", newTestCollection())
+
+	if err == nil {
+		t.Fatal("expected the export to fail when the tenants can not be read")
+	}
+}
+
+func TestProjectConverterContinueOnError(t *testing.T) {
+	_, projectConverter := createConverters(createFailingClient(t, "/api/Spaces-1/Lifecycles/Lifecycles-1"))
+	dependencies := newTestCollection()
+	dependencies.ContinueOnError = true
+
+	err := projectConverter.ToHclById(context.Background(), "Projects-1", dependencies)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resourceErrors := dependencies.Errors()
+	if len(resourceErrors) != 1 || resourceErrors[0].ResourceType != "Lifecycles" || resourceErrors[0].Id != "Lifecycles-1" {
+		t.Fatalf("expected the lifecycle error to be recorded, found %v", resourceErrors)
+	}
+
+	// The project is still exported, with an unresolved reference to the lifecycle
+	unresolved := []UnresolvedReference{}
+	for _, r := range dependencies.SortedResources() {
+		if r.ResourceType == "Projects" && r.ToHcl != nil {
+			_, resourceUnresolved, err := dependencies.RenderResource(r)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			unresolved = append(unresolved, resourceUnresolved...)
+		}
+	}
+
+	if len(unresolved) != 1 || unresolved[0].ResourceType != "Lifecycles" {
+		t.Fatalf("expected the project to reference the lifecycle that failed to export, found %v", unresolved)
+	}
+}
@@ -1,11 +1,10 @@
 package converters
 
 import (
-	"fmt"
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/logger"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
@@ -15,16 +14,16 @@ type ProjectTriggerConverter struct {
 	Client client.OctopusClient
 }
 
-func (c ProjectTriggerConverter) ToHclByProjectIdAndName(projectId string, projectName string, dependencies *ResourceDetailsCollection) error {
+func (c ProjectTriggerConverter) ToHclByProjectIdAndName(ctx context.Context, projectId string, projectName string, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.ProjectTrigger]{}
-	err := c.Client.GetAllResources(c.GetGroupResourceType(projectId), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetGroupResourceType(projectId), &collection)
 
 	if err != nil {
 		return err
 	}
 
 	for _, projectTrigger := range collection.Items {
-		err = c.toHcl(projectTrigger, false, projectId, projectName, dependencies)
+		err = c.toHcl(ctx, projectTrigger, false, projectId, projectName, dependencies)
 		if err != nil {
 			return err
 		}
@@ -33,18 +32,30 @@ func (c ProjectTriggerConverter) ToHclByProjectIdAndName(projectId string, proje
 	return nil
 }
 
-func (c ProjectTriggerConverter) toHcl(projectTrigger octopus2.ProjectTrigger, recursive bool, projectId string, projectName string, dependencies *ResourceDetailsCollection) error {
+func (c ProjectTriggerConverter) toHcl(ctx context.Context, projectTrigger octopus2.ProjectTrigger, recursive bool, projectId string, projectName string, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, projectTrigger.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
 	// Scheduled triggers with types like "OnceDailySchedule" are not supported
 	if projectTrigger.Filter.FilterType != "MachineFilter" {
-		fmt.Println("Found an unsupported trigger type " + projectTrigger.Filter.FilterType)
+		logger.Debug("skipping a trigger with an unsupported type", "id", projectTrigger.Id, "name", projectTrigger.Name, "filterType", projectTrigger.Filter.FilterType)
+		dependencies.AddWarning(ResourceWarning{
+			ResourceType: c.GetGroupResourceType(projectId),
+			Id:           projectTrigger.Id,
+			Name:         projectTrigger.Name,
+			Message:      "The trigger was not exported because the trigger type " + projectTrigger.Filter.FilterType + " is not supported",
+		})
 		return nil
 	}
 
-	projectTriggerName := "projecttrigger_" + sanitizer.SanitizeName(projectName) + "_" + sanitizer.SanitizeName(projectTrigger.Name)
+	projectTriggerName := dependencies.ResourceName("projecttrigger_"+sanitizer.SanitizeName(projectName)+"_", c.GetResourceType(), projectTrigger.Id, projectTrigger.Name)
 
 	thisResource := ResourceDetails{}
 	thisResource.FileName = "space_population/" + projectTriggerName + ".tf"
 	thisResource.Id = projectTrigger.Id
+	thisResource.Name = projectTrigger.Name
 	thisResource.ResourceType = c.GetGroupResourceType(projectId)
 	thisResource.Lookup = "${octopusdeploy_project_deployment_target_trigger." + projectTriggerName + ".id}"
 	thisResource.ToHcl = func() (string, error) {
@@ -61,21 +72,17 @@ func (c ProjectTriggerConverter) toHcl(projectTrigger octopus2.ProjectTrigger, r
 			ShouldRedeploy:  projectTrigger.Action.ShouldRedeployWhenMachineHasBeenDeployedTo,
 			Id:              nil,
 		}
-		file := hclwrite.NewEmptyFile()
+		file := hcl.NewFile()
 
 		// Add a comment with the import command
-		baseUrl, _ := c.Client.GetSpaceBaseUrl()
-		file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-			Type: hclsyntax.TokenComment,
-			Bytes: []byte("# Import existing resources with the following commands:\n" +
-				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + projectTrigger.Name + "\") | .Id')\n" +
-				"# terraform import octopusdeploy_project_deployment_target_trigger." + projectTriggerName + " ${RESOURCE_ID}\n"),
-			SpacesBefore: 0,
-		}})
-
-		file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-		return string(file.Bytes()), nil
+		baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+		file.AppendComment("# Import existing resources with the following commands:\n" +
+			"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + projectTrigger.Name + "\") | .Id')\n" +
+			"# terraform import octopusdeploy_project_deployment_target_trigger." + projectTriggerName + " ${RESOURCE_ID}\n")
+
+		file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+		return file.Render(dependencies.Format)
 	}
 
 	dependencies.AddResource(thisResource)
@@ -0,0 +1,71 @@
+package converters
+
+import (
+	"context"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
+	"strings"
+)
+
+// RenderFiles renders the resources to a map of file names to file content, returning the references to any
+// resources that were not exported. Resources written to the same file by the layout are combined in one file.
+// Resources that fail to render are recorded with RecordError.
+func (c *ResourceDetailsCollection) RenderFiles(ctx context.Context) (map[string]string, []UnresolvedReference, error) {
+	renderedFiles := map[string][]string{}
+	unresolved := []UnresolvedReference{}
+
+	for _, r := range c.SortedResources() {
+		// Rendering may call the Octopus API, so stop as soon as the export is cancelled
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		// Some resources are already resolved by their parent, but exist in the resource details map as a lookup.
+		// In these cases, ToHcl is nil.
+		if r.ToHcl == nil {
+			continue
+		}
+
+		rendered, resourceUnresolved, err := c.RenderResource(r)
+
+		if err != nil {
+			if err = c.RecordError(r.ResourceType, r.Id, err); err != nil {
+				return nil, nil, err
+			}
+
+			// The error was recorded, so continue without the resource
+			continue
+		}
+
+		unresolved = append(unresolved, resourceUnresolved...)
+
+		if len(strings.TrimSpace(rendered)) != 0 {
+			fileName := c.FileName(r)
+			renderedFiles[fileName] = append(renderedFiles[fileName], rendered)
+		}
+	}
+
+	fileMap := map[string]string{}
+	for fileName, contents := range renderedFiles {
+		// Files that are not Terraform configuration, like the backend settings, are always plain text. A file
+		// with a single resource, like a script, is written as is.
+		format := c.Format
+		if !strings.HasSuffix(fileName, hcl.FileExtension(format)) {
+			if len(contents) == 1 {
+				fileMap[fileName] = contents[0]
+				continue
+			}
+			format = hcl.FormatHcl
+		}
+
+		content, err := hcl.Concat(format, contents...)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fileMap[fileName] = content
+	}
+
+	return strutil.UnEscapeDollar(fileMap), unresolved, nil
+}
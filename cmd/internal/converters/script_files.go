@@ -0,0 +1,44 @@
+package converters
+
+import (
+	"path"
+	"strings"
+)
+
+// scriptsDirectory is the directory, relative to the Terraform configuration, that scripts are written to when
+// ScriptFiles is set
+const scriptsDirectory = "scripts"
+
+// scriptExtensions maps the syntax of a script in Octopus to the extension of the file it is written to
+var scriptExtensions = map[string]string{
+	"PowerShell": ".ps1",
+	"Bash":       ".sh",
+	"Python":     ".py",
+	"CSharp":     ".csx",
+	"FSharp":     ".fsx",
+}
+
+// addScriptFile writes the script to the file with the name and extension in the scripts directory next to the
+// resource file, returning the name of the file relative to the directory of the resource file. Scripts are
+// left inline, and false is returned, when ScriptFiles is not set, when the script is empty, or when the extension
+// is empty because the syntax of the script is unknown.
+func addScriptFile(resourceFileName string, name string, extension string, script string, dependencies *ResourceDetailsCollection) (string, bool) {
+	if !dependencies.ScriptFiles || extension == "" || strings.TrimSpace(script) == "" {
+		return "", false
+	}
+
+	fileName := scriptsDirectory + "/" + name + extension
+
+	thisResource := ResourceDetails{}
+	thisResource.FileName = path.Dir(resourceFileName) + "/" + fileName
+	thisResource.Id = ""
+	thisResource.ResourceType = ""
+	thisResource.Lookup = ""
+	thisResource.ToHcl = func() (string, error) {
+		return script, nil
+	}
+
+	dependencies.AddResource(thisResource)
+
+	return fileName, true
+}
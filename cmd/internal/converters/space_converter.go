@@ -1,14 +1,12 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
+	"strings"
 )
 
 // SpaceConverter creates the files required to create a new space. These files are used in a separate
@@ -39,205 +37,84 @@ type SpaceConverter struct {
 	AzureCloudServiceTargetConverter  Converter
 	AzureServiceFabricTargetConverter Converter
 	AzureWebAppTargetConverter        Converter
+	// ProviderGenerator creates the provider, backend and variables of the space_creation and space_population
+	// directories.
+	ProviderGenerator TerraformProviderGenerator
 }
 
 // ToHcl is a bulk export that takes advantage of the collection endpoints to download and export everything
 // with no filter and with the least number of network calls.
-func (c SpaceConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c SpaceConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 
-	err := c.createSpaceTf(dependencies)
+	err := c.createSpaceTf(ctx, dependencies)
 
 	if err != nil {
 		return err
 	}
 
 	// Generate common terraform config files
-	pacePopulateCommonGenerator := TerraformProviderGenerator{}
-	pacePopulateCommonGenerator.ToHcl("space_population", dependencies)
-	pacePopulateCommonGenerator.ToHcl("space_creation", dependencies)
-
-	// Convert the feeds
-	err = c.FeedConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the accounts
-	err = c.AccountConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the environments
-	err = c.EnvironmentConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the library variables
-	err = c.LibraryVariableSetConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the lifecycles
-	err = c.LifecycleConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the worker pools
-	err = c.WorkerPoolConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the tag sets
-	err = c.TagSetConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the git credentials
-	err = c.GitCredentialsConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the projects groups
-	err = c.ProjectGroupConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the projects
-	err = c.ProjectConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the tenants
-	err = c.TenantConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the certificates
-	err = c.CertificateConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the tenant variables
-	err = c.TenantVariableConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the machine policies
-	err = c.MachinePolicyConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the k8s targets
-	err = c.KubernetesTargetConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the ssh targets
-	err = c.SshTargetConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the ssh targets
-	err = c.ListeningTargetConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the polling targets
-	err = c.PollingTargetConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the cloud region targets
-	err = c.CloudRegionTargetConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the cloud region targets
-	err = c.OfflineDropTargetConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the azure cloud service targets
-	err = c.AzureCloudServiceTargetConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the azure cloud service targets
-	err = c.AzureServiceFabricTargetConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	// Convert the azure web app targets
-	err = c.AzureWebAppTargetConverter.ToHcl(dependencies)
-
-	if err != nil {
-		return err
-	}
-
-	return nil
+	c.ProviderGenerator.ToHcl("space_population", dependencies)
+	c.ProviderGenerator.ToHcl("space_creation", dependencies)
+
+	// The converters are independent of each other, as resources are only rendered once all dependencies have
+	// been discovered, and any resource found by more than one converter is only claimed by the first.
+	// This allows the converters to run concurrently.
+	converters := []Converter{
+		c.FeedConverter,
+		c.AccountConverter,
+		c.EnvironmentConverter,
+		c.LibraryVariableSetConverter,
+		c.LifecycleConverter,
+		c.WorkerPoolConverter,
+		c.TagSetConverter,
+		c.GitCredentialsConverter,
+		c.ProjectGroupConverter,
+		c.ProjectConverter,
+		c.TenantConverter,
+		c.CertificateConverter,
+		c.TenantVariableConverter,
+		c.MachinePolicyConverter,
+		c.KubernetesTargetConverter,
+		c.SshTargetConverter,
+		c.ListeningTargetConverter,
+		c.PollingTargetConverter,
+		c.CloudRegionTargetConverter,
+		c.OfflineDropTargetConverter,
+		c.AzureCloudServiceTargetConverter,
+		c.AzureServiceFabricTargetConverter,
+		c.AzureWebAppTargetConverter,
+	}
+
+	group := dependencies.WorkerPool.NewGroup()
+	for _, converter := range converters {
+		converter := converter
+		group.Go(func() error {
+			return converter.ToHcl(ctx, dependencies)
+		})
+	}
+
+	return group.Wait()
 }
 
 func (c SpaceConverter) getResourceType() string {
 	return "Spaces"
 }
 
-func (c SpaceConverter) createSpaceTf(dependencies *ResourceDetailsCollection) error {
+func (c SpaceConverter) createSpaceTf(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	space := octopus.Space{}
-	err := c.Client.GetSpace(&space)
+	err := c.Client.GetSpace(ctx, &space)
 
 	if err != nil {
 		return err
 	}
 
-	spaceResourceName := "octopus_space_" + sanitizer.SanitizeName(space.Name)
+	spaceResourceName := dependencies.ResourceName("octopus_space_", c.getResourceType(), space.Id, space.Name)
 	spaceName := "${var.octopus_space_name}"
 
 	thisResource := ResourceDetails{}
 	thisResource.FileName = "space_creation/" + spaceResourceName + ".tf"
 	thisResource.Id = space.Id
+	thisResource.Name = space.Name
 	thisResource.ResourceType = "Spaces"
 	thisResource.Lookup = "${octopusdeploy_space." + spaceResourceName + ".id}"
 	thisResource.ToHcl = func() (string, error) {
@@ -269,26 +146,24 @@ func (c SpaceConverter) createSpaceTf(dependencies *ResourceDetailsCollection) e
 			Default:     &space.Name,
 		}
 
-		file := hclwrite.NewEmptyFile()
+		file := hcl.NewFile()
 
 		// Add a comment with the import command
-		baseUrl, _ := c.Client.GetSpaceBaseUrl()
-		file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-			Type: hclsyntax.TokenComment,
-			Bytes: []byte("# Import existing resources with the following commands:\n" +
-				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/Spaces | jq -r '.Items[] | select(.Name==\"" + space.Name + "\") | .Id')\n" +
-				"# terraform import octopusdeploy_space." + spaceName + " ${RESOURCE_ID}\n"),
-			SpacesBefore: 0,
-		}})
+		// Spaces are listed by the server rather than the space
+		baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+		serverUrl := strings.TrimSuffix(baseUrl, "/"+space.Id)
+		file.AppendComment("# Import existing resources with the following commands:\n" +
+			"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + serverUrl + "/Spaces | jq -r '.Items[] | select(.Name==\"" + space.Name + "\") | .Id')\n" +
+			"# terraform import octopusdeploy_space." + spaceResourceName + " ${RESOURCE_ID}\n")
 
-		file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-		file.Body().AppendBlock(gohcl.EncodeAsBlock(spaceOutput, "output"))
+		file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+		file.AppendBlock(hcl.EncodeAsBlock(spaceOutput, "output"))
 
-		block := gohcl.EncodeAsBlock(spaceNameVar, "variable")
+		block := hcl.EncodeAsBlock(spaceNameVar, "variable")
 		hcl.WriteUnquotedAttribute(block, "type", "string")
-		file.Body().AppendBlock(block)
+		file.AppendBlock(block)
 
-		return string(file.Bytes()), nil
+		return file.Render(dependencies.Format)
 	}
 
 	dependencies.AddResource(thisResource)
@@ -0,0 +1,138 @@
+package converters
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/workerpool"
+)
+
+func TestSpaceConverter(t *testing.T) {
+	spaceConverter, _ := createConverters(createFakeClient(t))
+	dependencies := newTestCollection()
+
+	err := spaceConverter.ToHcl(context.Background(), dependencies)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := toFileMap(t, dependencies)
+
+	assertFileContains(t, files, "space_creation/octopus_space_default.tf",
+		`resource "octopusdeploy_space" "octopus_space_default"`,
+		`default     = "Default"`)
+	assertFileContains(t, files, "space_creation/provider.tf", `provider "octopusdeploy"`)
+	assertFileContains(t, files, "space_population/provider.tf", `provider "octopusdeploy"`)
+
+	assertFileContains(t, files, "space_population/environment_development.tf",
+		`resource "octopusdeploy_environment" "environment_development"`,
+		`allow_dynamic_infrastructure = true`)
+	assertFileContains(t, files, "space_population/environment_production.tf",
+		`use_guided_failure           = true`)
+	assertFileContains(t, files, "space_population/account_deployment_token.tf",
+		`resource "octopusdeploy_token_account" "account_deployment_token"`,
+		`environments                      = ["${octopusdeploy_environment.environment_development.id}"]`,
+		`variable "account_deployment_token"`)
+	assertFileContains(t, files, "space_population/feed_docker_hub.tf",
+		`resource "octopusdeploy_docker_container_registry" "feed_docker_hub"`)
+	assertFileContains(t, files, "space_population/feed_octopus_server__built_in_.tf",
+		`data "octopusdeploy_feeds" "built_in_feed"`)
+	assertFileContains(t, files, "space_population/target_web_server.tf",
+		`resource "octopusdeploy_listening_tentacle_deployment_target" "target_web_server"`,
+		`machine_policy_id                 = "${data.octopusdeploy_machine_policies.default_machine_policy.machine_policies[0].id}"`)
+	assertFileContains(t, files, "space_population/tagset_regions.tf",
+		`resource "octopusdeploy_tag_set" "tagset_regions"`)
+	assertFileContains(t, files, "space_population/tag_us_east_1.tf",
+		`tag_set_id  = "${octopusdeploy_tag_set.tagset_regions.id}"`)
+	assertFileContains(t, files, "space_population/tenant_acme.tf",
+		`project_id   = "${octopusdeploy_project.project_web_app.id}"`)
+	assertFileContains(t, files, "space_population/project_project_web_app.tf",
+		`resource "octopusdeploy_project" "project_web_app"`)
+	assertFileContains(t, files, "space_population/workerpool_default_worker_pool.tf",
+		`data "octopusdeploy_worker_pools" "workerpool_default_worker_pool"`)
+
+	assertFileContains(t, files, "space_population/outputs.tf",
+		`"Web App" = "${octopusdeploy_project.project_web_app.id}"`)
+	assertFileContains(t, files, "space_creation/outputs.tf",
+		`"Default" = "${octopusdeploy_space.octopus_space_default.id}"`)
+
+	if len(files) != 34 {
+		t.Errorf("expected 34 files, but found %d", len(files))
+	}
+}
+
+func TestSpaceConverterScriptFiles(t *testing.T) {
+	spaceConverter, _ := createConverters(createFakeClient(t))
+	dependencies := newTestCollection()
+	dependencies.ScriptFiles = true
+
+	err := spaceConverter.ToHcl(context.Background(), dependencies)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := toFileMap(t, dependencies)
+
+	assertFileContains(t, files, "space_population/library_variable_set_logging.tf",
+		`body   = file("${path.module}/scripts/library_variable_set_logging.ps1")`)
+
+	if script := files["space_population/scripts/library_variable_set_logging.ps1"]; script != "function Write-Log($Message) {\n    Write-Host \"${Message}\"\n}\n" {
+		t.Errorf("expected the script module to be written to its own file, got %q", script)
+	}
+}
+
+func TestSpaceConverterCancelled(t *testing.T) {
+	spaceConverter, _ := createConverters(createFakeClient(t))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := spaceConverter.ToHcl(ctx, newTestCollection())
+
+	if err == nil {
+		t.Fatal("expected a cancelled export to fail")
+	}
+}
+
+// TestSpaceConverterRecordAndReplay records a concurrent space export, and replays it with different levels of
+// parallelism. The resources requested by ID depend on which worker exports a resource first, so the replay
+// must not depend on the schedule of the recording.
+func TestSpaceConverterRecordAndReplay(t *testing.T) {
+	directory := t.TempDir()
+	recorder, err := client.NewRecordingOctopusClient(createFakeClient(t), directory, "http://octopus", "Spaces-1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spaceConverter, _ := createConverters(recorder)
+	dependencies := newTestCollection()
+
+	if err := spaceConverter.ToHcl(context.Background(), dependencies); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := toFileMap(t, dependencies)
+
+	for _, parallelism := range []int{1, 2, 4, 8, 4, 8} {
+		replay, err := client.NewReplayOctopusClient(directory)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		spaceConverter, _ := createConverters(replay)
+		dependencies := &ResourceDetailsCollection{WorkerPool: workerpool.NewWorkerPool(parallelism)}
+
+		if err := spaceConverter.ToHcl(context.Background(), dependencies); err != nil {
+			t.Fatalf("failed to replay with a parallelism of %d: %v", parallelism, err)
+		}
+
+		if replayed := toFileMap(t, dependencies); !reflect.DeepEqual(recorded, replayed) {
+			t.Errorf("expected the replay with a parallelism of %d to match the recording", parallelism)
+		}
+	}
+}
@@ -1,23 +1,97 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
 	"github.com/hashicorp/hcl2/hclwrite"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
+	"github.com/zclconf/go-cty/cty"
+	"path"
+	"strings"
 )
 
+// outputResourceTypes are the resource types with names that are unique in a space, which are written to an output
+// mapping the name of each resource to its ID. Resources like variables and channels share names, and are not
+// included.
+var outputResourceTypes = []string{
+	"Accounts",
+	"Certificates",
+	"Environments",
+	"Feeds",
+	"Git-Credentials",
+	"LibraryVariableSets",
+	"Lifecycles",
+	"MachinePolicies",
+	"Machines",
+	"ProjectGroups",
+	"Projects",
+	"Spaces",
+	"TagSets",
+	"Tenants",
+	"WorkerPools",
+}
+
+// sensitiveOutputResourceTypes are the resource types holding credentials, which are marked as sensitive with
+// SensitiveOutputs.
+var sensitiveOutputResourceTypes = map[string]bool{
+	"Accounts":        true,
+	"Certificates":    true,
+	"Git-Credentials": true,
+}
+
+const (
+	BackendLocal   = "local"
+	BackendS3      = "s3"
+	BackendAzureRm = "azurerm"
+	BackendGcs     = "gcs"
+	BackendHttp    = "http"
+	BackendRemote  = "remote"
+)
+
+// Backends are the backends that can be generated, in the order they are listed in the help text.
+var Backends = []string{BackendLocal, BackendS3, BackendAzureRm, BackendGcs, BackendHttp, BackendRemote}
+
+// remoteStateDirectory is the directory holding the configuration that creates the space
+const remoteStateDirectory = "space_creation"
+
 // TerraformProviderGenerator creates the common terraform files required to populate a space
 // including the provider, terraform config, and common vars
 type TerraformProviderGenerator struct {
 	Client client.OctopusClient
+	// ProviderVersion is the version constraint of the Octopus provider. An empty value means
+	// terraform.DefaultOctopusProviderVersion.
+	ProviderVersion string
+	// Backend is the type of the backend storing the state, which is one of the Backends. An empty value means
+	// no backend block is written, and Terraform uses the local backend.
+	Backend string
+	// SensitiveOutputs marks the outputs holding the IDs of credentials, like accounts and certificates, as sensitive.
+	SensitiveOutputs bool
+	// SpaceIdFromRemoteState configures the provider with the ID of the space read from the state of the
+	// space_creation directory, rather than from the octopus_space_id variable. This requires a Backend.
+	SpaceIdFromRemoteState bool
 }
 
 func (c TerraformProviderGenerator) ToHcl(directory string, dependencies *ResourceDetailsCollection) {
 	c.createProvider(directory, dependencies)
-	c.createTerraformConfig(directory, dependencies)
+	c.createTerraformConfig(directory, true, dependencies)
 	c.createVariables(directory, dependencies)
+	c.createBackendConfig(directory, dependencies)
+	c.createRemoteState(directory, dependencies)
+	c.createOutputs(directory, dependencies)
+}
+
+// ToModuleHcl creates the terraform config required by a child module. Child modules use the provider and the
+// backend configured by the root module that calls them.
+func (c TerraformProviderGenerator) ToModuleHcl(directory string, dependencies *ResourceDetailsCollection) {
+	c.createTerraformConfig(directory, false, dependencies)
+	c.createOutputs(directory, dependencies)
+}
+
+// spaceIdFromRemoteState returns true if the provider in the directory reads the space ID from the remote state.
+// The directory that creates the space can not read its own state.
+func (c TerraformProviderGenerator) spaceIdFromRemoteState(directory string) bool {
+	return c.SpaceIdFromRemoteState && c.Backend != "" && directory != remoteStateDirectory
 }
 
 func (c TerraformProviderGenerator) createProvider(directory string, dependencies *ResourceDetailsCollection) {
@@ -28,30 +102,37 @@ func (c TerraformProviderGenerator) createProvider(directory string, dependencie
 	thisResource.Lookup = ""
 	thisResource.ToHcl = func() (string, error) {
 		spaceId := "${var.octopus_space_id}"
+		if c.spaceIdFromRemoteState(directory) {
+			spaceId = "${data.terraform_remote_state." + remoteStateDirectory + ".outputs.octopus_space_id}"
+		}
 		terraformResource := terraform2.TerraformProvider{
 			Type:    "octopusdeploy",
 			Address: "${var.octopus_server}",
 			ApiKey:  "${var.octopus_apikey}",
 			SpaceId: &spaceId,
 		}
-		file := hclwrite.NewEmptyFile()
-		file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "provider"))
-		return string(file.Bytes()), nil
+		file := hcl.NewFile()
+		file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "provider"))
+		return file.Render(dependencies.Format)
 	}
 	dependencies.AddResource(thisResource)
 }
 
-func (c TerraformProviderGenerator) createTerraformConfig(directory string, dependencies *ResourceDetailsCollection) {
+func (c TerraformProviderGenerator) createTerraformConfig(directory string, rootModule bool, dependencies *ResourceDetailsCollection) {
 	thisResource := ResourceDetails{}
 	thisResource.FileName = directory + "/config.tf"
 	thisResource.Id = ""
 	thisResource.ResourceType = ""
 	thisResource.Lookup = ""
 	thisResource.ToHcl = func() (string, error) {
-		terraformResource := terraform2.TerraformConfig{}.CreateTerraformConfig()
-		file := hclwrite.NewEmptyFile()
-		file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "terraform"))
-		return string(file.Bytes()), nil
+		backend := ""
+		if rootModule {
+			backend = c.Backend
+		}
+		terraformResource := terraform2.TerraformConfig{}.CreateTerraformConfig(c.ProviderVersion, backend)
+		file := hcl.NewFile()
+		file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "terraform"))
+		return file.Render(dependencies.Format)
 	}
 	dependencies.AddResource(thisResource)
 }
@@ -87,21 +168,173 @@ func (c TerraformProviderGenerator) createVariables(directory string, dependenci
 			Description: "The ID of the Octopus space to populate.",
 		}
 
-		file := hclwrite.NewEmptyFile()
+		file := hcl.NewFile()
 
-		octopusServerBlock := gohcl.EncodeAsBlock(octopusServer, "variable")
+		octopusServerBlock := hcl.EncodeAsBlock(octopusServer, "variable")
 		hcl.WriteUnquotedAttribute(octopusServerBlock, "type", "string")
-		file.Body().AppendBlock(octopusServerBlock)
+		file.AppendBlock(octopusServerBlock)
 
-		octopusApiKeyBlock := gohcl.EncodeAsBlock(octopusApiKey, "variable")
+		octopusApiKeyBlock := hcl.EncodeAsBlock(octopusApiKey, "variable")
 		hcl.WriteUnquotedAttribute(octopusApiKeyBlock, "type", "string")
-		file.Body().AppendBlock(octopusApiKeyBlock)
+		file.AppendBlock(octopusApiKeyBlock)
+
+		if !c.spaceIdFromRemoteState(directory) {
+			octopusSpaceIdBlock := hcl.EncodeAsBlock(octopusSpaceId, "variable")
+			hcl.WriteUnquotedAttribute(octopusSpaceIdBlock, "type", "string")
+			file.AppendBlock(octopusSpaceIdBlock)
+		}
+
+		return file.Render(dependencies.Format)
+	}
+	dependencies.AddResource(thisResource)
+}
+
+// createBackendConfig writes the settings of the backend to backend.tfbackend. A backend block can not reference
+// variables, so the settings are passed to terraform init with -backend-config=backend.tfbackend. The settings
+// that depend on where the state is stored are left empty, and the state of each directory is stored under
+// a different key.
+func (c TerraformProviderGenerator) createBackendConfig(directory string, dependencies *ResourceDetailsCollection) {
+	if c.Backend == "" || c.Backend == BackendLocal {
+		return
+	}
+
+	thisResource := ResourceDetails{}
+	thisResource.FileName = directory + "/backend.tfbackend"
+	thisResource.Id = ""
+	thisResource.ResourceType = ""
+	thisResource.Lookup = ""
+	thisResource.ToHcl = func() (string, error) {
+		file := hclwrite.NewEmptyFile()
+		file.Body().AppendUnstructuredTokens(hclwrite.Tokens{{
+			Type: hclsyntax.TokenComment,
+			Bytes: []byte("# Fill in the empty settings, and initialise the directory with:\n" +
+				"# terraform init -backend-config=backend.tfbackend\n"),
+		}})
+
+		for _, setting := range backendSettings(c.Backend, directory) {
+			file.Body().SetAttributeValue(setting[0], cty.StringVal(setting[1]))
+		}
 
-		octopusSpaceIdBlock := gohcl.EncodeAsBlock(octopusSpaceId, "variable")
-		hcl.WriteUnquotedAttribute(octopusSpaceIdBlock, "type", "string")
-		file.Body().AppendBlock(octopusSpaceIdBlock)
+		// The remote backend selects the workspace holding the state in a nested block
+		if c.Backend == BackendRemote {
+			file.Body().AppendNewBlock("workspaces", nil).Body().SetAttributeValue("name", cty.StringVal(directory))
+		}
 
 		return string(file.Bytes()), nil
 	}
+
+	dependencies.AddResource(thisResource)
+}
+
+// backendSettings returns the names and values of the settings of the backend. The settings identifying the
+// state of the directory are filled in, and the others are empty.
+func backendSettings(backend string, directory string) [][2]string {
+	switch backend {
+	case BackendS3:
+		return [][2]string{{"bucket", ""}, {"key", directory + "/terraform.tfstate"}, {"region", ""}}
+	case BackendAzureRm:
+		return [][2]string{{"resource_group_name", ""}, {"storage_account_name", ""}, {"container_name", ""},
+			{"key", directory + ".terraform.tfstate"}}
+	case BackendGcs:
+		return [][2]string{{"bucket", ""}, {"prefix", directory}}
+	case BackendHttp:
+		return [][2]string{{"address", ""}, {"lock_address", ""}, {"unlock_address", ""}}
+	case BackendRemote:
+		return [][2]string{{"hostname", "app.terraform.io"}, {"organization", ""}}
+	}
+
+	return [][2]string{}
+}
+
+// createRemoteState adds a terraform_remote_state data source that reads the outputs of the space_creation
+// directory, including the ID of the space. The settings of the backend holding that state are passed in the
+// space_creation_backend_config variable, which defaults to the state file of the local backend.
+func (c TerraformProviderGenerator) createRemoteState(directory string, dependencies *ResourceDetailsCollection) {
+	if !c.spaceIdFromRemoteState(directory) {
+		return
+	}
+
+	thisResource := ResourceDetails{}
+	thisResource.FileName = directory + "/remote_state.tf"
+	thisResource.Id = ""
+	thisResource.ResourceType = ""
+	thisResource.Lookup = ""
+	thisResource.ToHcl = func() (string, error) {
+		variableName := remoteStateDirectory + "_backend_config"
+
+		remoteState := hcl.EncodeAsBlock(terraform2.TerraformRemoteState{
+			Type:    "terraform_remote_state",
+			Name:    remoteStateDirectory,
+			Backend: c.Backend,
+		}, "data")
+		hcl.WriteUnquotedAttribute(remoteState, "config", "var."+variableName)
+
+		variable := terraform2.TerraformMapVariable{
+			Name:        variableName,
+			Type:        "any",
+			Description: "The settings of the " + c.Backend + " backend holding the state of the " + remoteStateDirectory + " directory.",
+		}
+
+		if c.Backend == BackendLocal {
+			variable.Default = &map[string]string{"path": "../" + remoteStateDirectory + "/terraform.tfstate"}
+		}
+
+		variableBlock := hcl.EncodeAsBlock(variable, "variable")
+		hcl.WriteUnquotedAttribute(variableBlock, "type", "any")
+
+		file := hcl.NewFile()
+		file.AppendComment("# The ID of the space is read from the state of the configuration that created the space.\n")
+		file.AppendBlock(remoteState)
+		file.AppendBlock(variableBlock)
+		return file.Render(dependencies.Format)
+	}
+
+	dependencies.AddResource(thisResource)
+}
+
+// createOutputs writes an output for each resource type in outputResourceTypes, mapping the Octopus name of each
+// resource in the directory to its ID. The outputs are built from the lookups of the resources when the file is
+// rendered, so they include every resource found while exporting, and the data sources looking up existing
+// resources.
+func (c TerraformProviderGenerator) createOutputs(directory string, dependencies *ResourceDetailsCollection) {
+	thisResource := ResourceDetails{}
+	thisResource.FileName = directory + "/outputs.tf"
+	thisResource.Id = ""
+	thisResource.ResourceType = ""
+	thisResource.Lookup = ""
+	thisResource.ToHcl = func() (string, error) {
+		ids := map[string]map[string]string{}
+
+		for _, r := range dependencies.SortedResources() {
+			if r.ToHcl == nil || r.Name == "" || r.Lookup == "" || path.Dir(r.FileName) != directory {
+				continue
+			}
+
+			if ids[r.ResourceType] == nil {
+				ids[r.ResourceType] = map[string]string{}
+			}
+			ids[r.ResourceType][r.Name] = r.Lookup
+		}
+
+		file := hcl.NewFile()
+
+		for _, resourceType := range outputResourceTypes {
+			if len(ids[resourceType]) == 0 {
+				continue
+			}
+
+			name := strings.TrimSuffix(typeFileName(resourceType), ".tf")
+			block := hcl.EncodeAsBlock(terraform2.TerraformOutputMap{
+				Name:        name,
+				Description: "The IDs of the " + strings.Replace(name, "_", " ", -1) + ", keyed by name.",
+				Sensitive:   c.SensitiveOutputs && sensitiveOutputResourceTypes[resourceType],
+			}, "output")
+			hcl.WriteMapAttribute(block, "value", ids[resourceType])
+			file.AppendBlock(block)
+		}
+
+		return file.Render(dependencies.Format)
+	}
+
 	dependencies.AddResource(thisResource)
 }
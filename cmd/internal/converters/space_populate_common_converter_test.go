@@ -0,0 +1,82 @@
+package converters
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTerraformProviderGeneratorBackend(t *testing.T) {
+	dependencies := newTestCollection()
+	generator := TerraformProviderGenerator{ProviderVersion: "~> 0.12", Backend: BackendS3, SpaceIdFromRemoteState: true}
+	generator.ToHcl("space_creation", dependencies)
+	generator.ToHcl("space_population", dependencies)
+
+	files := toFileMap(t, dependencies)
+
+	assertFileContains(t, files, "space_population/config.tf",
+		`version = "~> 0.12"`,
+		`backend "s3" {`)
+	assertFileContains(t, files, "space_population/backend.tfbackend",
+		`key    = "space_population/terraform.tfstate"`)
+	assertFileContains(t, files, "space_creation/backend.tfbackend",
+		`key    = "space_creation/terraform.tfstate"`)
+	assertFileContains(t, files, "space_population/provider.tf",
+		`space_id = "${data.terraform_remote_state.space_creation.outputs.octopus_space_id}"`)
+	assertFileContains(t, files, "space_population/remote_state.tf",
+		`data "terraform_remote_state" "space_creation"`,
+		`config  = var.space_creation_backend_config`)
+
+	// The space_creation directory creates the space, so it can not read the ID from its own state
+	assertFileContains(t, files, "space_creation/provider.tf", `space_id = "${var.octopus_space_id}"`)
+	assertFileContains(t, files, "space_creation/provider_vars.tf", `variable "octopus_space_id"`)
+
+	if _, ok := files["space_creation/remote_state.tf"]; ok {
+		t.Error("did not expect space_creation to read the remote state")
+	}
+}
+
+func TestTerraformProviderGeneratorDefaults(t *testing.T) {
+	dependencies := newTestCollection()
+	TerraformProviderGenerator{}.ToHcl("space_population", dependencies)
+
+	files := toFileMap(t, dependencies)
+
+	assertFileContains(t, files, "space_population/config.tf", `version = "0.10.1"`)
+
+	for _, fileName := range []string{"space_population/backend.tfbackend", "space_population/remote_state.tf"} {
+		if _, ok := files[fileName]; ok {
+			t.Errorf("did not expect %s without a backend", fileName)
+		}
+	}
+}
+
+func TestTerraformProviderGeneratorOutputs(t *testing.T) {
+	dependencies := newTestCollection()
+	render := func() (string, error) { return "", nil }
+	dependencies.AddResource(
+		ResourceDetails{Id: "Environments-1", Name: "Development", ResourceType: "Environments", ToHcl: render,
+			FileName: "space_population/environment_development.tf", Lookup: "${octopusdeploy_environment.environment_development.id}"},
+		ResourceDetails{Id: "Accounts-1", Name: "Token", ResourceType: "Accounts", ToHcl: render,
+			FileName: "space_population/account_token.tf", Lookup: "${octopusdeploy_token_account.account_token.id}"},
+		ResourceDetails{Id: "Variables-1", Name: "Url", ResourceType: "Variables", ToHcl: render,
+			FileName: "space_population/variable_url.tf", Lookup: "${octopusdeploy_variable.variable_url.id}"},
+		ResourceDetails{Id: "Spaces-1", Name: "Default", ResourceType: "Spaces", ToHcl: render,
+			FileName: "space_creation/space_default.tf", Lookup: "${octopusdeploy_space.space_default.id}"},
+	)
+	TerraformProviderGenerator{SensitiveOutputs: true}.ToHcl("space_population", dependencies)
+
+	files := toFileMap(t, dependencies)
+
+	assertFileContains(t, files, "space_population/outputs.tf",
+		`output "environments" {`,
+		`"Development" = "${octopusdeploy_environment.environment_development.id}"`,
+		`output "accounts" {`,
+		`sensitive   = true`)
+
+	// Variables share names, and the space is in another directory
+	for _, unexpected := range []string{"variable_url", "space_default"} {
+		if strings.Contains(files["space_population/outputs.tf"], unexpected) {
+			t.Errorf("did not expect the outputs to contain %s", unexpected)
+		}
+	}
+}
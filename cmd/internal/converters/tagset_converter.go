@@ -1,13 +1,11 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
 )
 
@@ -15,50 +13,58 @@ type TagSetConverter struct {
 	Client client.OctopusClient
 }
 
-func (c TagSetConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c TagSetConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.TagSet]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, tagSet := range collection.Items {
-		err = c.ToHclByResource(tagSet, dependencies)
-
-		if err != nil {
-			return err
-		}
+		tagSet := tagSet
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), tagSet.Id, c.ToHclByResource(ctx, tagSet, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c TagSetConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c TagSetConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	tagSet := octopus2.TagSet{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &tagSet)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &tagSet)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.ToHclByResource(tagSet, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.ToHclByResource(ctx, tagSet, dependencies))
 }
 
-func (c TagSetConverter) ToHclByResource(tagSet octopus2.TagSet, dependencies *ResourceDetailsCollection) error {
-	tagSetName := "tagset_" + sanitizer.SanitizeName(tagSet.Name)
+func (c TagSetConverter) ToHclByResource(ctx context.Context, tagSet octopus2.TagSet, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, tagSet.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
+	tagSetName := dependencies.ResourceName("tagset_", c.GetResourceType(), tagSet.Id, tagSet.Name)
 
 	thisResource := ResourceDetails{}
 	thisResource.FileName = "space_population/" + tagSetName + ".tf"
 	thisResource.Id = tagSet.Id
+	thisResource.Name = tagSet.Name
 	thisResource.ResourceType = c.GetResourceType()
 	thisResource.Lookup = "${octopusdeploy_tag_set." + tagSetName + ".id}"
 	thisResource.ToHcl = func() (string, error) {
@@ -69,21 +75,17 @@ func (c TagSetConverter) ToHclByResource(tagSet octopus2.TagSet, dependencies *R
 			Description:  strutil.NilIfEmptyPointer(tagSet.Description),
 			SortOrder:    tagSet.SortOrder,
 		}
-		file := hclwrite.NewEmptyFile()
+		file := hcl.NewFile()
 
 		// Add a comment with the import command
-		baseUrl, _ := c.Client.GetSpaceBaseUrl()
-		file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-			Type: hclsyntax.TokenComment,
-			Bytes: []byte("# Import existing resources with the following commands:\n" +
-				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + tagSet.Name + "\") | .Id')\n" +
-				"# terraform import octopusdeploy_tag_set." + tagSetName + " ${RESOURCE_ID}\n"),
-			SpacesBefore: 0,
-		}})
-
-		file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-		return string(file.Bytes()), nil
+		baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+		file.AppendComment("# Import existing resources with the following commands:\n" +
+			"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + tagSet.Name + "\") | .Id')\n" +
+			"# terraform import octopusdeploy_tag_set." + tagSetName + " ${RESOURCE_ID}\n")
+
+		file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+		return file.Render(dependencies.Format)
 	}
 	dependencies.AddResource(thisResource)
 
@@ -92,11 +94,12 @@ func (c TagSetConverter) ToHclByResource(tagSet octopus2.TagSet, dependencies *R
 		// https://go.dev/doc/faq#closures_and_goroutines
 		tag := tag
 
-		tagName := "tag_" + sanitizer.SanitizeName(tag.Name)
+		tagName := dependencies.ResourceName("tag_", "Tags", tag.Id, tag.Name)
 
 		tagResource := ResourceDetails{}
 		tagResource.FileName = "space_population/" + tagName + ".tf"
 		tagResource.Id = tag.Id
+		tagResource.Name = tag.Name
 		tagResource.ResourceType = "Tags"
 		tagResource.Lookup = "${octopusdeploy_tag." + tagName + ".id}"
 		tagResource.ToHcl = func() (string, error) {
@@ -109,12 +112,12 @@ func (c TagSetConverter) ToHclByResource(tagSet octopus2.TagSet, dependencies *R
 				Description:  tag.Description,
 				SortOrder:    tag.SortOrder,
 			}
-			file := hclwrite.NewEmptyFile()
-			file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
+			file := hcl.NewFile()
+			file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
-		dependencies.AddResource(tagResource)
+		dependencies.AddChildResource(ctx, tagResource)
 	}
 
 	return nil
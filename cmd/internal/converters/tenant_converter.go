@@ -1,16 +1,15 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sliceutil"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
 	"k8s.io/utils/strings/slices"
+	"sort"
 	"strings"
 )
 
@@ -21,90 +20,105 @@ type TenantConverter struct {
 	TagSetConverter         ConvertToHclByResource[octopus2.TagSet]
 }
 
-func (c TenantConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c TenantConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.Tenant]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, resource := range collection.Items {
-		err = c.toHcl(resource, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		resource := resource
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), resource.Id, c.toHcl(ctx, resource, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c TenantConverter) ToHclByProjectId(projectId string, dependencies *ResourceDetailsCollection) error {
+func (c TenantConverter) ToHclByProjectId(ctx context.Context, projectId string, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.Tenant]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection, []string{"projectId", projectId})
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection, []string{"projectId", projectId})
 
 	if err != nil {
-		return nil
+		return err
 	}
 
 	for _, tenant := range collection.Items {
-		err = c.toHcl(tenant, true, dependencies)
+		err = dependencies.RecordError(c.GetResourceType(), tenant.Id, c.toHcl(ctx, tenant, true, dependencies))
 		if err != nil {
-			return nil
+			return err
 		}
 	}
 	return nil
 }
 
-func (c TenantConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c TenantConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
+	if id == "" {
+		return nil
+	}
+
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
+	if dependencies.HasResource(id, c.GetResourceType()) {
+		return nil
+	}
+
 	tenant := octopus2.Tenant{}
-	found, err := c.Client.GetResourceById(c.GetResourceType(), id, &tenant)
+	found, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &tenant)
 
 	if err != nil {
-		return nil
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
 	if found {
-		return c.toHcl(tenant, true, dependencies)
+		return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, tenant, true, dependencies))
 	}
 
 	return nil
 }
 
-func (c TenantConverter) toHcl(tenant octopus2.Tenant, recursive bool, dependencies *ResourceDetailsCollection) error {
+func (c TenantConverter) toHcl(ctx context.Context, tenant octopus2.Tenant, recursive bool, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, tenant.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
 
 	if recursive {
 		// Export the tenant variables
-		err := c.TenantVariableConverter.ToHclByTenantId(tenant.Id, dependencies)
+		err := c.TenantVariableConverter.ToHclByTenantId(ctx, tenant.Id, dependencies)
 
 		if err != nil {
 			return err
 		}
 
 		// Export the tenant environments
-		for _, environments := range tenant.ProjectEnvironments {
-			for _, environment := range environments {
-				err = c.EnvironmentConverter.ToHclById(environment, dependencies)
-			}
-		}
+		for _, projectId := range sliceutil.SortedKeys(tenant.ProjectEnvironments) {
+			for _, environment := range tenant.ProjectEnvironments[projectId] {
+				err = c.EnvironmentConverter.ToHclById(ctx, environment, dependencies)
 
-		if err != nil {
-			return err
+				if err != nil {
+					return err
+				}
+			}
 		}
 	}
 
-	tagSetDependencies, err := c.addTagSetDependencies(tenant, recursive, dependencies)
+	tagSetDependencies, err := c.addTagSetDependencies(ctx, tenant, recursive, dependencies)
 
 	if err != nil {
 		return err
 	}
 
-	tenantName := "tenant_" + sanitizer.SanitizeName(tenant.Name)
+	tenantName := dependencies.ResourceName("tenant_", c.GetResourceType(), tenant.Id, tenant.Name)
 
 	thisResource := ResourceDetails{}
 	thisResource.FileName = "space_population/" + tenantName + ".tf"
 	thisResource.Id = tenant.Id
+	thisResource.Name = tenant.Name
 	thisResource.ResourceType = c.GetResourceType()
 	thisResource.Lookup = "${octopusdeploy_tenant." + tenantName + ".id}"
 	thisResource.ToHcl = func() (string, error) {
@@ -118,19 +132,15 @@ func (c TenantConverter) toHcl(tenant octopus2.Tenant, recursive bool, dependenc
 			TenantTags:         tenant.TenantTags,
 			ProjectEnvironment: c.getProjects(tenant.ProjectEnvironments, dependencies),
 		}
-		file := hclwrite.NewEmptyFile()
+		file := hcl.NewFile()
 
 		// Add a comment with the import command
-		baseUrl, _ := c.Client.GetSpaceBaseUrl()
-		file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-			Type: hclsyntax.TokenComment,
-			Bytes: []byte("# Import existing resources with the following commands:\n" +
-				"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + tenant.Name + "\") | .Id')\n" +
-				"# terraform import octopusdeploy_tenant." + tenantName + " ${RESOURCE_ID}\n"),
-			SpacesBefore: 0,
-		}})
+		baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+		file.AppendComment("# Import existing resources with the following commands:\n" +
+			"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + tenant.Name + "\") | .Id')\n" +
+			"# terraform import octopusdeploy_tenant." + tenantName + " ${RESOURCE_ID}\n")
 
-		block := gohcl.EncodeAsBlock(terraformResource, "resource")
+		block := hcl.EncodeAsBlock(terraformResource, "resource")
 
 		// Explicitly describe the dependency between a target and a tag set
 		dependsOn := []string{}
@@ -141,11 +151,12 @@ func (c TenantConverter) toHcl(tenant octopus2.Tenant, recursive bool, dependenc
 				dependsOn = append(dependsOn, dependency)
 			}
 		}
+		sort.Strings(dependsOn)
 
 		hcl.WriteUnquotedAttribute(block, "depends_on", "["+strings.Join(dependsOn[:], ",")+"]")
-		file.Body().AppendBlock(block)
+		file.AppendBlock(block)
 
-		return string(file.Bytes()), nil
+		return file.Render(dependencies.Format)
 	}
 
 	dependencies.AddResource(thisResource)
@@ -159,13 +170,11 @@ func (c TenantConverter) GetResourceType() string {
 
 func (c TenantConverter) getProjects(tags map[string][]string, dependencies *ResourceDetailsCollection) []terraform.TerraformProjectEnvironment {
 	terraformProjectEnvironments := make([]terraform.TerraformProjectEnvironment, len(tags))
-	index := 0
-	for k, v := range tags {
-		terraformProjectEnvironments[index] = terraform.TerraformProjectEnvironment{
-			Environments: c.lookupEnvironments(v, dependencies),
+	for i, k := range sliceutil.SortedKeys(tags) {
+		terraformProjectEnvironments[i] = terraform.TerraformProjectEnvironment{
+			Environments: c.lookupEnvironments(tags[k], dependencies),
 			ProjectId:    dependencies.GetResource("Projects", k),
 		}
-		index++
 	}
 	return terraformProjectEnvironments
 }
@@ -180,9 +189,9 @@ func (c TenantConverter) lookupEnvironments(envs []string, dependencies *Resourc
 
 // addTagSetDependencies finds the tag sets that contains the tags associated with a tenant. These dependencies are
 // captured, as Terraform has no other way to map the dependency between a tagset and a tenant.
-func (c TenantConverter) addTagSetDependencies(tenant octopus2.Tenant, recursive bool, dependencies *ResourceDetailsCollection) (map[string][]string, error) {
+func (c TenantConverter) addTagSetDependencies(ctx context.Context, tenant octopus2.Tenant, recursive bool, dependencies *ResourceDetailsCollection) (map[string][]string, error) {
 	collection := octopus2.GeneralCollection[octopus2.TagSet]{}
-	err := c.Client.GetAllResources("TagSets", &collection)
+	err := c.Client.GetAllResources(ctx, "TagSets", &collection)
 
 	if err != nil {
 		return nil, err
@@ -204,7 +213,7 @@ func (c TenantConverter) addTagSetDependencies(tenant octopus2.Tenant, recursive
 					}
 
 					if recursive {
-						err = c.TagSetConverter.ToHclByResource(tagSet, dependencies)
+						err = c.TagSetConverter.ToHclByResource(ctx, tagSet, dependencies)
 
 						if err != nil {
 							return nil, err
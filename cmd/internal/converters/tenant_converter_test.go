@@ -0,0 +1,51 @@
+package converters
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+)
+
+func TestTenantConverterById(t *testing.T) {
+	octopusClient := client.NewCountingOctopusClient(createFakeClient(t))
+	environmentConverter := EnvironmentConverter{Client: octopusClient}
+	tenantConverter := TenantConverter{
+		Client:                  octopusClient,
+		TenantVariableConverter: TenantVariableConverter{Client: octopusClient},
+		EnvironmentConverter:    environmentConverter,
+		TagSetConverter:         TagSetConverter{Client: octopusClient},
+	}
+	dependencies := newTestCollection()
+
+	// Tenants are exported by ID from each of the resources that reference them, like accounts
+	parents := []string{"Accounts-1", "Accounts-2"}
+	calls := []int{}
+	for _, parent := range parents {
+		accountCtx, _ := dependencies.ClaimResource(context.Background(), parent, "Accounts")
+
+		if err := tenantConverter.ToHclById(accountCtx, "Tenants-1", dependencies); err != nil {
+			t.Fatal(err)
+		}
+
+		calls = append(calls, octopusClient.Calls()["Tenants"])
+	}
+
+	if tenants := dependencies.GetAllResource("Tenants"); len(tenants) != 1 {
+		t.Fatalf("expected the tenant to be exported once, found %d", len(tenants))
+	}
+
+	if calls[0] != calls[1] {
+		t.Fatalf("expected the tenant to be read once, but the second account made %d calls", calls[1]-calls[0])
+	}
+
+	// The tenant was exported for the first account, and is a dependency of both
+	for _, parent := range parents {
+		expected := Dependency{ResourceType: "Accounts", Id: parent, DependencyType: "Tenants", DependencyId: "Tenants-1"}
+		if actual := fmt.Sprint(dependencies.Dependencies()); !strings.Contains(actual, fmt.Sprint(expected)) {
+			t.Fatalf("expected the dependency %v, found %s", expected, actual)
+		}
+	}
+}
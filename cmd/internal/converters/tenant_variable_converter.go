@@ -1,69 +1,87 @@
 package converters
 
 import (
-	"fmt"
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sliceutil"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
 )
 
 type TenantVariableConverter struct {
 	Client client.OctopusClient
 }
 
-func (c TenantVariableConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c TenantVariableConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := []octopus.TenantVariable{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, resource := range collection {
-		err = c.toHcl(resource, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		resource := resource
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), resource.TenantId, c.toHcl(ctx, resource, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c TenantVariableConverter) ToHclByTenantId(id string, dependencies *ResourceDetailsCollection) error {
+func (c TenantVariableConverter) ToHclByTenantId(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	resource := octopus.TenantVariable{}
-	err := c.Client.GetAllResources("Tenants/"+id+"/Variables", &resource)
+	err := c.Client.GetAllResources(ctx, "Tenants/"+id+"/Variables", &resource)
 
 	if err != nil {
 		return err
 	}
 
-	return c.toHcl(resource, true, dependencies)
+	return c.toHcl(ctx, resource, true, dependencies)
 }
 
-func (c TenantVariableConverter) toHcl(tenant octopus.TenantVariable, recursive bool, dependencies *ResourceDetailsCollection) error {
+func (c TenantVariableConverter) toHcl(ctx context.Context, tenant octopus.TenantVariable, recursive bool, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, tenant.TenantId, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
+	environmentNames := map[string]string{}
+
+	for _, projectId := range sliceutil.SortedKeys(tenant.ProjectVariables) {
+		p := tenant.ProjectVariables[projectId]
 
-	for _, p := range tenant.ProjectVariables {
+		for _, env := range sliceutil.SortedKeys(p.Variables) {
+			env := env
+			variable := p.Variables[env]
 
-		projectVariableIndex := 0
+			environmentName, err := c.environmentName(ctx, env, environmentNames)
 
-		for env, variable := range p.Variables {
-			for templateId, value := range variable {
-				value := value
+			if err != nil {
+				return err
+			}
+
+			for _, templateId := range sliceutil.SortedKeys(variable) {
+				templateId := templateId
+				value := variable[templateId]
 
-				projectVariableIndex++
-				variableName := "tenantprojectvariable_" + fmt.Sprint(projectVariableIndex) + "_" + sanitizer.SanitizeName(tenant.TenantName)
+				// Many tenants set the same template, so the ID includes the tenant, project and environment
+				variableId := tenant.TenantId + "/" + projectId + "/" + env + "/" + templateId
+				variableName := dependencies.ResourceName("tenantprojectvariable_", c.GetResourceType(), variableId,
+					tenant.TenantName+"_"+p.ProjectName+"_"+environmentName+"_"+c.templateName(p.Templates, templateId))
 
 				thisResource := ResourceDetails{}
 				thisResource.FileName = "space_population/" + variableName + ".tf"
-				thisResource.Id = templateId
+				thisResource.Id = variableId
+				thisResource.Name = tenant.TenantName
 				thisResource.ResourceType = c.GetResourceType()
 				thisResource.Lookup = "${octopusdeploy_tenant_project_variable." + variableName + ".id}"
 				thisResource.ToHcl = func() (string, error) {
-					file := hclwrite.NewEmptyFile()
+					file := hcl.NewFile()
 
 					terraformResource := terraform2.TerraformTenantProjectVariable{
 						Type:          "octopusdeploy_tenant_project_variable",
@@ -75,28 +93,34 @@ func (c TenantVariableConverter) toHcl(tenant octopus.TenantVariable, recursive
 						TenantId:      dependencies.GetResource("Tenants", tenant.TenantId),
 						Value:         &value,
 					}
-					file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-					return string(file.Bytes()), nil
+					file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+					return file.Render(dependencies.Format)
 				}
-				dependencies.AddResource(thisResource)
+				dependencies.AddChildResource(ctx, thisResource)
 			}
 		}
 	}
 
-	for _, l := range tenant.LibraryVariables {
-		commonVariableIndex := 0
+	for _, libraryVariableSetId := range sliceutil.SortedKeys(tenant.LibraryVariables) {
+		l := tenant.LibraryVariables[libraryVariableSetId]
+
+		for _, id := range sliceutil.SortedKeys(l.Variables) {
+			id := id
+			value := l.Variables[id]
 
-		for id, value := range l.Variables {
-			commonVariableIndex++
-			variableName := "tenantcommonvariable" + fmt.Sprint(commonVariableIndex) + "_" + sanitizer.SanitizeName(tenant.TenantName)
+			// Many tenants set the same template, so the ID includes the tenant and library variable set
+			variableId := tenant.TenantId + "/" + libraryVariableSetId + "/" + id
+			variableName := dependencies.ResourceName("tenantcommonvariable_", c.GetResourceType(), variableId,
+				tenant.TenantName+"_"+l.LibraryVariableSetName+"_"+c.templateName(l.Templates, id))
 
 			thisResource := ResourceDetails{}
 			thisResource.FileName = "space_population/" + variableName + ".tf"
-			thisResource.Id = id
+			thisResource.Id = variableId
+			thisResource.Name = tenant.TenantName
 			thisResource.ResourceType = c.GetResourceType()
 			thisResource.Lookup = "${octopusdeploy_tenant_common_variable." + variableName + ".id}"
 			thisResource.ToHcl = func() (string, error) {
-				file := hclwrite.NewEmptyFile()
+				file := hcl.NewFile()
 				terraformResource := terraform2.TerraformTenantCommonVariable{
 					Type:                 "octopusdeploy_tenant_common_variable",
 					Name:                 variableName,
@@ -106,16 +130,49 @@ func (c TenantVariableConverter) toHcl(tenant octopus.TenantVariable, recursive
 					TenantId:             dependencies.GetResource("Tenants", tenant.TenantId),
 					Value:                &value,
 				}
-				file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-				return string(file.Bytes()), nil
+				file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+				return file.Render(dependencies.Format)
 			}
-			dependencies.AddResource(thisResource)
+			dependencies.AddChildResource(ctx, thisResource)
 		}
 	}
 
 	return nil
 }
 
+// environmentName returns the name of the environment with the ID, or the ID if the environment no longer exists.
+// The names are cached, as each tenant sets variables for the same environments.
+func (c TenantVariableConverter) environmentName(ctx context.Context, id string, names map[string]string) (string, error) {
+	if name, ok := names[id]; ok {
+		return name, nil
+	}
+
+	environment := octopus.Environment{}
+	found, err := c.Client.GetResourceById(ctx, "Environments", id, &environment)
+
+	if err != nil {
+		return "", err
+	}
+
+	names[id] = id
+	if found && environment.Name != "" {
+		names[id] = environment.Name
+	}
+
+	return names[id], nil
+}
+
+// templateName returns the name of the template with the ID, or the ID if the template is not listed.
+func (c TenantVariableConverter) templateName(templates []octopus.Template, id string) string {
+	for _, template := range templates {
+		if template.Id == id && strutil.EmptyIfNil(template.Name) != "" {
+			return *template.Name
+		}
+	}
+
+	return id
+}
+
 func (c TenantVariableConverter) GetResourceType() string {
 	return "TenantVariables/All"
 }
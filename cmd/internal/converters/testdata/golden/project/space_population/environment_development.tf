@@ -0,0 +1,30 @@
+# Import existing resources with the following commands:
+# RESOURCE_ID=$(curl -H "X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}" https://octopus.example/api/Spaces-1/Environments | jq -r '.Items[] | select(.Name=="Development") | .Id')
+# terraform import octopusdeploy_environment.environment_development ${RESOURCE_ID}
+resource "octopusdeploy_environment" "environment_development" {
+  name                         = "Development"
+  description                  = "Development environment"
+  allow_dynamic_infrastructure = true
+  use_guided_failure           = false
+  sort_order                   = 0
+
+  jira_extension_settings {
+    environment_type = "unmapped"
+  }
+
+  jira_service_management_extension_settings {
+    is_enabled = false
+  }
+
+  servicenow_extension_settings {
+    is_enabled = false
+  }
+}
+# To use an existing environment, delete the resource above and use the following lookup instead:
+# data.octopusdeploy_environments.environment_development.environments[0].id
+data "octopusdeploy_environments" "environment_development" {
+  ids          = null
+  partial_name = "Development"
+  skip         = 0
+  take         = 1
+}
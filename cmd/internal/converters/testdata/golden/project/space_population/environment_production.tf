@@ -0,0 +1,30 @@
+# Import existing resources with the following commands:
+# RESOURCE_ID=$(curl -H "X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}" https://octopus.example/api/Spaces-1/Environments | jq -r '.Items[] | select(.Name=="Production") | .Id')
+# terraform import octopusdeploy_environment.environment_production ${RESOURCE_ID}
+resource "octopusdeploy_environment" "environment_production" {
+  name                         = "Production"
+  description                  = "Production environment"
+  allow_dynamic_infrastructure = false
+  use_guided_failure           = true
+  sort_order                   = 0
+
+  jira_extension_settings {
+    environment_type = "unmapped"
+  }
+
+  jira_service_management_extension_settings {
+    is_enabled = false
+  }
+
+  servicenow_extension_settings {
+    is_enabled = false
+  }
+}
+# To use an existing environment, delete the resource above and use the following lookup instead:
+# data.octopusdeploy_environments.environment_production.environments[0].id
+data "octopusdeploy_environments" "environment_production" {
+  ids          = null
+  partial_name = "Production"
+  skip         = 0
+  take         = 1
+}
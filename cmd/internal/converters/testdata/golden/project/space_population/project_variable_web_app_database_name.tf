@@ -0,0 +1,25 @@
+variable "web_app_database_name" {
+  type        = string
+  nullable    = false
+  sensitive   = false
+  description = "The value associated with the variable Database.Name"
+  default     = "webapp"
+}
+resource "octopusdeploy_variable" "web_app_database_name" {
+  owner_id     = "${octopusdeploy_project.project_web_app.id}"
+  value        = "${var.web_app_database_name}"
+  name         = "Database.Name"
+  type         = "String"
+  description  = "The database name"
+  is_sensitive = false
+
+  scope {
+    actions      = []
+    channels     = []
+    environments = ["${octopusdeploy_environment.environment_development.id}"]
+    machines     = []
+    roles        = null
+    tenant_tags  = null
+  }
+  depends_on = []
+}
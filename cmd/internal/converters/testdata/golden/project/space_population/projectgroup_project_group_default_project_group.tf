@@ -0,0 +1,6 @@
+data "octopusdeploy_project_groups" "project_group_default_project_group" {
+  ids          = null
+  partial_name = "Default Project Group"
+  skip         = 0
+  take         = 1
+}
@@ -0,0 +1,7 @@
+resource "octopusdeploy_tag" "tag_eu_west_1" {
+  name        = "eu-west-1"
+  tag_set_id  = "${octopusdeploy_tag_set.tagset_regions.id}"
+  color       = "#555555"
+  description = ""
+  sort_order  = 1
+}
@@ -0,0 +1,7 @@
+resource "octopusdeploy_tag" "tag_us_east_1" {
+  name        = "us-east-1"
+  tag_set_id  = "${octopusdeploy_tag_set.tagset_regions.id}"
+  color       = "#333333"
+  description = ""
+  sort_order  = 0
+}
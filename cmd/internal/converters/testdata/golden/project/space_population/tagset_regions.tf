@@ -0,0 +1,8 @@
+# Import existing resources with the following commands:
+# RESOURCE_ID=$(curl -H "X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}" https://octopus.example/api/Spaces-1/TagSets | jq -r '.Items[] | select(.Name=="Regions") | .Id')
+# terraform import octopusdeploy_tag_set.tagset_regions ${RESOURCE_ID}
+resource "octopusdeploy_tag_set" "tagset_regions" {
+  name        = "Regions"
+  description = "Deployment regions"
+  sort_order  = 0
+}
@@ -0,0 +1,15 @@
+# Import existing resources with the following commands:
+# RESOURCE_ID=$(curl -H "X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}" https://octopus.example/api/Spaces-1/Tenants | jq -r '.Items[] | select(.Name=="Acme") | .Id')
+# terraform import octopusdeploy_tenant.tenant_acme ${RESOURCE_ID}
+resource "octopusdeploy_tenant" "tenant_acme" {
+  name        = "Acme"
+  description = "Our first customer"
+  tenant_tags = ["Regions/us-east-1"]
+
+  project_environment {
+    environments = ["${octopusdeploy_environment.environment_development.id}", "${octopusdeploy_environment.environment_production.id}"]
+    project_id   = "${octopusdeploy_project.project_web_app.id}"
+  }
+
+  depends_on = [octopusdeploy_tag.tag_us_east_1,octopusdeploy_tag_set.tagset_regions]
+}
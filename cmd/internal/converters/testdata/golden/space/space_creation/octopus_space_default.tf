@@ -0,0 +1,21 @@
+# Import existing resources with the following commands:
+# RESOURCE_ID=$(curl -H "X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}" https://octopus.example/api/Spaces | jq -r '.Items[] | select(.Name=="Default") | .Id')
+# terraform import octopusdeploy_space.octopus_space_default ${RESOURCE_ID}
+resource "octopusdeploy_space" "octopus_space_default" {
+  description                 = "The default space"
+  name                        = "${var.octopus_space_name}"
+  is_default                  = true
+  is_task_queue_stopped       = false
+  space_managers_team_members = null
+  space_managers_teams        = ["teams-administrators"]
+}
+output "octopus_space_id" {
+  value = "${octopusdeploy_space.octopus_space_default.id}"
+}
+variable "octopus_space_name" {
+  type        = string
+  nullable    = false
+  sensitive   = false
+  description = "The name of the new space (the exported space was called Default)"
+  default     = "Default"
+}
@@ -0,0 +1,7 @@
+output "spaces" {
+  description = "The IDs of the spaces, keyed by name."
+  sensitive   = false
+  value       = {
+    "Default" = "${octopusdeploy_space.octopus_space_default.id}"
+  }
+}
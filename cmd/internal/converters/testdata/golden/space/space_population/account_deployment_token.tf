@@ -0,0 +1,18 @@
+# Import existing resources with the following commands:
+# RESOURCE_ID=$(curl -H "X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}" https://octopus.example/api/Spaces-1/Accounts | jq -r '.Items[] | select(.Name=="Deployment Token") | .Id')
+# terraform import octopusdeploy_token_account.account_deployment_token ${RESOURCE_ID}
+resource "octopusdeploy_token_account" "account_deployment_token" {
+  name                              = "Deployment Token"
+  description                       = "A token used by deployments"
+  environments                      = ["${octopusdeploy_environment.environment_development.id}"]
+  tenant_tags                       = []
+  tenants                           = []
+  tenanted_deployment_participation = "Untenanted"
+  token                             = "${var.account_deployment_token}"
+}
+variable "account_deployment_token" {
+  type        = string
+  nullable    = false
+  sensitive   = true
+  description = "The token associated with the account Deployment Token"
+}
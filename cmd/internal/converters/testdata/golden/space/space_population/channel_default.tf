@@ -0,0 +1,6 @@
+data "octopusdeploy_channels" "channel_default" {
+  ids          = null
+  partial_name = "Default"
+  skip         = 0
+  take         = 1
+}
@@ -0,0 +1,6 @@
+terraform {
+
+  required_providers {
+    octopusdeploy = { source = "OctopusDeployLabs/octopusdeploy", version = "0.10.1" }
+  }
+}
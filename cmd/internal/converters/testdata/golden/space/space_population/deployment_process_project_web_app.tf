@@ -0,0 +1,34 @@
+resource "octopusdeploy_deployment_process" "deployment_process_project_web_app" {
+  project_id = "${octopusdeploy_project.project_web_app.id}"
+
+  step {
+    condition           = "Success"
+    name                = "Deploy Web App"
+    package_requirement = "LetOctopusDecide"
+    start_trigger       = "StartAfterPrevious"
+
+    action {
+      action_type                        = "Octopus.Script"
+      name                               = "Deploy Web App"
+      condition                          = "Success"
+      run_on_server                      = false
+      is_disabled                        = false
+      can_be_used_for_project_versioning = false
+      is_required                        = false
+      worker_pool_id                     = ""
+      properties                         = {
+        "Octopus.Action.Script.ScriptBody" = "echo \"Deploying to #{Octopus.Environment.Name}\""
+        "Octopus.Action.Script.ScriptSource" = "Inline"
+        "Octopus.Action.Script.Syntax" = "Bash"
+      }
+      environments                       = []
+      excluded_environments              = []
+      channels                           = []
+      tenant_tags                        = []
+      features                           = []
+    }
+
+    properties   = {}
+    target_roles = ["web"]
+  }
+}
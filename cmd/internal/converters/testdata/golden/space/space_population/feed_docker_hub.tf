@@ -0,0 +1,17 @@
+# Import existing resources with the following commands:
+# RESOURCE_ID=$(curl -H "X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}" https://octopus.example/api/Spaces-1/Feeds | jq -r '.Items[] | select(.Name=="Docker Hub") | .Id')
+# terraform import octopusdeploy_docker_container_registry.feed_docker_hub ${RESOURCE_ID}
+resource "octopusdeploy_docker_container_registry" "feed_docker_hub" {
+  name                                 = "Docker Hub"
+  password                             = "${var.feed_docker_hub_password}"
+  registry_path                        = ""
+  api_version                          = "v1"
+  feed_uri                             = "https://index.docker.io"
+  package_acquisition_location_options = ["ExecutionTarget", "NotAcquired"]
+}
+variable "feed_docker_hub_password" {
+  type        = string
+  nullable    = false
+  sensitive   = true
+  description = "The password used by the feed Docker Hub"
+}
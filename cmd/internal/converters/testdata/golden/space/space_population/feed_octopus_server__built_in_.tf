@@ -0,0 +1,7 @@
+data "octopusdeploy_feeds" "built_in_feed" {
+  feed_type    = "BuiltIn"
+  ids          = null
+  partial_name = ""
+  skip         = 0
+  take         = 1
+}
@@ -0,0 +1,12 @@
+# Import existing resources with the following commands:
+# RESOURCE_ID=$(curl -H "X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}" https://octopus.example/api/Spaces-1/LibraryVariableSets | jq -r '.Items[] | select(.Name=="Logging") | .Id')
+# terraform import octopusdeploy_script_module.library_variable_set_logging ${RESOURCE_ID}
+resource "octopusdeploy_script_module" "library_variable_set_logging" {
+  description = "Logging functions shared between scripts"
+  name        = "Logging"
+
+  script {
+    body   = "function Write-Log($Message) {\n    Write-Host \"$${Message}\"\n}\n"
+    syntax = "PowerShell"
+  }
+}
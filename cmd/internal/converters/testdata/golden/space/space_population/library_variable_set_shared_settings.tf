@@ -0,0 +1,15 @@
+# Import existing resources with the following commands:
+# RESOURCE_ID=$(curl -H "X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}" https://octopus.example/api/Spaces-1/LibraryVariableSets | jq -r '.Items[] | select(.Name=="Shared Settings") | .Id')
+# terraform import octopusdeploy_library_variable_set.library_variable_set_shared_settings ${RESOURCE_ID}
+resource "octopusdeploy_library_variable_set" "library_variable_set_shared_settings" {
+  name        = "Shared Settings"
+  description = "Settings shared between projects"
+}
+# To use an existing environment, delete the resource above and use the following lookup instead:
+# data.octopusdeploy_library_variable_sets.library_variable_set_shared_settings.library_variable_sets[0].id
+data "octopusdeploy_library_variable_sets" "library_variable_set_shared_settings" {
+  ids          = null
+  partial_name = "Shared Settings"
+  skip         = 0
+  take         = 1
+}
@@ -0,0 +1,87 @@
+output "accounts" {
+  description = "The IDs of the accounts, keyed by name."
+  sensitive   = false
+  value       = {
+    "Deployment Token" = "${octopusdeploy_token_account.account_deployment_token.id}"
+  }
+}
+output "environments" {
+  description = "The IDs of the environments, keyed by name."
+  sensitive   = false
+  value       = {
+    "Development" = "${octopusdeploy_environment.environment_development.id}"
+    "Production" = "${octopusdeploy_environment.environment_production.id}"
+  }
+}
+output "feeds" {
+  description = "The IDs of the feeds, keyed by name."
+  sensitive   = false
+  value       = {
+    "Docker Hub" = "${octopusdeploy_docker_container_registry.feed_docker_hub.id}"
+    "Octopus Server (built-in)" = "${data.octopusdeploy_feeds.built_in_feed.feeds[0].id}"
+  }
+}
+output "library_variable_sets" {
+  description = "The IDs of the library variable sets, keyed by name."
+  sensitive   = false
+  value       = {
+    "Logging" = "${octopusdeploy_script_module.library_variable_set_logging.id}"
+    "Shared Settings" = "${octopusdeploy_library_variable_set.library_variable_set_shared_settings.id}"
+  }
+}
+output "lifecycles" {
+  description = "The IDs of the lifecycles, keyed by name."
+  sensitive   = false
+  value       = {
+    "Default Lifecycle" = "${data.octopusdeploy_lifecycles.lifecycle_default_lifecycle.lifecycles[0].id}"
+  }
+}
+output "machine_policies" {
+  description = "The IDs of the machine policies, keyed by name."
+  sensitive   = false
+  value       = {
+    "Default Machine Policy" = "${data.octopusdeploy_machine_policies.default_machine_policy.machine_policies[0].id}"
+  }
+}
+output "machines" {
+  description = "The IDs of the machines, keyed by name."
+  sensitive   = false
+  value       = {
+    "Web Server" = "${octopusdeploy_listening_tentacle_deployment_target.target_web_server.id}"
+  }
+}
+output "project_groups" {
+  description = "The IDs of the project groups, keyed by name."
+  sensitive   = false
+  value       = {
+    "Default Project Group" = "${data.octopusdeploy_project_groups.project_group_default_project_group.project_groups[0].id}"
+  }
+}
+output "projects" {
+  description = "The IDs of the projects, keyed by name."
+  sensitive   = false
+  value       = {
+    "Web App" = "${octopusdeploy_project.project_web_app.id}"
+  }
+}
+output "tag_sets" {
+  description = "The IDs of the tag sets, keyed by name."
+  sensitive   = false
+  value       = {
+    "Regions" = "${octopusdeploy_tag_set.tagset_regions.id}"
+  }
+}
+output "tenants" {
+  description = "The IDs of the tenants, keyed by name."
+  sensitive   = false
+  value       = {
+    "Acme" = "${octopusdeploy_tenant.tenant_acme.id}"
+  }
+}
+output "worker_pools" {
+  description = "The IDs of the worker pools, keyed by name."
+  sensitive   = false
+  value       = {
+    "Default Worker Pool" = "${data.octopusdeploy_worker_pools.workerpool_default_worker_pool.worker_pools[0].id}"
+  }
+}
@@ -0,0 +1,31 @@
+# Import existing resources with the following commands:
+# RESOURCE_ID=$(curl -H "X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}" https://octopus.example/api/Spaces-1/Projects | jq -r '.Items[] | select(.Name=="Web App") | .Id')
+# terraform import octopusdeploy_project.project_web_app ${RESOURCE_ID}
+resource "octopusdeploy_project" "project_web_app" {
+  name                                 = "Web App"
+  auto_create_release                  = false
+  default_guided_failure_mode          = "EnvironmentDefault"
+  default_to_skip_if_already_installed = false
+  description                          = "Deploys the web app"
+  discrete_channel_release             = false
+  is_disabled                          = false
+  is_version_controlled                = false
+  lifecycle_id                         = "${data.octopusdeploy_lifecycles.lifecycle_default_lifecycle.lifecycles[0].id}"
+  project_group_id                     = "${data.octopusdeploy_project_groups.project_group_default_project_group.project_groups[0].id}"
+  included_library_variable_sets       = ["${octopusdeploy_library_variable_set.library_variable_set_shared_settings.id}"]
+  tenanted_deployment_participation    = "TenantedOrUntenanted"
+
+  template {
+    name             = "Tenant.Database"
+    label            = "Database name"
+    help_text        = "The tenant database"
+    default_value    = ""
+    display_settings = { "Octopus.ControlType" = "SingleLineText" }
+  }
+
+  connectivity_policy {
+    allow_deployments_to_no_targets = true
+    exclude_unhealthy_targets       = false
+    skip_machine_behavior           = "None"
+  }
+}
@@ -0,0 +1,24 @@
+variable "library_variable_set_shared_settings_shared_url" {
+  type        = string
+  nullable    = false
+  sensitive   = false
+  description = "The value associated with the variable Shared.Url"
+  default     = "https://example.org"
+}
+resource "octopusdeploy_variable" "library_variable_set_shared_settings_shared_url" {
+  owner_id     = "${octopusdeploy_library_variable_set.library_variable_set_shared_settings.id}"
+  value        = "${var.library_variable_set_shared_settings_shared_url}"
+  name         = "Shared.Url"
+  type         = "String"
+  is_sensitive = false
+
+  scope {
+    actions      = []
+    channels     = []
+    environments = []
+    machines     = []
+    roles        = null
+    tenant_tags  = null
+  }
+  depends_on = []
+}
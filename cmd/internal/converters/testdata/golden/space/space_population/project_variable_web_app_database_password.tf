@@ -0,0 +1,23 @@
+variable "web_app_database_password" {
+  type        = string
+  nullable    = false
+  sensitive   = true
+  description = "The secret variable value associated with the variable Database.Password"
+}
+resource "octopusdeploy_variable" "web_app_database_password" {
+  owner_id        = "${octopusdeploy_project.project_web_app.id}"
+  name            = "Database.Password"
+  type            = "Sensitive"
+  sensitive_value = "${var.web_app_database_password}"
+  is_sensitive    = true
+
+  scope {
+    actions      = []
+    channels     = []
+    environments = []
+    machines     = []
+    roles        = null
+    tenant_tags  = null
+  }
+  depends_on = []
+}
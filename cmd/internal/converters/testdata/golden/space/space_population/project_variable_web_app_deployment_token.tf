@@ -0,0 +1,17 @@
+resource "octopusdeploy_variable" "web_app_deployment_token" {
+  owner_id     = "${octopusdeploy_project.project_web_app.id}"
+  value        = "${octopusdeploy_token_account.account_deployment_token.id}"
+  name         = "Deployment.Token"
+  type         = "TokenAccount"
+  is_sensitive = false
+
+  scope {
+    actions      = []
+    channels     = []
+    environments = []
+    machines     = []
+    roles        = null
+    tenant_tags  = null
+  }
+  depends_on = []
+}
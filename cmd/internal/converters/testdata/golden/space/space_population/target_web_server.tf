@@ -0,0 +1,21 @@
+# Import existing resources with the following commands:
+# RESOURCE_ID=$(curl -H "X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}" https://octopus.example/api/Spaces-1/Machines | jq -r '.Items[] | select(.Name=="Web Server") | .Id')
+# terraform import octopusdeploy_listening_tentacle_deployment_target.target_web_server ${RESOURCE_ID}
+resource "octopusdeploy_listening_tentacle_deployment_target" "target_web_server" {
+  environments                      = ["${octopusdeploy_environment.environment_development.id}"]
+  name                              = "Web Server"
+  roles                             = ["web"]
+  tentacle_url                      = "https://webserver:10933/"
+  thumbprint                        = "1854A302E5D9EAC1CAA3DA1F5249F82C28BB2B86"
+  is_disabled                       = false
+  is_in_process                     = false
+  machine_policy_id                 = "${data.octopusdeploy_machine_policies.default_machine_policy.machine_policies[0].id}"
+  shell_name                        = ""
+  shell_version                     = ""
+  tenant_tags                       = []
+  tenanted_deployment_participation = "Untenanted"
+  tenants                           = []
+
+  tentacle_version_details {
+  }
+}
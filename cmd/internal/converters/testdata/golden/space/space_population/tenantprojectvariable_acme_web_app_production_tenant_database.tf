@@ -0,0 +1,7 @@
+resource "octopusdeploy_tenant_project_variable" "tenantprojectvariable_acme_web_app_production_tenant_database" {
+  environment_id = "${octopusdeploy_environment.environment_production.id}"
+  project_id     = "${octopusdeploy_project.project_web_app.id}"
+  template_id    = "${octopusdeploy_project.project_web_app.template[0].id}"
+  tenant_id      = "${octopusdeploy_tenant.tenant_acme.id}"
+  value          = "acme_production"
+}
@@ -0,0 +1,6 @@
+data "octopusdeploy_worker_pools" "workerpool_default_worker_pool" {
+  name = "Default Worker Pool"
+  ids  = null
+  skip = 0
+  take = 1
+}
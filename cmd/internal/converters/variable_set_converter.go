@@ -1,9 +1,7 @@
 package converters
 
 import (
-	"fmt"
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
@@ -12,6 +10,7 @@ import (
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
 	"k8s.io/utils/strings/slices"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -39,65 +38,79 @@ type VariableSetConverter struct {
 	WorkerPoolConverter               ConverterById
 }
 
-func (c VariableSetConverter) ToHclByIdAndName(id string, parentName string, parentLookup string, dependencies *ResourceDetailsCollection) error {
+func (c VariableSetConverter) ToHclByIdAndName(ctx context.Context, id string, parentName string, parentLookup string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	resource := octopus2.VariableSet{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &resource)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &resource)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.toHcl(resource, true, parentName, parentLookup, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, resource, true, parentName, parentLookup, dependencies))
 }
 
-func (c VariableSetConverter) toHcl(resource octopus2.VariableSet, recursive bool, parentName string, parentLookup string, dependencies *ResourceDetailsCollection) error {
+func (c VariableSetConverter) toHcl(ctx context.Context, resource octopus2.VariableSet, recursive bool, parentName string, parentLookup string, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, strutil.EmptyIfNil(resource.Id), c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
 	if recursive {
-		c.exportChildDependencies(resource, dependencies)
+		err := c.exportChildDependencies(ctx, resource, dependencies)
+
+		if err != nil {
+			return err
+		}
 	}
 
-	for i, v := range resource.Variables {
+	for _, v := range resource.Variables {
 		v := v
-		file := hclwrite.NewEmptyFile()
+		file := hcl.NewFile()
 		thisResource := ResourceDetails{}
 
-		resourceName := sanitizer.SanitizeName(parentName) + "_" + sanitizer.SanitizeName(v.Name) + "_" + fmt.Sprint(i)
+		resourceName := dependencies.ResourceName(sanitizer.SanitizeName(parentName)+"_", c.GetResourceType(), v.Id, v.Name)
+
+		// The dependencies found in the variable are recorded against the variable rather than the variable set
+		variableCtx := withParent(ctx, c.GetResourceType(), v.Id)
 
 		if recursive {
 			// Export linked accounts
-			err := c.exportAccounts(v.Value, dependencies)
+			err := c.exportAccounts(variableCtx, v.Value, dependencies)
 			if err != nil {
 				return err
 			}
 
 			// Export linked feeds
-			err = c.exportFeeds(v.Value, dependencies)
+			err = c.exportFeeds(variableCtx, v.Value, dependencies)
 			if err != nil {
 				return err
 			}
 
 			// Export linked certificates
-			err = c.exportCertificates(v.Value, dependencies)
+			err = c.exportCertificates(variableCtx, v.Value, dependencies)
 			if err != nil {
 				return err
 			}
 
 			// Export linked worker pools
-			err = c.exportWorkerPools(v.Value, dependencies)
+			err = c.exportWorkerPools(variableCtx, v.Value, dependencies)
 			if err != nil {
 				return err
 			}
 
 			// Export linked environments
 			for _, e := range v.Scope.Environment {
-				err = c.EnvironmentConverter.ToHclById(e, dependencies)
+				err = c.EnvironmentConverter.ToHclById(variableCtx, e, dependencies)
 				if err != nil {
 					return err
 				}
@@ -105,54 +118,54 @@ func (c VariableSetConverter) toHcl(resource octopus2.VariableSet, recursive boo
 
 			// Export linked targets
 			for _, m := range v.Scope.Machine {
-				err = c.AzureCloudServiceTargetConverter.ToHclById(m, dependencies)
+				err = c.AzureCloudServiceTargetConverter.ToHclById(variableCtx, m, dependencies)
 				if err != nil {
 					return err
 				}
 
-				err = c.AzureServiceFabricTargetConverter.ToHclById(m, dependencies)
+				err = c.AzureServiceFabricTargetConverter.ToHclById(variableCtx, m, dependencies)
 				if err != nil {
 					return err
 				}
 
-				err = c.AzureWebAppTargetConverter.ToHclById(m, dependencies)
+				err = c.AzureWebAppTargetConverter.ToHclById(variableCtx, m, dependencies)
 				if err != nil {
 					return err
 				}
 
-				err = c.CloudRegionTargetConverter.ToHclById(m, dependencies)
+				err = c.CloudRegionTargetConverter.ToHclById(variableCtx, m, dependencies)
 				if err != nil {
 					return err
 				}
 
-				err = c.KubernetesTargetConverter.ToHclById(m, dependencies)
+				err = c.KubernetesTargetConverter.ToHclById(variableCtx, m, dependencies)
 				if err != nil {
 					return err
 				}
 
-				err = c.ListeningTargetConverter.ToHclById(m, dependencies)
+				err = c.ListeningTargetConverter.ToHclById(variableCtx, m, dependencies)
 				if err != nil {
 					return err
 				}
 
-				err = c.OfflineDropTargetConverter.ToHclById(m, dependencies)
+				err = c.OfflineDropTargetConverter.ToHclById(variableCtx, m, dependencies)
 				if err != nil {
 					return err
 				}
 
-				err = c.PollingTargetConverter.ToHclById(m, dependencies)
+				err = c.PollingTargetConverter.ToHclById(variableCtx, m, dependencies)
 				if err != nil {
 					return err
 				}
 
-				err = c.SshTargetConverter.ToHclById(m, dependencies)
+				err = c.SshTargetConverter.ToHclById(variableCtx, m, dependencies)
 				if err != nil {
 					return err
 				}
 			}
 		}
 
-		tagSetDependencies, err := c.addTagSetDependencies(v, recursive, dependencies)
+		tagSetDependencies, err := c.addTagSetDependencies(variableCtx, v, recursive, dependencies)
 
 		if err != nil {
 			return err
@@ -160,6 +173,7 @@ func (c VariableSetConverter) toHcl(resource octopus2.VariableSet, recursive boo
 
 		thisResource.FileName = "space_population/project_variable_" + resourceName + ".tf"
 		thisResource.Id = v.Id
+		thisResource.Name = v.Name
 		thisResource.ResourceType = c.GetResourceType()
 		thisResource.Lookup = "${octopusdeploy_variable." + resourceName + ".id}"
 		thisResource.ToHcl = func() (string, error) {
@@ -193,9 +207,9 @@ func (c VariableSetConverter) toHcl(resource octopus2.VariableSet, recursive boo
 					Description: "The secret variable value associated with the variable " + v.Name,
 				}
 
-				block := gohcl.EncodeAsBlock(secretVariableResource, "variable")
+				block := hcl.EncodeAsBlock(secretVariableResource, "variable")
 				hcl.WriteUnquotedAttribute(block, "type", "string")
-				file.Body().AppendBlock(block)
+				file.AppendBlock(block)
 			} else if v.Type == "String" {
 				// Use a second terraform variable to allow the octopus variable to be defined at apply time.
 				// Note this only applies to string variables, as other types likely reference resources
@@ -211,16 +225,16 @@ func (c VariableSetConverter) toHcl(resource octopus2.VariableSet, recursive boo
 					Default:     value,
 				}
 
-				block := gohcl.EncodeAsBlock(regularVariable, "variable")
+				block := hcl.EncodeAsBlock(regularVariable, "variable")
 				hcl.WriteUnquotedAttribute(block, "type", "string")
 				// variable lookups need to be raw expressions
 				if hcl.IsInterpolation(strutil.EmptyIfNil(value)) {
 					hcl.WriteUnquotedAttribute(block, "default", strutil.EmptyIfNil(value))
 				}
-				file.Body().AppendBlock(block)
+				file.AppendBlock(block)
 			}
 
-			block := gohcl.EncodeAsBlock(terraformResource, "resource")
+			block := hcl.EncodeAsBlock(terraformResource, "resource")
 
 			// Explicitly describe the dependency between a target and a tag set
 			dependsOn := []string{}
@@ -231,13 +245,14 @@ func (c VariableSetConverter) toHcl(resource octopus2.VariableSet, recursive boo
 					dependsOn = append(dependsOn, dependency)
 				}
 			}
+			sort.Strings(dependsOn)
 			hcl.WriteUnquotedAttribute(block, "depends_on", "["+strings.Join(dependsOn[:], ",")+"]")
 
-			file.Body().AppendBlock(block)
+			file.AppendBlock(block)
 
-			return string(file.Bytes()), nil
+			return file.Render(dependencies.Format)
 		}
-		dependencies.AddResource(thisResource)
+		dependencies.AddChildResource(ctx, thisResource)
 	}
 
 	return nil
@@ -289,14 +304,14 @@ func (c VariableSetConverter) convertScope(prompt octopus2.Scope, dependencies *
 
 }
 
-func (c VariableSetConverter) exportAccounts(value *string, dependencies *ResourceDetailsCollection) error {
+func (c VariableSetConverter) exportAccounts(ctx context.Context, value *string, dependencies *ResourceDetailsCollection) error {
 	if value == nil {
 		return nil
 	}
 
 	accountRegex, _ := regexp.Compile("Accounts-\\d+")
 	for _, account := range accountRegex.FindAllString(*value, -1) {
-		err := c.AccountConverter.ToHclById(account, dependencies)
+		err := c.AccountConverter.ToHclById(ctx, account, dependencies)
 
 		if err != nil {
 			return err
@@ -320,14 +335,14 @@ func (c VariableSetConverter) getAccount(value *string, dependencies *ResourceDe
 	return &retValue
 }
 
-func (c VariableSetConverter) exportFeeds(value *string, dependencies *ResourceDetailsCollection) error {
+func (c VariableSetConverter) exportFeeds(ctx context.Context, value *string, dependencies *ResourceDetailsCollection) error {
 	if value == nil {
 		return nil
 	}
 
 	feedRegex, _ := regexp.Compile("Feeds-\\d+")
 	for _, account := range feedRegex.FindAllString(*value, -1) {
-		err := c.FeedConverter.ToHclById(account, dependencies)
+		err := c.FeedConverter.ToHclById(ctx, account, dependencies)
 
 		if err != nil {
 			return err
@@ -351,14 +366,14 @@ func (c VariableSetConverter) getFeeds(value *string, dependencies *ResourceDeta
 	return &retValue
 }
 
-func (c VariableSetConverter) exportCertificates(value *string, dependencies *ResourceDetailsCollection) error {
+func (c VariableSetConverter) exportCertificates(ctx context.Context, value *string, dependencies *ResourceDetailsCollection) error {
 	if value == nil {
 		return nil
 	}
 
 	regex, _ := regexp.Compile("Certificates-\\d+")
 	for _, cert := range regex.FindAllString(*value, -1) {
-		err := c.CertificateConverter.ToHclById(cert, dependencies)
+		err := c.CertificateConverter.ToHclById(ctx, cert, dependencies)
 
 		if err != nil {
 			return err
@@ -382,14 +397,14 @@ func (c VariableSetConverter) getCertificates(value *string, dependencies *Resou
 	return &retValue
 }
 
-func (c VariableSetConverter) exportWorkerPools(value *string, dependencies *ResourceDetailsCollection) error {
+func (c VariableSetConverter) exportWorkerPools(ctx context.Context, value *string, dependencies *ResourceDetailsCollection) error {
 	if value == nil {
 		return nil
 	}
 
 	regex, _ := regexp.Compile("WorkerPools-\\d+")
 	for _, cert := range regex.FindAllString(*value, -1) {
-		err := c.WorkerPoolConverter.ToHclById(cert, dependencies)
+		err := c.WorkerPoolConverter.ToHclById(ctx, cert, dependencies)
 
 		if err != nil {
 			return err
@@ -413,10 +428,10 @@ func (c VariableSetConverter) getWorkerPools(value *string, dependencies *Resour
 	return &retValue
 }
 
-func (c VariableSetConverter) exportChildDependencies(variableSet octopus2.VariableSet, dependencies *ResourceDetailsCollection) error {
+func (c VariableSetConverter) exportChildDependencies(ctx context.Context, variableSet octopus2.VariableSet, dependencies *ResourceDetailsCollection) error {
 	for _, v := range variableSet.Variables {
 		for _, e := range v.Scope.Environment {
-			err := c.EnvironmentConverter.ToHclById(e, dependencies)
+			err := c.EnvironmentConverter.ToHclById(ctx, e, dependencies)
 			if err != nil {
 				return err
 			}
@@ -428,9 +443,9 @@ func (c VariableSetConverter) exportChildDependencies(variableSet octopus2.Varia
 
 // addTagSetDependencies finds the tag sets that contains the tags associated with a tenant. These dependencies are
 // captured, as Terraform has no other way to map the dependency between a tagset and a tenant.
-func (c VariableSetConverter) addTagSetDependencies(variable octopus2.Variable, recursive bool, dependencies *ResourceDetailsCollection) (map[string][]string, error) {
+func (c VariableSetConverter) addTagSetDependencies(ctx context.Context, variable octopus2.Variable, recursive bool, dependencies *ResourceDetailsCollection) (map[string][]string, error) {
 	collection := octopus2.GeneralCollection[octopus2.TagSet]{}
-	err := c.Client.GetAllResources("TagSets", &collection)
+	err := c.Client.GetAllResources(ctx, "TagSets", &collection)
 
 	if err != nil {
 		return nil, err
@@ -452,7 +467,7 @@ func (c VariableSetConverter) addTagSetDependencies(variable octopus2.Variable,
 					}
 
 					if recursive {
-						err = c.TagSetConverter.ToHclByResource(tagSet, dependencies)
+						err = c.TagSetConverter.ToHclByResource(ctx, tagSet, dependencies)
 
 						if err != nil {
 							return nil, err
@@ -1,63 +1,69 @@
 package converters
 
 import (
-	"github.com/hashicorp/hcl2/gohcl"
-	"github.com/hashicorp/hcl2/hcl/hclsyntax"
-	"github.com/hashicorp/hcl2/hclwrite"
+	"context"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	octopus2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	terraform2 "github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
 )
 
 type WorkerPoolConverter struct {
 	Client client.OctopusClient
 }
 
-func (c WorkerPoolConverter) ToHcl(dependencies *ResourceDetailsCollection) error {
+func (c WorkerPoolConverter) ToHcl(ctx context.Context, dependencies *ResourceDetailsCollection) error {
 	collection := octopus2.GeneralCollection[octopus2.WorkerPool]{}
-	err := c.Client.GetAllResources(c.GetResourceType(), &collection)
+	err := c.Client.GetAllResources(ctx, c.GetResourceType(), &collection)
 
 	if err != nil {
 		return err
 	}
 
+	group := dependencies.WorkerPool.NewGroup()
 	for _, resource := range collection.Items {
-		err = c.toHcl(resource, false, dependencies)
-
-		if err != nil {
-			return err
-		}
+		resource := resource
+		group.Go(func() error {
+			return dependencies.RecordError(c.GetResourceType(), resource.Id, c.toHcl(ctx, resource, false, dependencies))
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
-func (c WorkerPoolConverter) ToHclById(id string, dependencies *ResourceDetailsCollection) error {
+func (c WorkerPoolConverter) ToHclById(ctx context.Context, id string, dependencies *ResourceDetailsCollection) error {
 	if id == "" {
 		return nil
 	}
 
+	dependencies.AddDependency(ctx, c.GetResourceType(), id)
+
 	if dependencies.HasResource(id, c.GetResourceType()) {
 		return nil
 	}
 
 	pool := octopus2.WorkerPool{}
-	_, err := c.Client.GetResourceById(c.GetResourceType(), id, &pool)
+	_, err := c.Client.GetResourceById(ctx, c.GetResourceType(), id, &pool)
 
 	if err != nil {
-		return err
+		return dependencies.RecordError(c.GetResourceType(), id, err)
 	}
 
-	return c.toHcl(pool, true, dependencies)
+	return dependencies.RecordError(c.GetResourceType(), id, c.toHcl(ctx, pool, true, dependencies))
 }
 
-func (c WorkerPoolConverter) toHcl(pool octopus2.WorkerPool, recursive bool, dependencies *ResourceDetailsCollection) error {
-	resourceName := "workerpool_" + sanitizer.SanitizeNamePointer(&pool.Name)
+func (c WorkerPoolConverter) toHcl(ctx context.Context, pool octopus2.WorkerPool, recursive bool, dependencies *ResourceDetailsCollection) error {
+	ctx, claimed := dependencies.ClaimResource(ctx, pool.Id, c.GetResourceType())
+	if !claimed {
+		return nil
+	}
+
+	resourceName := dependencies.ResourceName("workerpool_", c.GetResourceType(), pool.Id, pool.Name)
 
 	thisResource := ResourceDetails{}
 	thisResource.FileName = "space_population/" + resourceName + ".tf"
 	thisResource.Id = pool.Id
+	thisResource.Name = pool.Name
 	thisResource.ResourceType = c.GetResourceType()
 
 	if pool.WorkerPoolType == "DynamicWorkerPool" {
@@ -90,10 +96,10 @@ func (c WorkerPoolConverter) toHcl(pool octopus2.WorkerPool, recursive bool, dep
 					Skip:         0,
 					Take:         1,
 				}
-				file := hclwrite.NewEmptyFile()
-				file.Body().AppendBlock(gohcl.EncodeAsBlock(data, "data"))
+				file := hcl.NewFile()
+				file.AppendBlock(hcl.EncodeAsBlock(data, "data"))
 
-				return string(file.Bytes()), nil
+				return file.Render(dependencies.Format)
 			} else {
 				terraformResource := terraform2.TerraformWorkerPool{
 					Type:         "octopusdeploy_dynamic_worker_pool",
@@ -104,21 +110,17 @@ func (c WorkerPoolConverter) toHcl(pool octopus2.WorkerPool, recursive bool, dep
 					SortOrder:    pool.SortOrder,
 					WorkerType:   pool.WorkerType,
 				}
-				file := hclwrite.NewEmptyFile()
+				file := hcl.NewFile()
 
 				// Add a comment with the import command
-				baseUrl, _ := c.Client.GetSpaceBaseUrl()
-				file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-					Type: hclsyntax.TokenComment,
-					Bytes: []byte("# Import existing resources with the following commands:\n" +
-						"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + pool.Name + "\") | .Id')\n" +
-						"# terraform import octopusdeploy_dynamic_worker_pool." + resourceName + " ${RESOURCE_ID}\n"),
-					SpacesBefore: 0,
-				}})
-
-				file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-				return string(file.Bytes()), nil
+				baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+				file.AppendComment("# Import existing resources with the following commands:\n" +
+					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + pool.Name + "\") | .Id')\n" +
+					"# terraform import octopusdeploy_dynamic_worker_pool." + resourceName + " ${RESOURCE_ID}\n")
+
+				file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+				return file.Render(dependencies.Format)
 			}
 		}
 
@@ -136,10 +138,10 @@ func (c WorkerPoolConverter) toHcl(pool octopus2.WorkerPool, recursive bool, dep
 					Skip:         0,
 					Take:         1,
 				}
-				file := hclwrite.NewEmptyFile()
-				file.Body().AppendBlock(gohcl.EncodeAsBlock(data, "data"))
+				file := hcl.NewFile()
+				file.AppendBlock(hcl.EncodeAsBlock(data, "data"))
 
-				return string(file.Bytes()), nil
+				return file.Render(dependencies.Format)
 			} else {
 				terraformResource := terraform2.TerraformWorkerPool{
 					Type:         "octopusdeploy_static_worker_pool",
@@ -150,21 +152,17 @@ func (c WorkerPoolConverter) toHcl(pool octopus2.WorkerPool, recursive bool, dep
 					SortOrder:    pool.SortOrder,
 					WorkerType:   pool.WorkerType,
 				}
-				file := hclwrite.NewEmptyFile()
+				file := hcl.NewFile()
 
 				// Add a comment with the import command
-				baseUrl, _ := c.Client.GetSpaceBaseUrl()
-				file.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
-					Type: hclsyntax.TokenComment,
-					Bytes: []byte("# Import existing resources with the following commands:\n" +
-						"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + pool.Name + "\") | .Id')\n" +
-						"# terraform import octopusdeploy_static_worker_pool." + resourceName + " ${RESOURCE_ID}\n"),
-					SpacesBefore: 0,
-				}})
-
-				file.Body().AppendBlock(gohcl.EncodeAsBlock(terraformResource, "resource"))
-
-				return string(file.Bytes()), nil
+				baseUrl, _ := c.Client.GetSpaceBaseUrl(ctx)
+				file.AppendComment("# Import existing resources with the following commands:\n" +
+					"# RESOURCE_ID=$(curl -H \"X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}\" " + baseUrl + "/" + c.GetResourceType() + " | jq -r '.Items[] | select(.Name==\"" + pool.Name + "\") | .Id')\n" +
+					"# terraform import octopusdeploy_static_worker_pool." + resourceName + " ${RESOURCE_ID}\n")
+
+				file.AppendBlock(hcl.EncodeAsBlock(terraformResource, "resource"))
+
+				return file.Render(dependencies.Format)
 			}
 		}
 
@@ -0,0 +1,196 @@
+package credentials
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ApiKeyEnvVar is the environment variable holding the Octopus API key
+	ApiKeyEnvVar = "OCTOPUS_CLI_API_KEY"
+	// UrlEnvVar is the environment variable holding the Octopus URL
+	UrlEnvVar = "OCTOPUS_URL"
+	// AccessTokenEnvVar is the environment variable holding an Octopus access token
+	AccessTokenEnvVar = "OCTOPUS_ACCESS_TOKEN"
+	// DefaultProfile is the profile used when no profile is selected
+	DefaultProfile = "default"
+)
+
+// Credentials are the details used to connect to an Octopus instance. Any of the fields may be empty.
+type Credentials struct {
+	Url         string
+	ApiKey      string
+	AccessToken string
+	// OidcToken is an ID token from an external identity provider, exchanged for an access token
+	// for the service account identified by ServiceAccountId.
+	OidcToken        string
+	ServiceAccountId string
+}
+
+// Resolve combines the credentials from several sources, in order of precedence. The URL and service
+// account are taken from the first source that defines them. The secrets are all taken from the first
+// source that defines any secret, so an API key from one source never competes with a token from another.
+func Resolve(sources ...Credentials) Credentials {
+	resolved := Credentials{}
+
+	for _, source := range sources {
+		resolved.Url = firstNonEmpty(resolved.Url, source.Url)
+		resolved.ServiceAccountId = firstNonEmpty(resolved.ServiceAccountId, source.ServiceAccountId)
+
+		if !resolved.HasSecret() {
+			resolved.ApiKey = source.ApiKey
+			resolved.AccessToken = source.AccessToken
+			resolved.OidcToken = source.OidcToken
+		}
+	}
+
+	return resolved
+}
+
+// HasSecret returns true if the credentials include an API key, access token or OIDC token.
+func (c Credentials) HasSecret() bool {
+	return c.ApiKey != "" || c.AccessToken != "" || c.OidcToken != ""
+}
+
+// FromEnvironment reads the credentials from the environment variables.
+func FromEnvironment() Credentials {
+	return Credentials{
+		Url:         os.Getenv(UrlEnvVar),
+		ApiKey:      os.Getenv(ApiKeyEnvVar),
+		AccessToken: os.Getenv(AccessTokenEnvVar),
+	}
+}
+
+// DefaultCredentialsFile returns the location of the credentials file in the user's home directory.
+func DefaultCredentialsFile() string {
+	home, err := os.UserHomeDir()
+
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".octoterra", "credentials")
+}
+
+// FromProfile reads the named profile from the credentials file. If the file does not exist and the
+// profile was not explicitly requested, empty credentials are returned.
+func FromProfile(file string, profile string, explicit bool) (Credentials, error) {
+	if file == "" {
+		return Credentials{}, nil
+	}
+
+	f, err := os.Open(file)
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return Credentials{}, nil
+		}
+		return Credentials{}, err
+	}
+	defer f.Close()
+
+	profiles, err := ParseProfiles(f)
+
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+
+	credentials, ok := profiles[profile]
+
+	if !ok && explicit {
+		return Credentials{}, errors.New("did not find the profile " + profile + " in " + file)
+	}
+
+	return credentials, nil
+}
+
+// ParseProfiles reads an INI style credentials file, where each section is a named profile:
+//
+//	[default]
+//	url = https://myinstance.octopus.app
+//	api_key = API-XXXXXXXXXXXXXXXXXXXXXXXXXX
+//
+//	[production]
+//	url = https://production.octopus.app
+//	access_token = eyJhbGciOi...
+func ParseProfiles(reader io.Reader) (map[string]Credentials, error) {
+	profiles := map[string]Credentials{}
+	profile := ""
+	scanner := bufio.NewScanner(reader)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			profile = strings.TrimSpace(text[1 : len(text)-1])
+			profiles[profile] = profiles[profile]
+			continue
+		}
+
+		key, value, found := strings.Cut(text, "=")
+
+		if !found {
+			return nil, fmt.Errorf("line %d: expected key = value", line)
+		}
+
+		if profile == "" {
+			return nil, fmt.Errorf("line %d: settings must be placed in a [profile] section", line)
+		}
+
+		credentials := profiles[profile]
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "url":
+			credentials.Url = value
+		case "api_key":
+			credentials.ApiKey = value
+		case "access_token":
+			credentials.AccessToken = value
+		case "oidc_token":
+			credentials.OidcToken = value
+		case "service_account_id":
+			credentials.ServiceAccountId = value
+		default:
+			return nil, fmt.Errorf("line %d: unknown setting %s", line, strings.TrimSpace(key))
+		}
+
+		profiles[profile] = credentials
+	}
+
+	return profiles, scanner.Err()
+}
+
+// ReadSecret reads a secret from the first line of the reader, which is typically stdin.
+func ReadSecret(reader io.Reader) (string, error) {
+	scanner := bufio.NewScanner(reader)
+
+	if !scanner.Scan() {
+		if scanner.Err() != nil {
+			return "", scanner.Err()
+		}
+		return "", errors.New("no value was supplied on stdin")
+	}
+
+	return strings.TrimSpace(scanner.Text()), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
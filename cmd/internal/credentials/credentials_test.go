@@ -0,0 +1,86 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const profiles = `
+# Shared credentials
+[default]
+url = https://myinstance.octopus.app
+api_key = API-DEFAULT
+
+[production]
+url = https://production.octopus.app
+access_token = token=with=equals
+`
+
+func TestParseProfiles(t *testing.T) {
+	parsed, err := ParseProfiles(strings.NewReader(profiles))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed["default"].Url != "https://myinstance.octopus.app" || parsed["default"].ApiKey != "API-DEFAULT" {
+		t.Fatalf("unexpected default profile %+v", parsed["default"])
+	}
+
+	if parsed["production"].AccessToken != "token=with=equals" {
+		t.Fatalf("unexpected production profile %+v", parsed["production"])
+	}
+}
+
+func TestParseProfilesRejectsUnknownSettings(t *testing.T) {
+	_, err := ParseProfiles(strings.NewReader("[default]\npassword = secret\n"))
+
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expected an error reporting line 2, got %v", err)
+	}
+}
+
+func TestFromProfile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(file, []byte(profiles), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	credentials, err := FromProfile(file, "production", true)
+	if err != nil || credentials.Url != "https://production.octopus.app" {
+		t.Fatalf("unexpected credentials %+v, error %v", credentials, err)
+	}
+
+	if _, err := FromProfile(file, "missing", true); err == nil {
+		t.Fatal("expected an error for a missing profile that was explicitly requested")
+	}
+
+	if _, err := FromProfile(filepath.Join(t.TempDir(), "missing"), DefaultProfile, false); err != nil {
+		t.Fatalf("a missing credentials file must be ignored for the default profile, got %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	resolved := Resolve(
+		Credentials{AccessToken: "from-args"},
+		Credentials{Url: "https://env.octopus.app", ApiKey: "API-ENV"},
+		Credentials{Url: "https://profile.octopus.app", ApiKey: "API-PROFILE"})
+
+	if resolved.Url != "https://env.octopus.app" {
+		t.Fatalf("expected the URL from the environment, got %s", resolved.Url)
+	}
+
+	if resolved.AccessToken != "from-args" || resolved.ApiKey != "" {
+		t.Fatalf("expected only the secret from the arguments, got %+v", resolved)
+	}
+}
+
+func TestReadSecret(t *testing.T) {
+	secret, err := ReadSecret(strings.NewReader("API-STDIN\r\n"))
+
+	if err != nil || secret != "API-STDIN" {
+		t.Fatalf("unexpected secret %q, error %v", secret, err)
+	}
+}
@@ -0,0 +1,220 @@
+// Package dryrun describes the scope of an export from the discovered resources, so it can be reviewed before
+// the Terraform configuration is rendered and written.
+package dryrun
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/converters"
+)
+
+// Report lists the resources that would be exported, and the API calls needed to export them.
+type Report struct {
+	// Types lists the resources of each type, ordered by type
+	Types []TypeGroup
+	// Roots are the resources that were not pulled in as a dependency of another resource, like the project
+	// being exported, with the dependencies they pulled in
+	Roots []*Node
+	// DiscoveryCalls is the number of API calls made for each resource type while discovering the resources
+	DiscoveryCalls map[string]int
+	// RenderCalls is the estimated number of API calls made while rendering the resources
+	RenderCalls int
+}
+
+// TypeGroup is the resources of a single type.
+type TypeGroup struct {
+	Type      string
+	Resources []converters.ResourceDetails
+}
+
+// Node is a resource, and the resources that were discovered as its dependencies.
+type Node struct {
+	Resource     converters.ResourceDetails
+	Dependencies []*Node
+}
+
+type key struct {
+	resourceType string
+	id           string
+}
+
+// Build creates the report from the discovered resources and the API calls made to discover them. Only Octopus
+// resources that are rendered to their own blocks are included. Resources like project templates are rendered by
+// the resource that owns them.
+func Build(dependencies *converters.ResourceDetailsCollection, discoveryCalls map[string]int) Report {
+	report := Report{DiscoveryCalls: discoveryCalls}
+
+	nodes := map[key]*Node{}
+	types := map[string]*TypeGroup{}
+	for _, r := range dependencies.SortedResources() {
+		k := key{resourceType: r.ResourceType, id: r.Id}
+
+		// Resources without an ID, like the provider configuration, are not Octopus resources
+		if _, found := nodes[k]; found || r.ToHcl == nil || r.Id == "" {
+			continue
+		}
+
+		nodes[k] = &Node{Resource: r}
+
+		if _, found := types[r.ResourceType]; !found {
+			types[r.ResourceType] = &TypeGroup{Type: r.ResourceType}
+		}
+		types[r.ResourceType].Resources = append(types[r.ResourceType].Resources, r)
+	}
+
+	for _, node := range nodes {
+		parent := findParent(dependencies, node.Resource, nodes)
+
+		if parent == nil {
+			report.Roots = append(report.Roots, node)
+		} else {
+			parent.Dependencies = append(parent.Dependencies, node)
+		}
+	}
+
+	for _, node := range nodes {
+		sortNodes(node.Dependencies)
+	}
+	sortNodes(report.Roots)
+
+	for _, group := range types {
+		sort.SliceStable(group.Resources, func(i, j int) bool {
+			return lessResource(group.Resources[i], group.Resources[j])
+		})
+		report.Types = append(report.Types, *group)
+	}
+	sort.Slice(report.Types, func(i, j int) bool {
+		return report.Types[i].Type < report.Types[j].Type
+	})
+
+	// Rendering each resource looks up the space URL for the import instructions written with the resource
+	report.RenderCalls = len(nodes)
+
+	return report
+}
+
+// findParent returns the closest resource in the report that pulled in the resource, skipping any resources
+// that are not in the report.
+func findParent(dependencies *converters.ResourceDetailsCollection, resource converters.ResourceDetails, nodes map[key]*Node) *Node {
+	resourceType, id := resource.ResourceType, resource.Id
+
+	for {
+		parentType, parentId, found := dependencies.GetParent(resourceType, id)
+
+		if !found {
+			return nil
+		}
+
+		if parent, ok := nodes[key{resourceType: parentType, id: parentId}]; ok {
+			return parent
+		}
+
+		resourceType, id = parentType, parentId
+	}
+}
+
+func sortNodes(nodes []*Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return lessResource(nodes[i].Resource, nodes[j].Resource)
+	})
+}
+
+// lessResource orders resources by type, name and ID.
+func lessResource(a converters.ResourceDetails, b converters.ResourceDetails) bool {
+	if a.ResourceType != b.ResourceType {
+		return a.ResourceType < b.ResourceType
+	}
+
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+
+	return a.Id < b.Id
+}
+
+// ResourceCount returns the number of resources that would be exported.
+func (r Report) ResourceCount() int {
+	count := 0
+	for _, group := range r.Types {
+		count += len(group.Resources)
+	}
+	return count
+}
+
+// DiscoveryCallCount returns the number of API calls made to discover the resources.
+func (r Report) DiscoveryCallCount() int {
+	count := 0
+	for _, calls := range r.DiscoveryCalls {
+		count += calls
+	}
+	return count
+}
+
+// String formats the report as an indented list of the resources by type, the tree of resources by the dependency
+// that pulled them in, and the API calls.
+func (r Report) String() string {
+	builder := strings.Builder{}
+
+	builder.WriteString(fmt.Sprintf("%d resources would be exported.\n\nResources by type:\n", r.ResourceCount()))
+	for _, group := range r.Types {
+		builder.WriteString(fmt.Sprintf("  %s (%d)\n", group.Type, len(group.Resources)))
+		for _, resource := range group.Resources {
+			builder.WriteString("    " + describe(resource) + "\n")
+		}
+	}
+
+	builder.WriteString("\nResources by dependency:\n")
+	for _, root := range r.Roots {
+		writeNode(&builder, root, 1)
+	}
+
+	builder.WriteString("\nAPI calls made to discover the resources, by type:\n")
+	callTypes := make([]string, 0, len(r.DiscoveryCalls))
+	for resourceType := range r.DiscoveryCalls {
+		callTypes = append(callTypes, resourceType)
+	}
+	sort.Strings(callTypes)
+	for _, resourceType := range callTypes {
+		builder.WriteString(fmt.Sprintf("  %s: %d\n", resourceType, r.DiscoveryCalls[resourceType]))
+	}
+
+	builder.WriteString(fmt.Sprintf("\nEstimated API calls for the export: %d (%d to discover the resources, and %d to render them)\n",
+		r.DiscoveryCallCount()+r.RenderCalls, r.DiscoveryCallCount(), r.RenderCalls))
+
+	return builder.String()
+}
+
+func writeNode(builder *strings.Builder, node *Node, depth int) {
+	builder.WriteString(strings.Repeat("  ", depth) + node.Resource.ResourceType + ": " + describe(node.Resource))
+
+	if count := countDependencies(node); count == 1 {
+		builder.WriteString(" [1 dependency]")
+	} else if count > 1 {
+		builder.WriteString(fmt.Sprintf(" [%d dependencies]", count))
+	}
+
+	builder.WriteString("\n")
+
+	for _, dependency := range node.Dependencies {
+		writeNode(builder, dependency, depth+1)
+	}
+}
+
+// countDependencies returns the number of resources pulled in by the resource, directly or indirectly.
+func countDependencies(node *Node) int {
+	count := len(node.Dependencies)
+	for _, dependency := range node.Dependencies {
+		count += countDependencies(dependency)
+	}
+	return count
+}
+
+func describe(resource converters.ResourceDetails) string {
+	if resource.Name == "" {
+		return resource.Id
+	}
+
+	return "\"" + resource.Name + "\" (" + resource.Id + ")"
+}
@@ -0,0 +1,96 @@
+package dryrun
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/converters"
+)
+
+func TestBuild(t *testing.T) {
+	render := func() (string, error) { return "", nil }
+	dependencies := converters.ResourceDetailsCollection{}
+
+	// The project pulls in the lifecycle, which pulls in the environments
+	projectCtx, _ := dependencies.ClaimResource(context.Background(), "Projects-1", "Projects")
+	lifecycleCtx, _ := dependencies.ClaimResource(projectCtx, "Lifecycles-1", "Lifecycles")
+	dependencies.ClaimResource(lifecycleCtx, "Environments-2", "Environments")
+	dependencies.ClaimResource(lifecycleCtx, "Environments-1", "Environments")
+
+	dependencies.AddResource(
+		converters.ResourceDetails{FileName: "space_population/provider.tf", ToHcl: render},
+		converters.ResourceDetails{Id: "Environments-1", Name: "Development", ResourceType: "Environments", ToHcl: render},
+		converters.ResourceDetails{Id: "Environments-2", Name: "Production", ResourceType: "Environments", ToHcl: render},
+		converters.ResourceDetails{Id: "Lifecycles-1", Name: "Default Lifecycle", ResourceType: "Lifecycles", ToHcl: render},
+		converters.ResourceDetails{Id: "Projects-1", Name: "Web App", ResourceType: "Projects", ToHcl: render},
+		// Templates are rendered by the project, so they are not listed
+		converters.ResourceDetails{Id: "Templates-1", ResourceType: "ProjectTemplates"},
+	)
+
+	report := Build(&dependencies, map[string]int{"Projects": 2, "Lifecycles": 1, "Environments": 2})
+
+	if report.ResourceCount() != 4 {
+		t.Errorf("expected 4 resources, got %d", report.ResourceCount())
+	}
+
+	if report.DiscoveryCallCount() != 5 || report.RenderCalls != 4 {
+		t.Errorf("expected 5 discovery calls and 4 render calls, got %d and %d", report.DiscoveryCallCount(), report.RenderCalls)
+	}
+
+	expected := `4 resources would be exported.
+
+Resources by type:
+  Environments (2)
+    "Development" (Environments-1)
+    "Production" (Environments-2)
+  Lifecycles (1)
+    "Default Lifecycle" (Lifecycles-1)
+  Projects (1)
+    "Web App" (Projects-1)
+
+Resources by dependency:
+  Projects: "Web App" (Projects-1) [3 dependencies]
+    Lifecycles: "Default Lifecycle" (Lifecycles-1) [2 dependencies]
+      Environments: "Development" (Environments-1)
+      Environments: "Production" (Environments-2)
+
+API calls made to discover the resources, by type:
+  Environments: 2
+  Lifecycles: 1
+  Projects: 2
+
+Estimated API calls for the export: 9 (5 to discover the resources, and 4 to render them)
+`
+
+	if report.String() != expected {
+		t.Errorf("unexpected report:\n%s", report.String())
+	}
+}
+
+func TestBuildSkipsMissingParents(t *testing.T) {
+	render := func() (string, error) { return "", nil }
+	dependencies := converters.ResourceDetailsCollection{}
+
+	// The deployment process is rendered by the project, so its dependencies are listed under the project
+	projectCtx, _ := dependencies.ClaimResource(context.Background(), "Projects-1", "Projects")
+	processCtx, _ := dependencies.ClaimResource(projectCtx, "DeploymentProcesses-1", "DeploymentProcesses")
+	dependencies.ClaimResource(processCtx, "Feeds-1", "Feeds")
+
+	dependencies.AddResource(
+		converters.ResourceDetails{Id: "Projects-1", Name: "Web App", ResourceType: "Projects", ToHcl: render},
+		converters.ResourceDetails{Id: "DeploymentProcesses-1", ResourceType: "DeploymentProcesses"},
+		converters.ResourceDetails{Id: "Feeds-1", Name: "Docker Hub", ResourceType: "Feeds", ToHcl: render},
+	)
+
+	report := Build(&dependencies, map[string]int{})
+
+	if len(report.Roots) != 1 || len(report.Roots[0].Dependencies) != 1 ||
+		report.Roots[0].Dependencies[0].Resource.Id != "Feeds-1" {
+		t.Errorf("expected the feed to be listed under the project, got:\n%s", report.String())
+	}
+
+	if !strings.Contains(report.String(), "Estimated API calls for the export: 2") {
+		t.Errorf("expected 2 estimated API calls, got:\n%s", report.String())
+	}
+}
@@ -0,0 +1,151 @@
+// Package fakeoctopus serves a read only Octopus API from JSON fixtures, so converters can be tested
+// without an Octopus instance.
+//
+// The fixtures mirror the API paths. A request for /api/Spaces-1/Projects/Projects-1 returns
+// api/Spaces-1/Projects/Projects-1.json. A request for /api/Spaces-1/Projects returns
+// api/Spaces-1/Projects.json if it exists, otherwise a collection holding every JSON file in the
+// api/Spaces-1/Projects directory, filtered by the partialName, name, ids and projectId query
+// parameters. Any other request returns a 404.
+package fakeoctopus
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+)
+
+// Fixtures holds a space called "Default" with the ID Spaces-1, containing a project called "Web App"
+// and the resources it depends on.
+//
+//go:embed fixtures
+var Fixtures embed.FS
+
+// NewServer starts a server that answers requests from the default fixtures.
+func NewServer() *httptest.Server {
+	fixtures, err := fs.Sub(Fixtures, "fixtures")
+
+	if err != nil {
+		panic(err)
+	}
+
+	return httptest.NewServer(NewHandler(fixtures))
+}
+
+// NewHandler returns a handler that answers requests from the supplied fixtures.
+func NewHandler(fixtures fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := readResponse(fixtures, strings.Trim(r.URL.Path, "/"), r)
+
+		if errors.Is(err, fs.ErrNotExist) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+}
+
+func readResponse(fixtures fs.FS, name string, r *http.Request) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, fs.ErrNotExist
+	}
+
+	body, err := fs.ReadFile(fixtures, name+".json")
+
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return body, err
+	}
+
+	entries, err := fs.ReadDir(fixtures, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := []map[string]any{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		item := map[string]any{}
+		body, err := fs.ReadFile(fixtures, path.Join(name, entry.Name()))
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(body, &item)
+
+		if err != nil {
+			return nil, errors.New("failed to parse " + path.Join(name, entry.Name()) + ": " + err.Error())
+		}
+
+		if matchesQuery(item, r) {
+			items = append(items, item)
+		}
+	}
+
+	return json.Marshal(map[string]any{
+		"ItemType":       path.Base(name),
+		"TotalResults":   len(items),
+		"ItemsPerPage":   len(items),
+		"NumberOfPages":  1,
+		"LastPageNumber": 0,
+		"Items":          items,
+	})
+}
+
+// matchesQuery applies the query parameters the Octopus API uses to filter collections.
+func matchesQuery(item map[string]any, r *http.Request) bool {
+	query := r.URL.Query()
+	name, _ := item["Name"].(string)
+
+	if partialName := query.Get("partialName"); partialName != "" &&
+		!strings.Contains(strings.ToLower(name), strings.ToLower(partialName)) {
+		return false
+	}
+
+	if exactName := query.Get("name"); exactName != "" && !strings.EqualFold(name, exactName) {
+		return false
+	}
+
+	if ids := query.Get("ids"); ids != "" {
+		id, _ := item["Id"].(string)
+		found := false
+		for _, i := range strings.Split(ids, ",") {
+			found = found || i == id
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if projectId := query.Get("projectId"); projectId != "" {
+		projectEnvironments, _ := item["ProjectEnvironments"].(map[string]any)
+		_, linked := projectEnvironments[projectId]
+
+		if !linked && item["ProjectId"] != projectId {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,62 @@
+package fakeoctopus
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func getCollection(t *testing.T, url string) []map[string]any {
+	res, err := http.Get(url)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected %s to return 200, got %d", url, res.StatusCode)
+	}
+
+	collection := struct{ Items []map[string]any }{}
+	if err := json.NewDecoder(res.Body).Decode(&collection); err != nil {
+		t.Fatal(err)
+	}
+
+	return collection.Items
+}
+
+func TestCollections(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	if items := getCollection(t, server.URL+"/api/Spaces-1/Environments?take=10000"); len(items) != 2 {
+		t.Fatalf("expected 2 environments, got %d", len(items))
+	}
+
+	if items := getCollection(t, server.URL+"/api/Spaces?partialName=def"); len(items) != 1 || items[0]["Id"] != "Spaces-1" {
+		t.Fatalf("expected to find the Default space, got %v", items)
+	}
+
+	if items := getCollection(t, server.URL+"/api/Spaces-1/Tenants?projectId=Projects-2"); len(items) != 0 {
+		t.Fatalf("expected no tenants linked to Projects-2, got %v", items)
+	}
+}
+
+func TestMissingResource(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	for _, path := range []string{"/api/Spaces-1/Projects/Projects-2", "/api/Spaces-1/Projects/../../../server.go"} {
+		res, err := http.Get(server.URL + path)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected %s to return 404, got %d", path, res.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package golden
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change
+const contextLines = 3
+
+// Diff returns a unified diff of the lines that changed between the expected and actual text.
+func Diff(expected string, actual string) string {
+	a := strings.Split(expected, "\n")
+	b := strings.Split(actual, "\n")
+	edits := diffLines(a, b)
+
+	builder := strings.Builder{}
+	builder.WriteString("--- golden\n+++ generated\n")
+
+	for start := 0; start < len(edits); {
+		// Find the next change
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+
+		if start == len(edits) {
+			break
+		}
+
+		// Extend the hunk until there are more than two runs of context between changes
+		end := start
+		for i := start; i < len(edits); i++ {
+			if edits[i].op != ' ' {
+				end = i
+			} else if i-end > 2*contextLines {
+				break
+			}
+		}
+
+		from := maxInt(0, start-contextLines)
+		to := minInt(len(edits), end+contextLines+1)
+
+		builder.WriteString(fmt.Sprintf("@@ -%d +%d @@\n", edits[from].aLine+1, edits[from].bLine+1))
+		for _, e := range edits[from:to] {
+			builder.WriteByte(e.op)
+			builder.WriteString(e.text)
+			builder.WriteByte('\n')
+		}
+
+		start = to
+	}
+
+	return builder.String()
+}
+
+type edit struct {
+	op    byte
+	text  string
+	aLine int
+	bLine int
+}
+
+// diffLines finds the longest common subsequence of lines, and returns the edits that turn a into b.
+func diffLines(a []string, b []string) []edit {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = maxInt(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	edits := []edit{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{op: ' ', text: a[i], aLine: i, bLine: j})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lengths[i][j+1] >= lengths[i+1][j]):
+			edits = append(edits, edit{op: '+', text: b[j], aLine: i, bLine: j})
+			j++
+		default:
+			edits = append(edits, edit{op: '-', text: a[i], aLine: i, bLine: j})
+			i++
+		}
+	}
+
+	return edits
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
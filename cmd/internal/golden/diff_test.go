@@ -0,0 +1,30 @@
+package golden
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	expected := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	actual := "a\nb\nc\nd\nE\nf\ng\nh\ni\nj\nk\n"
+
+	diff := Diff(expected, actual)
+
+	for _, line := range []string{"--- golden", "+++ generated", "@@ -2 +2 @@", "-e", "+E", " h", "+k"} {
+		if !strings.Contains(diff, line+"\n") {
+			t.Errorf("expected the diff to contain %q, but it was:\n%s", line, diff)
+		}
+	}
+
+	// The unchanged first line is more than three lines from the change
+	if strings.Contains(diff, " a\n") {
+		t.Errorf("expected the diff to only include three lines of context, but it was:\n%s", diff)
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	if diff := Diff("a\nb\n", "a\nb\n"); diff != "--- golden\n+++ generated\n" {
+		t.Errorf("expected an empty diff, got:\n%s", diff)
+	}
+}
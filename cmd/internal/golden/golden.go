@@ -0,0 +1,110 @@
+// Package golden compares generated files with the expected output checked in under a testdata directory.
+//
+// Run the tests with -update to regenerate the expected output:
+//
+//	go test ./cmd/internal/converters/ -run TestGoldenFiles -update
+package golden
+
+import (
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Regenerate the golden files instead of comparing against them")
+
+// AssertFiles compares the files, which map relative paths to content, with the files in the directory.
+// Every generated file must match its golden file, and every golden file must still be generated.
+func AssertFiles(t *testing.T, directory string, files map[string]string) {
+	t.Helper()
+
+	if *update {
+		writeFiles(t, directory, files)
+		return
+	}
+
+	golden := readFiles(t, directory)
+
+	for _, name := range sortedKeys(files) {
+		expected, ok := golden[name]
+
+		if !ok {
+			t.Errorf("%s was generated but has no golden file in %s. Run the test with -update to add it.", name, directory)
+			continue
+		}
+
+		if expected != files[name] {
+			t.Errorf("%s does not match the golden file. Run the test with -update if the change is expected.\n%s",
+				name, Diff(expected, files[name]))
+		}
+	}
+
+	for _, name := range sortedKeys(golden) {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s has a golden file in %s but was not generated. Run the test with -update to remove it.", name, directory)
+		}
+	}
+}
+
+func writeFiles(t *testing.T, directory string, files map[string]string) {
+	if err := os.RemoveAll(directory); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(directory, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFiles(t *testing.T, directory string) map[string]string {
+	files := map[string]string{}
+
+	err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(directory, path)
+
+		if err != nil {
+			return err
+		}
+
+		// Tolerate files checked out with Windows line endings
+		files[filepath.ToSlash(name)] = strings.ReplaceAll(string(content), "\r\n", "\n")
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("failed to read the golden files in %s: %v. Run the test with -update to create them.", directory, err)
+	}
+
+	return files
+}
+
+func sortedKeys(files map[string]string) []string {
+	keys := make([]string, 0, len(files))
+	for k := range files {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,220 @@
+// Package graph renders the dependencies recorded while discovering the resources to export, so the way a project
+// fans out into shared space resources can be visualised.
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/converters"
+)
+
+const (
+	// FormatDot renders the graph in the Graphviz DOT language
+	FormatDot = "dot"
+	// FormatMermaid renders the graph as a Mermaid flowchart
+	FormatMermaid = "mermaid"
+)
+
+// FileNames maps each format to the name of the file the graph is written to alongside the export.
+var FileNames = map[string]string{
+	FormatDot:     "dependencies.dot",
+	FormatMermaid: "dependencies.mmd",
+}
+
+// Node is a resource in the graph.
+type Node struct {
+	ResourceType string
+	Id           string
+	Name         string
+}
+
+// Label describes the resource by its type, and its name if it has one.
+func (n Node) Label() string {
+	if n.Name == "" {
+		return n.ResourceType + " " + n.Id
+	}
+
+	return n.ResourceType + " " + n.Name
+}
+
+// Edge records that discovering the From resource found the To resource as one of its dependencies.
+type Edge struct {
+	From Node
+	To   Node
+}
+
+// Graph is the resources and the dependencies between them, ordered by type, ID and name.
+type Graph struct {
+	Nodes []Node
+	Edges []Edge
+}
+
+type key struct {
+	resourceType string
+	id           string
+}
+
+// Build creates the graph from the discovered resources and their dependencies. Resources like project templates,
+// which are rendered by the resource that owns them, are not included. Resources with dependencies, but no resource
+// of their own, like variable sets, are replaced by edges from the resource that owns them to their dependencies.
+func Build(dependencies *converters.ResourceDetailsCollection) Graph {
+	nodes := map[key]Node{}
+	ownedByParent := map[key]bool{}
+
+	for _, r := range dependencies.SortedResources() {
+		k := key{resourceType: r.ResourceType, id: r.Id}
+
+		if r.Id == "" {
+			continue
+		}
+
+		if r.ToHcl == nil {
+			ownedByParent[k] = true
+			continue
+		}
+
+		if _, found := nodes[k]; !found {
+			nodes[k] = Node{ResourceType: r.ResourceType, Id: r.Id, Name: r.Name}
+		}
+	}
+
+	edges := map[key][]key{}
+	for _, d := range dependencies.Dependencies() {
+		from := key{resourceType: d.ResourceType, id: d.Id}
+		to := key{resourceType: d.DependencyType, id: d.DependencyId}
+
+		if !ownedByParent[from] && !ownedByParent[to] {
+			edges[from] = append(edges[from], to)
+		}
+	}
+
+	graph := Graph{}
+	for from, node := range nodes {
+		for _, to := range reachableNodes(from, edges, nodes) {
+			graph.Edges = append(graph.Edges, Edge{From: node, To: nodes[to]})
+		}
+	}
+
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return lessNode(graph.Nodes[i], graph.Nodes[j])
+	})
+
+	sort.SliceStable(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return lessNode(graph.Edges[i].From, graph.Edges[j].From)
+		}
+
+		return lessNode(graph.Edges[i].To, graph.Edges[j].To)
+	})
+
+	return graph
+}
+
+// reachableNodes returns the nodes that are dependencies of the resource, following the dependencies of the
+// resources that are not nodes.
+func reachableNodes(from key, edges map[key][]key, nodes map[key]Node) []key {
+	reachable := []key{}
+	visited := map[key]bool{from: true}
+	pending := append([]key{}, edges[from]...)
+
+	for len(pending) != 0 {
+		next := pending[0]
+		pending = pending[1:]
+
+		if visited[next] {
+			continue
+		}
+
+		visited[next] = true
+
+		if _, found := nodes[next]; found {
+			reachable = append(reachable, next)
+		} else {
+			pending = append(pending, edges[next]...)
+		}
+	}
+
+	return reachable
+}
+
+func lessNode(a Node, b Node) bool {
+	if a.ResourceType != b.ResourceType {
+		return a.ResourceType < b.ResourceType
+	}
+
+	if a.Id != b.Id {
+		return a.Id < b.Id
+	}
+
+	return a.Name < b.Name
+}
+
+// Render returns the graph in the format, which is one of FormatDot or FormatMermaid.
+func (g Graph) Render(format string) (string, error) {
+	switch format {
+	case FormatDot:
+		return g.Dot(), nil
+	case FormatMermaid:
+		return g.Mermaid(), nil
+	}
+
+	return "", fmt.Errorf("the graph format must be one of %s or %s, but was %s", FormatDot, FormatMermaid, format)
+}
+
+// Dot renders the graph in the Graphviz DOT language, e.g. to be converted to an image with
+// "dot -Tsvg dependencies.dot -o dependencies.svg".
+func (g Graph) Dot() string {
+	builder := strings.Builder{}
+	builder.WriteString("digraph dependencies {\n  rankdir=LR;\n  node [shape=box];\n")
+
+	for _, node := range g.Nodes {
+		builder.WriteString(fmt.Sprintf("  %s [label=%s];\n", dotId(node), dotQuote(node.Label())))
+	}
+
+	for _, edge := range g.Edges {
+		builder.WriteString(fmt.Sprintf("  %s -> %s;\n", dotId(edge.From), dotId(edge.To)))
+	}
+
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart, which GitHub and many wikis display in markdown.
+func (g Graph) Mermaid() string {
+	// Mermaid node IDs can not contain most punctuation, so nodes are numbered in order
+	ids := map[Node]string{}
+	for i, node := range g.Nodes {
+		ids[node] = "n" + fmt.Sprint(i)
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString("flowchart LR\n")
+
+	for _, node := range g.Nodes {
+		builder.WriteString(fmt.Sprintf("  %s[\"%s\"]\n", ids[node], mermaidEscape(node.Label())))
+	}
+
+	for _, edge := range g.Edges {
+		builder.WriteString(fmt.Sprintf("  %s --> %s\n", ids[edge.From], ids[edge.To]))
+	}
+
+	return builder.String()
+}
+
+func dotId(node Node) string {
+	return dotQuote(node.ResourceType + "/" + node.Id)
+}
+
+func dotQuote(value string) string {
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(value) + "\""
+}
+
+func mermaidEscape(value string) string {
+	return strings.NewReplacer("\"", "#quot;", "\n", " ").Replace(value)
+}
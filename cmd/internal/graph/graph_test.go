@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/converters"
+)
+
+// createDependencies records a project that depends on a lifecycle and a variable set. The project and the
+// lifecycle both depend on the same environment. The tenant variables of a tenant are claimed, like the variable
+// set, without a resource of their own.
+func createDependencies() *converters.ResourceDetailsCollection {
+	render := func() (string, error) { return "", nil }
+	dependencies := converters.ResourceDetailsCollection{}
+
+	projectCtx, _ := dependencies.ClaimResource(context.Background(), "Projects-1", "Projects")
+	lifecycleCtx, _ := dependencies.ClaimResource(projectCtx, "Lifecycles-1", "Lifecycles")
+	dependencies.ClaimResource(lifecycleCtx, "Environments-1", "Environments")
+	variableSetCtx, _ := dependencies.ClaimResource(projectCtx, "variableset-Projects-1", "Variables")
+	dependencies.AddChildResource(variableSetCtx,
+		converters.ResourceDetails{Id: "variable-1", Name: "Url", ResourceType: "Variables", ToHcl: render})
+	dependencies.AddDependency(projectCtx, "Environments", "Environments-1")
+	dependencies.AddChildResource(projectCtx, converters.ResourceDetails{Id: "Templates-1", ResourceType: "ProjectTemplates"})
+	tenantVariablesCtx, _ := dependencies.ClaimResource(context.Background(), "TenantVariables/All/Tenants-1", "TenantVariables/All")
+	dependencies.AddChildResource(tenantVariablesCtx,
+		converters.ResourceDetails{Id: "Tenants-1/Projects-1/template-1", Name: "Database", ResourceType: "TenantVariables/All", ToHcl: render})
+
+	dependencies.AddResource(
+		converters.ResourceDetails{FileName: "space_population/provider.tf", ToHcl: render},
+		converters.ResourceDetails{Id: "Environments-1", Name: "Development", ResourceType: "Environments", ToHcl: render},
+		converters.ResourceDetails{Id: "Lifecycles-1", Name: "Default Lifecycle", ResourceType: "Lifecycles", ToHcl: render},
+		converters.ResourceDetails{Id: "Projects-1", Name: "Web \"App\"", ResourceType: "Projects", ToHcl: render},
+	)
+
+	return &dependencies
+}
+
+func TestDot(t *testing.T) {
+	expected := `digraph dependencies {
+  rankdir=LR;
+  node [shape=box];
+  "Environments/Environments-1" [label="Environments Development"];
+  "Lifecycles/Lifecycles-1" [label="Lifecycles Default Lifecycle"];
+  "Projects/Projects-1" [label="Projects Web \"App\""];
+  "TenantVariables/All/Tenants-1/Projects-1/template-1" [label="TenantVariables/All Database"];
+  "Variables/variable-1" [label="Variables Url"];
+  "Lifecycles/Lifecycles-1" -> "Environments/Environments-1";
+  "Projects/Projects-1" -> "Environments/Environments-1";
+  "Projects/Projects-1" -> "Lifecycles/Lifecycles-1";
+  "Projects/Projects-1" -> "Variables/variable-1";
+}
+`
+
+	if dot := Build(createDependencies()).Dot(); dot != expected {
+		t.Errorf("unexpected DOT graph:\n%s", dot)
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	expected := `flowchart LR
+  n0["Environments Development"]
+  n1["Lifecycles Default Lifecycle"]
+  n2["Projects Web #quot;App#quot;"]
+  n3["TenantVariables/All Database"]
+  n4["Variables Url"]
+  n1 --> n0
+  n2 --> n0
+  n2 --> n1
+  n2 --> n4
+`
+
+	mermaid, err := Build(createDependencies()).Render(FormatMermaid)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mermaid != expected {
+		t.Errorf("unexpected Mermaid graph:\n%s", mermaid)
+	}
+
+	if _, err := Build(createDependencies()).Render("svg"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+// TestNodesAreRenderedResources checks the graph only holds the resources that are rendered, so resources like
+// variable sets and the tenant variables of a tenant are not shown.
+func TestNodesAreRenderedResources(t *testing.T) {
+	dependencies := createDependencies()
+
+	rendered := map[string]bool{}
+	for _, r := range dependencies.SortedResources() {
+		if r.ToHcl != nil {
+			rendered[r.ResourceType+"/"+r.Id] = true
+		}
+	}
+
+	graph := Build(dependencies)
+	nodes := map[Node]bool{}
+
+	for _, node := range graph.Nodes {
+		nodes[node] = true
+
+		if !rendered[node.ResourceType+"/"+node.Id] {
+			t.Errorf("expected %s %s to be a rendered resource", node.ResourceType, node.Id)
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		if !nodes[edge.From] || !nodes[edge.To] {
+			t.Errorf("expected the edge from %s to %s to join two nodes", edge.From.Label(), edge.To.Label())
+		}
+	}
+}
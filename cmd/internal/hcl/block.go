@@ -0,0 +1,235 @@
+package hcl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/hcl2/gohcl"
+	"github.com/hashicorp/hcl2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/gocty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"reflect"
+	"strings"
+)
+
+// Block is a block encoded from a terraform model struct. The embedded hclwrite.Block holds the block in the HCL
+// native syntax, and the block is also encoded as the body of an object in the Terraform JSON syntax, so the
+// same block can be written in either syntax.
+type Block struct {
+	*hclwrite.Block
+	blockType string
+	labels    []string
+	body      map[string]any
+}
+
+// EncodeAsBlock encodes the value, which must be a struct or a pointer to a struct with hcl tags, as a block.
+// This is a replacement for gohcl.EncodeAsBlock, and follows the same rules to build the JSON object: nil pointers
+// are skipped, attributes are encoded from their implied cty type, and block fields are encoded as one block for
+// each item in a slice.
+func EncodeAsBlock(val any, blockType string) *Block {
+	labels, body := encodeJsonBlock(reflect.ValueOf(val))
+	return &Block{
+		Block:     gohcl.EncodeAsBlock(val, blockType),
+		blockType: blockType,
+		labels:    labels,
+		body:      body,
+	}
+}
+
+// encodeJsonBlock returns the labels and the body of the struct in the value.
+func encodeJsonBlock(rv reflect.Value) ([]string, map[string]any) {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("value is %s, not struct", rv.Kind()))
+	}
+
+	labels := []string{}
+	body := map[string]any{}
+	ty := rv.Type()
+
+	for i := 0; i < ty.NumField(); i++ {
+		name, kind, ok := fieldTag(ty.Field(i))
+
+		if !ok {
+			continue
+		}
+
+		fieldVal := rv.Field(i)
+
+		switch kind {
+		case "label":
+			labels = append(labels, fmt.Sprintf("%s", fieldVal.Interface()))
+		case "attr", "optional":
+			if fieldVal.Kind() == reflect.Ptr {
+				if fieldVal.IsNil() {
+					continue
+				}
+				fieldVal = fieldVal.Elem()
+			}
+
+			body[name] = encodeJsonAttribute(fieldVal.Interface())
+		case "block":
+			if fieldVal.Kind() == reflect.Ptr {
+				if fieldVal.IsNil() {
+					continue
+				}
+				fieldVal = fieldVal.Elem()
+			}
+
+			blocks := []any{}
+			if fieldVal.Kind() == reflect.Slice || fieldVal.Kind() == reflect.Array {
+				for j := 0; j < fieldVal.Len(); j++ {
+					elemVal := fieldVal.Index(j)
+					if elemVal.Kind() == reflect.Ptr && elemVal.IsNil() {
+						continue
+					}
+					blocks = append(blocks, nestedJsonBlock(encodeJsonBlock(elemVal)))
+				}
+			} else {
+				blocks = append(blocks, nestedJsonBlock(encodeJsonBlock(fieldVal)))
+			}
+
+			// Nested blocks are written as an array of objects, which Terraform accepts for single and repeated blocks
+			if len(blocks) != 0 {
+				body[name] = blocks
+			}
+		}
+	}
+
+	return labels, body
+}
+
+// fieldTag returns the name and kind of a field from its hcl tag, like gohcl does.
+func fieldTag(field reflect.StructField) (string, string, bool) {
+	tag := field.Tag.Get("hcl")
+	if tag == "" {
+		return "", "", false
+	}
+
+	name, kind, found := strings.Cut(tag, ",")
+	if !found {
+		kind = "attr"
+	}
+
+	return name, kind, true
+}
+
+// nestedJsonBlock wraps the body of a labelled block in an object for each label.
+func nestedJsonBlock(labels []string, body map[string]any) any {
+	var value any = body
+	for i := len(labels) - 1; i >= 0; i-- {
+		value = map[string]any{labels[i]: value}
+	}
+	return value
+}
+
+// encodeJsonAttribute converts the attribute value to a JSON value through its implied cty type, escaping the
+// template sequences in strings the same way hclwrite does.
+func encodeJsonAttribute(value any) any {
+	valTy, err := gocty.ImpliedType(value)
+	if err != nil {
+		panic(fmt.Sprintf("cannot encode %T as JSON: %s", value, err))
+	}
+
+	val, err := gocty.ToCtyValue(value, valTy)
+	if err != nil {
+		panic(fmt.Sprintf("failed to encode %T as %#v: %s", value, valTy, err))
+	}
+
+	val, err = cty.Transform(val, func(path cty.Path, v cty.Value) (cty.Value, error) {
+		if v.Type() == cty.String && v.IsKnown() && !v.IsNull() {
+			return cty.StringVal(escapeTemplate(v.AsString())), nil
+		}
+		return v, nil
+	})
+	if err != nil {
+		panic(fmt.Sprintf("failed to escape %T: %s", value, err))
+	}
+
+	jsonBytes, err := ctyjson.Marshal(val, valTy)
+	if err != nil {
+		panic(fmt.Sprintf("failed to encode %T as JSON: %s", value, err))
+	}
+
+	// Numbers are decoded as json.Number so large values are not rounded
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+	var jsonValue any
+	if err := decoder.Decode(&jsonValue); err != nil {
+		panic(fmt.Sprintf("failed to decode the JSON encoding of %T: %s", value, err))
+	}
+
+	return jsonValue
+}
+
+// escapeTemplate doubles the template introducers, so ${ is written as $${ and %{ as %%{, which matches the strings
+// written by hclwrite. strutil.UnEscapeDollar then restores the strings that are a single interpolation.
+func escapeTemplate(value string) string {
+	return strings.NewReplacer("${", "$${", "%{", "%%{").Replace(value)
+}
+
+// setJsonAttribute sets the attribute in the JSON body from a raw HCL expression. A tuple of references, like the
+// value of depends_on, is written as an array of strings. Expressions like [for r in list : r.id][0] are not a
+// tuple of references. Type constraints, the address an import block imports to,
+// and interpolations are written as strings, and any other expression, like a reference, is written as an
+// interpolation.
+func (b *Block) setJsonAttribute(name string, expression string) {
+	trimmed := strings.TrimSpace(expression)
+
+	if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") && !strings.ContainsAny(trimmed[1:len(trimmed)-1], "[]") {
+		items := []any{}
+		for _, item := range strings.Split(trimmed[1:len(trimmed)-1], ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		b.body[name] = items
+		return
+	}
+
+	if name == "type" || name == "to" || IsInterpolation(trimmed) {
+		b.body[name] = expression
+		return
+	}
+
+	b.body[name] = "${" + trimmed + "}"
+}
+
+// setJsonActionProperties sets the properties of the matching action in the JSON body. The property values have
+// already been escaped, so they are written as is.
+func (b *Block) setJsonActionProperties(stepName string, actionName string, properties map[string]string) {
+	for _, step := range jsonBlocks(b.body, "step") {
+		if step["name"] != escapeTemplate(stepName) {
+			continue
+		}
+
+		for _, action := range jsonBlocks(step, "action") {
+			if action["name"] == escapeTemplate(actionName) {
+				jsonProperties := map[string]any{}
+				for key, value := range properties {
+					jsonProperties[key] = value
+				}
+				action["properties"] = jsonProperties
+				return
+			}
+		}
+	}
+}
+
+// jsonBlocks returns the bodies of the unlabelled nested blocks with the name.
+func jsonBlocks(body map[string]any, name string) []map[string]any {
+	blocks := []map[string]any{}
+
+	items, _ := body[name].([]any)
+	for _, item := range items {
+		if block, ok := item.(map[string]any); ok {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks
+}
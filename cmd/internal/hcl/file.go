@@ -0,0 +1,200 @@
+package hcl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
+	"github.com/hashicorp/hcl2/hclwrite"
+	"strings"
+)
+
+const (
+	// FormatHcl writes the configuration in the HCL native syntax
+	FormatHcl = "hcl"
+	// FormatTfJson writes the configuration in the Terraform JSON syntax
+	FormatTfJson = "tfjson"
+)
+
+// FileExtension returns the extension of the files holding configuration in the format.
+func FileExtension(format string) string {
+	if format == FormatTfJson {
+		return ".tf.json"
+	}
+
+	return ".tf"
+}
+
+// File is a Terraform configuration file that can be rendered in the HCL native syntax or the Terraform JSON syntax.
+type File struct {
+	hcl      *hclwrite.File
+	blocks   []*Block
+	comments []string
+}
+
+// NewFile creates an empty file.
+func NewFile() *File {
+	return &File{hcl: hclwrite.NewEmptyFile()}
+}
+
+// AppendBlock adds the block to the end of the file.
+func (f *File) AppendBlock(block *Block) {
+	f.hcl.Body().AppendBlock(block.Block)
+	f.blocks = append(f.blocks, block)
+}
+
+// AppendComment adds the comment, which is one or more lines starting with #, to the end of the file. The Terraform
+// JSON syntax has no comments, so the comments are collected in a "//" property, which Terraform ignores.
+func (f *File) AppendComment(comment string) {
+	f.hcl.Body().AppendUnstructuredTokens([]*hclwrite.Token{{
+		Type:         hclsyntax.TokenComment,
+		Bytes:        []byte(comment),
+		SpacesBefore: 0,
+	}})
+
+	for _, line := range strings.Split(strings.TrimRight(comment, "\n"), "\n") {
+		f.comments = append(f.comments, strings.TrimPrefix(strings.TrimPrefix(line, "#"), " "))
+	}
+}
+
+// Render returns the file in the format, which is FormatHcl or FormatTfJson. An empty format is treated as FormatHcl.
+func (f *File) Render(format string) (string, error) {
+	switch format {
+	case "", FormatHcl:
+		return string(f.hcl.Bytes()), nil
+	case FormatTfJson:
+		return f.json()
+	}
+
+	return "", errors.New("the format must be one of " + FormatHcl + " or " + FormatTfJson + ", but was " + format)
+}
+
+// json renders the blocks as a Terraform JSON object, with the labels of each block as nested objects, like
+// {"resource": {"type": {"name": {...}}}}.
+func (f *File) json() (string, error) {
+	if len(f.blocks) == 0 && len(f.comments) == 0 {
+		return "", nil
+	}
+
+	root := map[string]any{}
+
+	if len(f.comments) != 0 {
+		root["//"] = strings.Join(f.comments, "\n")
+	}
+
+	for _, block := range f.blocks {
+		if len(block.labels) == 0 {
+			// Blocks without labels, like the terraform block, can be repeated as an array
+			blocks, _ := root[block.blockType].([]any)
+			root[block.blockType] = append(blocks, block.body)
+			continue
+		}
+
+		parent := root
+		key := block.blockType
+		for _, label := range block.labels {
+			child, ok := parent[key].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				parent[key] = child
+			}
+			parent = child
+			key = label
+		}
+
+		// Repeated blocks with the same labels, like aliased providers, are written as an array
+		switch existing := parent[key].(type) {
+		case nil:
+			parent[key] = block.body
+		case []any:
+			parent[key] = append(existing, block.body)
+		default:
+			parent[key] = []any{existing, block.body}
+		}
+	}
+
+	return encodeJson(root)
+}
+
+func encodeJson(value any) (string, error) {
+	buffer := bytes.Buffer{}
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
+
+// Concat combines the rendered files in the format into one file. Files in the HCL native syntax are appended, and
+// files in the Terraform JSON syntax are merged into one object.
+func Concat(format string, files ...string) (string, error) {
+	if format != FormatTfJson {
+		nonEmpty := []string{}
+		for _, file := range files {
+			if strings.TrimSpace(file) != "" {
+				nonEmpty = append(nonEmpty, strings.TrimRight(file, "\n"))
+			}
+		}
+		if len(nonEmpty) == 0 {
+			return "", nil
+		}
+		return strings.Join(nonEmpty, "\n\n") + "\n", nil
+	}
+
+	root := map[string]any{}
+	for _, file := range files {
+		if strings.TrimSpace(file) == "" {
+			continue
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(file))
+		decoder.UseNumber()
+		value := map[string]any{}
+		if err := decoder.Decode(&value); err != nil {
+			return "", err
+		}
+
+		mergeJson(root, value)
+	}
+
+	if len(root) == 0 {
+		return "", nil
+	}
+
+	return encodeJson(root)
+}
+
+// mergeJson merges the source object into the destination. Objects are merged by key, arrays like repeated
+// blocks are appended, and the "//" comments are joined as lines. Other values are replaced.
+func mergeJson(destination map[string]any, source map[string]any) {
+	for key, value := range source {
+		existing, found := destination[key]
+		if !found {
+			destination[key] = value
+			continue
+		}
+
+		switch existingValue := existing.(type) {
+		case map[string]any:
+			if sourceValue, ok := value.(map[string]any); ok {
+				mergeJson(existingValue, sourceValue)
+				continue
+			}
+		case []any:
+			if sourceValue, ok := value.([]any); ok {
+				destination[key] = append(existingValue, sourceValue...)
+				continue
+			}
+		case string:
+			if sourceValue, ok := value.(string); ok && key == "//" {
+				destination[key] = existingValue + "\n" + sourceValue
+				continue
+			}
+		}
+
+		destination[key] = value
+	}
+}
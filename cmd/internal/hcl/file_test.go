@@ -0,0 +1,226 @@
+package hcl
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testResource struct {
+	Type        string       `hcl:"type,label"`
+	Name        string       `hcl:"name,label"`
+	Description *string      `hcl:"description"`
+	Script      string       `hcl:"script"`
+	Tags        []string     `hcl:"tags"`
+	Step        []testStep   `hcl:"step,block"`
+	Lifecycle   *testNothing `hcl:"lifecycle,block"`
+}
+
+type testStep struct {
+	Name   string       `hcl:"name"`
+	Action []testAction `hcl:"action,block"`
+}
+
+type testAction struct {
+	Name string `hcl:"name"`
+}
+
+type testNothing struct {
+	IgnoreChanges []string `hcl:"ignore_changes"`
+}
+
+type testVariable struct {
+	Name    string  `hcl:"name,label"`
+	Default *string `hcl:"default"`
+}
+
+func newTestFile() *File {
+	resource := testResource{
+		Type:   "octopusdeploy_deployment_process",
+		Name:   "process",
+		Script: "echo ${HOME} %{if}",
+		Tags:   []string{"a"},
+		Step: []testStep{{
+			Name:   "Step",
+			Action: []testAction{{Name: "First"}, {Name: "Second"}},
+		}},
+	}
+
+	file := NewFile()
+	file.AppendComment("# Import the process with:\n# terraform import octopusdeploy_deployment_process.process ID\n")
+
+	block := EncodeAsBlock(resource, "resource")
+	WriteUnquotedAttribute(block, "depends_on", "[octopusdeploy_project.a,octopusdeploy_project.b]")
+	WriteActionProperties(block, "Step", "Second", map[string]string{"Octopus.Action.Script.ScriptBody": "echo hi"})
+	file.AppendBlock(block)
+
+	variable := EncodeAsBlock(testVariable{Name: "value"}, "variable")
+	WriteUnquotedAttribute(variable, "type", "string")
+	file.AppendBlock(variable)
+
+	return file
+}
+
+func TestRenderTerraformJson(t *testing.T) {
+	output, err := newTestFile().Render(FormatTfJson)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := map[string]any{}
+	if err := json.Unmarshal([]byte(output), &actual); err != nil {
+		t.Fatalf("expected valid JSON, got %s: %v", output, err)
+	}
+
+	expected := map[string]any{
+		"//": "Import the process with:\nterraform import octopusdeploy_deployment_process.process ID",
+		"resource": map[string]any{
+			"octopusdeploy_deployment_process": map[string]any{
+				"process": map[string]any{
+					"script":     "echo $${HOME} %%{if}",
+					"tags":       []any{"a"},
+					"depends_on": []any{"octopusdeploy_project.a", "octopusdeploy_project.b"},
+					"step": []any{map[string]any{
+						"name": "Step",
+						"action": []any{
+							map[string]any{"name": "First"},
+							map[string]any{
+								"name":       "Second",
+								"properties": map[string]any{"Octopus.Action.Script.ScriptBody": "echo hi"},
+							},
+						},
+					}},
+				},
+			},
+		},
+		"variable": map[string]any{
+			"value": map[string]any{"type": "string"},
+		},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestRenderHcl(t *testing.T) {
+	output, err := newTestFile().Render(FormatHcl)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"# terraform import octopusdeploy_deployment_process.process ID",
+		"resource \"octopusdeploy_deployment_process\" \"process\" {",
+		"depends_on = [octopusdeploy_project.a,octopusdeploy_project.b]",
+		"\"Octopus.Action.Script.ScriptBody\" = \"echo hi\"",
+		"type = string",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected the HCL to contain %s, got %s", expected, output)
+		}
+	}
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	if _, err := NewFile().Render("yaml"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestConcat(t *testing.T) {
+	output, err := Concat(FormatHcl, "resource \"a\" \"b\" {\n}\n", "", "variable \"c\" {\n}\n")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output != "resource \"a\" \"b\" {\n}\n\nvariable \"c\" {\n}\n" {
+		t.Fatalf("expected the files to be appended, got %s", output)
+	}
+}
+
+func TestConcatTerraformJson(t *testing.T) {
+	output, err := Concat(FormatTfJson,
+		`{"//": "first", "resource": {"octopusdeploy_project": {"a": {"name": "A"}}}, "terraform": [{"x": 1}]}`,
+		`{"//": "second", "resource": {"octopusdeploy_project": {"b": {"name": "B"}}}, "terraform": [{"y": 2}]}`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := map[string]any{}
+	if err := json.Unmarshal([]byte(output), &actual); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"//": "first\nsecond",
+		"resource": map[string]any{"octopusdeploy_project": map[string]any{
+			"a": map[string]any{"name": "A"},
+			"b": map[string]any{"name": "B"},
+		}},
+		"terraform": []any{map[string]any{"x": float64(1)}, map[string]any{"y": float64(2)}},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestRenderTerraformJsonReference(t *testing.T) {
+	block := EncodeAsBlock(testVariable{Name: "value"}, "module")
+	WriteUnquotedAttribute(block, "environment_id", "data.octopusdeploy_environments.dev.environments[0].id")
+	file := NewFile()
+	file.AppendBlock(block)
+
+	output, err := file.Render(FormatTfJson)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(output, `"environment_id": "${data.octopusdeploy_environments.dev.environments[0].id}"`) {
+		t.Fatalf("expected the reference to be written as an interpolation, got %s", output)
+	}
+}
+
+func TestRenderFileReference(t *testing.T) {
+	resource := testResource{
+		Type: "octopusdeploy_deployment_process",
+		Name: "process",
+		Step: []testStep{{Name: "Step", Action: []testAction{{Name: "Action"}}}},
+	}
+
+	for format, expected := range map[string][]string{
+		FormatHcl: {
+			`"Octopus.Action.Script.ScriptBody" = file("${path.module}/scripts/deploy.ps1")`,
+			`name = file("${path.module}/scripts/step.sh")`,
+		},
+		FormatTfJson: {
+			`"Octopus.Action.Script.ScriptBody": "${file(\"${path.module}/scripts/deploy.ps1\")}"`,
+			`"name": "${file(\"${path.module}/scripts/step.sh\")}"`,
+		},
+	} {
+		block := EncodeAsBlock(resource, "resource")
+		WriteActionProperties(block, "Step", "Action", map[string]string{"Octopus.Action.Script.ScriptBody": FileReference("scripts/deploy.ps1")})
+		WriteFileAttribute(block, "step", "name", "scripts/step.sh")
+		file := NewFile()
+		file.AppendBlock(block)
+
+		output, err := file.Render(format)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, e := range expected {
+			if !strings.Contains(output, e) {
+				t.Errorf("expected the %s output to contain %s, got %s", format, e, output)
+			}
+		}
+	}
+}
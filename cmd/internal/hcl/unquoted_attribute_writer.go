@@ -6,21 +6,82 @@ import (
 	"github.com/hashicorp/hcl2/hcl"
 	"github.com/hashicorp/hcl2/hcl/hclsyntax"
 	"github.com/hashicorp/hcl2/hclwrite"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sliceutil"
 	"regexp"
 	"strings"
 )
 
+// fileReference matches a reference returned by FileReference, capturing the call to the file function
+var fileReference = regexp.MustCompile(`^\$\{(file\("\$\{path\.module\}/[^"]*"\))\}$`)
+
 // WriteUnquotedAttribute uses the example from https://github.com/hashicorp/hcl/issues/442
-// to add an unquoted attribute to a block
-func WriteUnquotedAttribute(block *hclwrite.Block, attrName string, attrValue string) {
+// to add an unquoted attribute to a block. The attribute is also added to the Terraform JSON syntax of the block,
+// where a tuple of references like depends_on is an array of strings, and other expressions are strings.
+func WriteUnquotedAttribute(block *Block, attrName string, attrValue string) {
 	block.Body().SetAttributeTraversal(attrName, hcl.Traversal{
 		hcl.TraverseRoot{Name: attrValue},
 	})
+	block.setJsonAttribute(attrName, attrValue)
+}
+
+// WriteMapAttribute writes a map of strings as a multiline object, with each value written as is, so the values
+// can be interpolations like "${octopusdeploy_project.project.id}". The keys are escaped, so they are literal
+// strings. The Terraform JSON syntax of the block holds the map as an object.
+func WriteMapAttribute(block *Block, attrName string, values map[string]string) {
+	output := "{"
+	for _, key := range sliceutil.SortedKeys(values) {
+		output += "\n    \"" + encodeString(escapeTemplate(key)) + "\" = \"" + encodeString(values[key]) + "\""
+	}
+	output += "\n  }"
+
+	block.Body().SetAttributeTraversal(attrName, hcl.Traversal{
+		hcl.TraverseRoot{Name: output},
+	})
+
+	jsonValues := map[string]any{}
+	for key, value := range values {
+		jsonValues[key] = value
+	}
+	block.body[attrName] = jsonValues
+}
+
+// FileReference returns the interpolation that reads the file, which is relative to the directory of the module,
+// like ${file("${path.module}/scripts/deploy.ps1")}. Action properties holding a file reference are written as a
+// call to the file function.
+func FileReference(fileName string) string {
+	return "${" + fileCall(fileName) + "}"
+}
+
+// fileCall returns the call to the file function that reads the file relative to the directory of the module.
+func fileCall(fileName string) string {
+	return "file(\"${path.module}/" + fileName + "\")"
+}
+
+// WriteFileAttribute writes the attribute of the first nested block with the name as a call to the file function,
+// which reads the file relative to the directory of the module. The contents of the file are not a template, so
+// they are not escaped.
+func WriteFileAttribute(block *Block, nestedBlockName string, attrName string, fileName string) {
+	for _, nestedBlock := range block.Body().Blocks() {
+		if getBlockType(nestedBlock.BuildTokens(hclwrite.Tokens{})) == nestedBlockName {
+			nestedBlock.Body().SetAttributeTraversal(attrName, hcl.Traversal{
+				hcl.TraverseRoot{Name: fileCall(fileName)},
+			})
+			break
+		}
+	}
+
+	for _, jsonBlock := range jsonBlocks(block.body, nestedBlockName) {
+		jsonBlock[attrName] = FileReference(fileName)
+		break
+	}
 }
 
 // WriteActionProperties is used to pretty print the properties of an action, writing a multiline map for the properties,
-// and extracting JSON blobs as maps for easy reading.
-func WriteActionProperties(block *hclwrite.Block, stepName string, actionName string, properties map[string]string) {
+// and extracting JSON blobs as maps for easy reading. The Terraform JSON syntax of the block holds the properties
+// as strings.
+func WriteActionProperties(block *Block, stepName string, actionName string, properties map[string]string) {
+	block.setJsonActionProperties(stepName, actionName, properties)
+
 	for _, stepBlock := range block.Body().Blocks() {
 		stepNameTokens := hclwrite.Tokens{}
 		blockStepName := getAttributeValue(stepBlock.Body().GetAttribute("name").BuildTokens(stepNameTokens))
@@ -32,14 +93,25 @@ func WriteActionProperties(block *hclwrite.Block, stepName string, actionName st
 					actionBlock.Body().SetAttributeTraversal("properties", hcl.Traversal{
 						hcl.TraverseRoot{Name: extractJsonAsMap(properties)},
 					})
+					break
 				}
-				break
 			}
 			break
 		}
 	}
 }
 
+// getBlockType returns the type of the block, which is the first identifier in its tokens.
+func getBlockType(tokens hclwrite.Tokens) string {
+	for _, token := range tokens {
+		if token.Type == hclsyntax.TokenIdent {
+			return string(token.Bytes)
+		}
+	}
+
+	return ""
+}
+
 func getAttributeValue(tokens hclwrite.Tokens) string {
 	for _, token := range tokens {
 		if token.Type == hclsyntax.TokenQuotedLit {
@@ -53,8 +125,8 @@ func getAttributeValue(tokens hclwrite.Tokens) string {
 func extractJsonAsMap(properties map[string]string) string {
 	output := "{"
 
-	for key, value := range properties {
-		output += "\n        \"" + key + "\" = " + jsonStringToHcl(value)
+	for _, key := range sliceutil.SortedKeys(properties) {
+		output += "\n        \"" + key + "\" = " + jsonStringToHcl(properties[key])
 	}
 
 	output += "\n      }"
@@ -63,6 +135,10 @@ func extractJsonAsMap(properties map[string]string) string {
 }
 
 func jsonStringToHcl(value string) string {
+	if match := fileReference.FindStringSubmatch(value); match != nil {
+		return match[1]
+	}
+
 	jsonMap := map[string]any{}
 	jsonMapError := json.Unmarshal([]byte(value), &jsonMap)
 
@@ -90,8 +166,8 @@ func anyToHcl(value any) string {
 
 func mapToHclMap(jsonMap map[string]any) string {
 	output := "{"
-	for k, v := range jsonMap {
-		output += "\n        \"" + k + "\" = " + anyToHcl(v)
+	for _, k := range sliceutil.SortedKeys(jsonMap) {
+		output += "\n        \"" + k + "\" = " + anyToHcl(jsonMap[k])
 	}
 	if len(jsonMap) != 0 {
 		output += "\n        "
@@ -112,6 +188,12 @@ func arrayToHclMap(jsonArray []any) string {
 	return output
 }
 
+// QuoteString returns the value as a quoted HCL string, which can be used in an expression written with
+// WriteUnquotedAttribute. Template sequences are escaped, so the value is a literal string.
+func QuoteString(value string) string {
+	return "\"" + encodeString(escapeTemplate(value)) + "\""
+}
+
 // encodeString assumes that HCL strings are escaped like JSON strings
 func encodeString(value string) string {
 	b, err := json.Marshal(value)
@@ -0,0 +1,222 @@
+// Package imports adopts existing resources into the Terraform state, so a space that was configured by hand can be
+// managed by the exported configuration. The resources are imported with Terraform import blocks, or with the
+// scripts written alongside the export.
+package imports
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/converters"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/logger"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sanitizer"
+)
+
+const (
+	// ModeLookup writes import blocks with the IDs of the resources found by name in the target space
+	ModeLookup = "lookup"
+	// ModeVariable writes import blocks with the IDs of the resources passed in as variables
+	ModeVariable = "variable"
+)
+
+const (
+	// BashScriptFileName is the bash script that imports the existing resources, written to the root of the export
+	BashScriptFileName = "import.sh"
+	// PowerShellScriptFileName is the PowerShell script that imports the existing resources
+	PowerShellScriptFileName = "import.ps1"
+)
+
+// importableResourceTypes are the resource types with names that are unique in a space, so the existing resources
+// can be found by name. Resources like variables and deployment processes are owned by a project, and are not
+// imported.
+var importableResourceTypes = map[string]bool{
+	"Accounts":            true,
+	"Certificates":        true,
+	"Environments":        true,
+	"Feeds":               true,
+	"Git-Credentials":     true,
+	"LibraryVariableSets": true,
+	"Lifecycles":          true,
+	"MachinePolicies":     true,
+	"Machines":            true,
+	"ProjectGroups":       true,
+	"Projects":            true,
+	"Spaces":              true,
+	"TagSets":             true,
+	"Tenants":             true,
+	"WorkerPools":         true,
+}
+
+// managedResource matches the lookup of a resource created by the configuration, like
+// ${octopusdeploy_environment.environment_production.id}, capturing the address of the resource.
+var managedResource = regexp.MustCompile(`^\$\{(octopusdeploy_[a-z0-9_]+\.[A-Za-z0-9_-]+)\.id\}$`)
+
+// Target is an exported resource that can be imported.
+type Target struct {
+	// Directory is the Terraform configuration directory holding the resource, like space_population
+	Directory string
+	// Address is the address of the resource in the configuration, like octopusdeploy_environment.environment_production
+	Address      string
+	ResourceType string
+	Name         string
+}
+
+// variableName is the name of the variable holding the ID of the resource to import in ModeVariable.
+func (t Target) variableName() string {
+	return "import_" + sanitizer.SanitizeName(t.Address[strings.Index(t.Address, ".")+1:]) + "_id"
+}
+
+// Targets returns the exported resources that can be imported, ordered by directory and address. Resources that
+// are looked up with data sources or passed in as variables already exist, and are not included.
+func Targets(dependencies *converters.ResourceDetailsCollection) []Target {
+	targets := []Target{}
+	found := map[string]bool{}
+
+	for _, r := range dependencies.SortedResources() {
+		if r.ToHcl == nil || !importableResourceTypes[r.ResourceType] {
+			continue
+		}
+
+		match := managedResource.FindStringSubmatch(r.Lookup)
+		if match == nil {
+			continue
+		}
+
+		target := Target{
+			Directory:    path.Dir(r.FileName),
+			Address:      match[1],
+			ResourceType: r.ResourceType,
+			Name:         r.Name,
+		}
+
+		if found[target.Directory+"/"+target.Address] {
+			continue
+		}
+		found[target.Directory+"/"+target.Address] = true
+
+		targets = append(targets, target)
+	}
+
+	sort.SliceStable(targets, func(i, j int) bool {
+		if targets[i].Directory != targets[j].Directory {
+			return targets[i].Directory < targets[j].Directory
+		}
+		return targets[i].Address < targets[j].Address
+	})
+
+	return targets
+}
+
+// ResolveIds finds the IDs of the targets by name in the space of the client, returning the IDs mapped to the
+// address of each target. The space itself is imported as the spaceId. Targets that do not exist in the space are
+// logged and left out of the map.
+func ResolveIds(ctx context.Context, octopusClient client.OctopusClient, spaceId string, targets []Target) (map[string]string, error) {
+	ids := map[string]string{}
+	collections := map[string][]octopus.NamedResource{}
+
+	for _, target := range targets {
+		if target.ResourceType == "Spaces" {
+			ids[target.Address] = spaceId
+			continue
+		}
+
+		resources, ok := collections[target.ResourceType]
+		if !ok {
+			collection := octopus.GeneralCollection[octopus.NamedResource]{}
+			if err := octopusClient.GetAllResources(ctx, target.ResourceType, &collection); err != nil {
+				return nil, err
+			}
+			resources = collection.Items
+			collections[target.ResourceType] = resources
+		}
+
+		for _, resource := range resources {
+			if resource.Name == target.Name {
+				ids[target.Address] = resource.Id
+				break
+			}
+		}
+
+		if _, ok := ids[target.Address]; !ok {
+			logger.Warn("did not find the resource to import in the target space",
+				"type", target.ResourceType, "name", target.Name, "address", target.Address)
+		}
+	}
+
+	return ids, nil
+}
+
+// AddImportBlocks adds an imports.tf file to each directory holding targets. In ModeLookup, each target with an
+// ID in ids is imported with that ID. In ModeVariable, each target is imported with the ID in a variable.
+func AddImportBlocks(mode string, targets []Target, ids map[string]string, dependencies *converters.ResourceDetailsCollection) {
+	byDirectory := map[string][]Target{}
+	directories := []string{}
+
+	for _, target := range targets {
+		if mode == ModeLookup && ids[target.Address] == "" {
+			continue
+		}
+
+		if _, ok := byDirectory[target.Directory]; !ok {
+			directories = append(directories, target.Directory)
+		}
+		byDirectory[target.Directory] = append(byDirectory[target.Directory], target)
+	}
+
+	for _, directory := range directories {
+		directoryTargets := byDirectory[directory]
+
+		thisResource := converters.ResourceDetails{}
+		thisResource.FileName = directory + "/imports.tf"
+		thisResource.Id = ""
+		thisResource.ResourceType = ""
+		thisResource.Lookup = ""
+		thisResource.ToHcl = func() (string, error) {
+			file := hcl.NewFile()
+			file.AppendComment("# Import the existing resources into the state when the configuration is applied.\n")
+
+			// Import blocks were added in Terraform 1.5, and 1.6 allows the ID to be a variable
+			requiredVersion := ">= 1.5.0"
+			if mode == ModeVariable {
+				requiredVersion = ">= 1.6.0"
+			}
+			file.AppendBlock(hcl.EncodeAsBlock(terraform.TerraformRequiredVersion{RequiredVersion: requiredVersion}, "terraform"))
+
+			for _, target := range directoryTargets {
+				block := hcl.EncodeAsBlock(terraform.TerraformImport{To: target.Address, Id: ids[target.Address]}, "import")
+				hcl.WriteUnquotedAttribute(block, "to", target.Address)
+
+				if mode == ModeVariable {
+					hcl.WriteUnquotedAttribute(block, "id", "var."+target.variableName())
+				}
+
+				file.AppendBlock(block)
+			}
+
+			if mode == ModeVariable {
+				for _, target := range directoryTargets {
+					variable := hcl.EncodeAsBlock(terraform.TerraformVariable{
+						Name:        target.variableName(),
+						Type:        "string",
+						Nullable:    false,
+						Sensitive:   false,
+						Description: "The ID of the existing resource " + target.Name + " to import as " + target.Address + ".",
+					}, "variable")
+					hcl.WriteUnquotedAttribute(variable, "type", "string")
+					file.AppendBlock(variable)
+				}
+			}
+
+			return file.Render(dependencies.Format)
+		}
+
+		dependencies.AddResource(thisResource)
+	}
+}
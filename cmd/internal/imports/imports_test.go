@@ -0,0 +1,155 @@
+package imports
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/converters"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/fakeoctopus"
+)
+
+// createDependencies records resources created by the configuration, and resources that are looked up or are not
+// importable by name.
+func createDependencies() *converters.ResourceDetailsCollection {
+	render := func() (string, error) { return "", nil }
+	dependencies := converters.ResourceDetailsCollection{}
+
+	dependencies.AddResource(
+		converters.ResourceDetails{FileName: "space_population/provider.tf", ToHcl: render},
+		converters.ResourceDetails{Id: "Spaces-1", Name: "Default", ResourceType: "Spaces", ToHcl: render,
+			FileName: "space_creation/octopus_space_default.tf", Lookup: "${octopusdeploy_space.octopus_space_default.id}"},
+		converters.ResourceDetails{Id: "Projects-1", Name: "Bob's App", ResourceType: "Projects", ToHcl: render,
+			FileName: "space_population/project_bobs_app.tf", Lookup: "${octopusdeploy_project.project_bobs_app.id}"},
+		converters.ResourceDetails{Id: "Environments-1", Name: "Development", ResourceType: "Environments", ToHcl: render,
+			FileName: "space_population/environment_development.tf", Lookup: "${octopusdeploy_environment.environment_development.id}"},
+		converters.ResourceDetails{Id: "Lifecycles-1", Name: "Default Lifecycle", ResourceType: "Lifecycles", ToHcl: render,
+			FileName: "space_population/lifecycle.tf", Lookup: "${data.octopusdeploy_lifecycles.lifecycle.lifecycles[0].id}"},
+		converters.ResourceDetails{Id: "variable-1", Name: "Url", ResourceType: "Variables", ToHcl: render,
+			FileName: "space_population/variable.tf", Lookup: "${octopusdeploy_variable.variable.id}"},
+	)
+
+	return &dependencies
+}
+
+func TestTargets(t *testing.T) {
+	targets := Targets(createDependencies())
+
+	expected := []Target{
+		{Directory: "space_creation", Address: "octopusdeploy_space.octopus_space_default", ResourceType: "Spaces", Name: "Default"},
+		{Directory: "space_population", Address: "octopusdeploy_environment.environment_development", ResourceType: "Environments", Name: "Development"},
+		{Directory: "space_population", Address: "octopusdeploy_project.project_bobs_app", ResourceType: "Projects", Name: "Bob's App"},
+	}
+
+	if len(targets) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, targets)
+	}
+
+	for i := range expected {
+		if targets[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], targets[i])
+		}
+	}
+}
+
+func TestResolveIds(t *testing.T) {
+	server := fakeoctopus.NewServer()
+	t.Cleanup(server.Close)
+	octopusClient := client.OctopusApiClient{Url: server.URL, Space: "Spaces-1"}
+
+	ids, err := ResolveIds(context.Background(), octopusClient, "Spaces-9", Targets(createDependencies()))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids["octopusdeploy_space.octopus_space_default"] != "Spaces-9" ||
+		ids["octopusdeploy_environment.environment_development"] != "Environments-1" {
+		t.Errorf("expected the space and environment to be found, got %v", ids)
+	}
+
+	if _, ok := ids["octopusdeploy_project.project_bobs_app"]; ok {
+		t.Errorf("did not expect to find a project that does not exist, got %v", ids)
+	}
+}
+
+func renderImports(t *testing.T, dependencies *converters.ResourceDetailsCollection, fileName string) string {
+	for _, r := range dependencies.Resources {
+		if r.FileName == fileName {
+			content, err := r.ToHcl()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return content
+		}
+	}
+
+	t.Fatalf("expected the file %s to be exported", fileName)
+	return ""
+}
+
+func TestAddImportBlocksLookup(t *testing.T) {
+	dependencies := createDependencies()
+	ids := map[string]string{
+		"octopusdeploy_space.octopus_space_default":         "Spaces-2",
+		"octopusdeploy_environment.environment_development": "Environments-5",
+	}
+
+	AddImportBlocks(ModeLookup, Targets(dependencies), ids, dependencies)
+	content := renderImports(t, dependencies, "space_population/imports.tf")
+
+	for _, expected := range []string{
+		`required_version = ">= 1.5.0"`,
+		"to = octopusdeploy_environment.environment_development",
+		`id = "Environments-5"`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected the imports to contain %s, got %s", expected, content)
+		}
+	}
+
+	// The project was not found in the target space, so it is created rather than imported
+	if strings.Contains(content, "octopusdeploy_project") {
+		t.Errorf("did not expect the project to be imported, got %s", content)
+	}
+}
+
+func TestAddImportBlocksVariable(t *testing.T) {
+	dependencies := createDependencies()
+
+	AddImportBlocks(ModeVariable, Targets(dependencies), nil, dependencies)
+	content := renderImports(t, dependencies, "space_population/imports.tf")
+
+	for _, expected := range []string{
+		`required_version = ">= 1.6.0"`,
+		"to = octopusdeploy_project.project_bobs_app",
+		"id = var.import_project_bobs_app_id",
+		`variable "import_project_bobs_app_id"`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected the imports to contain %s, got %s", expected, content)
+		}
+	}
+}
+
+func TestScripts(t *testing.T) {
+	scripts := Scripts(Targets(createDependencies()))
+
+	for fileName, expected := range map[string][]string{
+		BashScriptFileName: {
+			"terraform -chdir='space_creation' init\nimport_resource 'space_creation' 'octopusdeploy_space.octopus_space_default' 'Spaces' 'Default'\n",
+			`import_resource 'space_population' 'octopusdeploy_project.project_bobs_app' 'Projects' 'Bob'\''s App'`,
+		},
+		PowerShellScriptFileName: {
+			"terraform \"-chdir=space_population\" init\n",
+			`Import-Resource 'space_population' 'octopusdeploy_project.project_bobs_app' 'Projects' 'Bob''s App'`,
+		},
+	} {
+		for _, e := range expected {
+			if !strings.Contains(scripts[fileName], e) {
+				t.Errorf("expected %s to contain %s, got %s", fileName, e, scripts[fileName])
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package imports
+
+import (
+	"strings"
+)
+
+const bashHeader = `#!/usr/bin/env bash
+# Imports the existing resources in a space into the Terraform state, so the space can be managed by the exported
+# configuration. Set OCTOPUS_URL, OCTOPUS_CLI_API_KEY and OCTOPUS_SPACE_ID to the space holding the existing
+# resources, and run this script from the directory it was exported to. Resources that do not exist in the space,
+# or that are already in the state, are skipped. Requires curl and jq.
+set -euo pipefail
+
+export TF_VAR_octopus_server="${OCTOPUS_URL}"
+export TF_VAR_octopus_apikey="${OCTOPUS_CLI_API_KEY}"
+export TF_VAR_octopus_space_id="${OCTOPUS_SPACE_ID}"
+
+# import_resource <directory> <address> <resource type> <name>
+import_resource() {
+  local id
+  if terraform -chdir="$1" state show "$2" > /dev/null 2>&1; then
+    echo "Skipping $2: already in the state"
+    return
+  fi
+  if [[ "$3" == "Spaces" ]]; then
+    id="${OCTOPUS_SPACE_ID}"
+  else
+    id=$(curl --silent --fail -H "X-Octopus-ApiKey: ${OCTOPUS_CLI_API_KEY}" "${OCTOPUS_URL}/api/${OCTOPUS_SPACE_ID}/$3?take=10000" \
+      | jq -r --arg name "$4" '.Items[] | select(.Name==$name) | .Id' | head -n 1)
+  fi
+  if [[ -z "${id}" ]]; then
+    echo "Skipping $2: $3 \"$4\" was not found"
+    return
+  fi
+  terraform -chdir="$1" import "$2" "${id}"
+}
+`
+
+const powershellHeader = `# Imports the existing resources in a space into the Terraform state, so the space can be managed by the exported
+# configuration. Set OCTOPUS_URL, OCTOPUS_CLI_API_KEY and OCTOPUS_SPACE_ID to the space holding the existing
+# resources, and run this script from the directory it was exported to. Resources that do not exist in the space,
+# or that are already in the state, are skipped.
+$ErrorActionPreference = "Stop"
+
+$env:TF_VAR_octopus_server = $env:OCTOPUS_URL
+$env:TF_VAR_octopus_apikey = $env:OCTOPUS_CLI_API_KEY
+$env:TF_VAR_octopus_space_id = $env:OCTOPUS_SPACE_ID
+
+function Import-Resource($Directory, $Address, $ResourceType, $Name) {
+    terraform "-chdir=$Directory" state show $Address *> $null
+    if ($LASTEXITCODE -eq 0) {
+        Write-Host "Skipping $($Address): already in the state"
+        return
+    }
+    if ($ResourceType -eq "Spaces") {
+        $id = $env:OCTOPUS_SPACE_ID
+    } else {
+        $url = "$($env:OCTOPUS_URL)/api/$($env:OCTOPUS_SPACE_ID)/$($ResourceType)?take=10000"
+        $id = (Invoke-RestMethod -Uri $url -Headers @{"X-Octopus-ApiKey" = $env:OCTOPUS_CLI_API_KEY}).Items |
+            Where-Object { $_.Name -eq $Name } |
+            Select-Object -First 1 -ExpandProperty Id
+    }
+    if (-not $id) {
+        Write-Host "Skipping $($Address): $ResourceType ""$Name"" was not found"
+        return
+    }
+    terraform "-chdir=$Directory" import $Address $id
+    if ($LASTEXITCODE -ne 0) {
+        throw "Failed to import $Address"
+    }
+}
+`
+
+// Scripts returns the bash and PowerShell scripts that import the targets, mapped to their file names. Each
+// directory is initialised before its resources are imported.
+func Scripts(targets []Target) map[string]string {
+	bash := strings.Builder{}
+	powershell := strings.Builder{}
+	bash.WriteString(bashHeader)
+	powershell.WriteString(powershellHeader)
+
+	directory := ""
+	for _, target := range targets {
+		if target.Directory != directory {
+			directory = target.Directory
+			bash.WriteString("\nterraform -chdir=" + bashQuote(directory) + " init\n")
+			powershell.WriteString("\nterraform \"-chdir=" + directory + "\" init\n")
+		}
+
+		bash.WriteString("import_resource " + bashQuote(target.Directory) + " " + bashQuote(target.Address) + " " +
+			bashQuote(target.ResourceType) + " " + bashQuote(target.Name) + "\n")
+		powershell.WriteString("Import-Resource " + powershellQuote(target.Directory) + " " + powershellQuote(target.Address) + " " +
+			powershellQuote(target.ResourceType) + " " + powershellQuote(target.Name) + "\n")
+	}
+
+	return map[string]string{
+		BashScriptFileName:       bash.String(),
+		PowerShellScriptFileName: powershell.String(),
+	}
+}
+
+// bashQuote quotes the value in single quotes, which bash does not expand.
+func bashQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// powershellQuote quotes the value in single quotes, which PowerShell does not expand.
+func powershellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,235 @@
+// Package logger writes levelled diagnostic messages, as text or JSON lines, to stderr. This keeps the progress
+// and problem reports out of stdout, which only holds the generated configuration when -console is set.
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the severity of a message. Messages below the level of the logger are discarded.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+
+	return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+}
+
+// ParseLevel converts one of debug, info, warn or error, ignoring case, to a level.
+func ParseLevel(level string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+
+	return LevelInfo, errors.New("the log level must be one of debug, info, warn or error, but was " + level)
+}
+
+// Logger writes one line per message. It is safe to use from multiple goroutines.
+type Logger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level Level
+	json  bool
+	// now returns the time of each message, and is replaced in tests
+	now func() time.Time
+}
+
+// New returns a logger that writes messages at or above the level to out, either as text or as JSON objects.
+func New(out io.Writer, level Level, json bool) *Logger {
+	return &Logger{out: out, level: level, json: json, now: time.Now}
+}
+
+// Enabled returns true if messages at the level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Debug logs the message with the key value pairs that follow it, e.g. Debug("request", "url", url).
+func (l *Logger) Debug(msg string, keyValues ...any) {
+	l.log(LevelDebug, msg, keyValues)
+}
+
+// Info logs the message with the key value pairs that follow it.
+func (l *Logger) Info(msg string, keyValues ...any) {
+	l.log(LevelInfo, msg, keyValues)
+}
+
+// Warn logs the message with the key value pairs that follow it.
+func (l *Logger) Warn(msg string, keyValues ...any) {
+	l.log(LevelWarn, msg, keyValues)
+}
+
+// Error logs the message with the key value pairs that follow it.
+func (l *Logger) Error(msg string, keyValues ...any) {
+	l.log(LevelError, msg, keyValues)
+}
+
+func (l *Logger) log(level Level, msg string, keyValues []any) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	timestamp := l.now().UTC().Format(time.RFC3339Nano)
+
+	var line []byte
+	if l.json {
+		line = jsonLine(timestamp, level, msg, keyValues)
+	} else {
+		line = textLine(timestamp, level, msg, keyValues)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(line)
+}
+
+// textLine formats the message as "time LEVEL msg key=value", quoting any values that contain spaces.
+func textLine(timestamp string, level Level, msg string, keyValues []any) []byte {
+	buffer := bytes.Buffer{}
+	buffer.WriteString(timestamp)
+	buffer.WriteByte(' ')
+	buffer.WriteString(level.String())
+	buffer.WriteByte(' ')
+	buffer.WriteString(msg)
+
+	forEachPair(keyValues, func(key string, value any) {
+		buffer.WriteByte(' ')
+		buffer.WriteString(key)
+		buffer.WriteByte('=')
+		buffer.WriteString(quoteIfNeeded(fmt.Sprint(value)))
+	})
+
+	buffer.WriteByte('\n')
+	return buffer.Bytes()
+}
+
+// jsonLine formats the message as a JSON object with the time, level and msg fields followed by the key value
+// pairs, in the order they were passed.
+func jsonLine(timestamp string, level Level, msg string, keyValues []any) []byte {
+	buffer := bytes.Buffer{}
+	buffer.WriteString(`{"time":`)
+	writeJsonValue(&buffer, timestamp)
+	buffer.WriteString(`,"level":`)
+	writeJsonValue(&buffer, level.String())
+	buffer.WriteString(`,"msg":`)
+	writeJsonValue(&buffer, msg)
+
+	forEachPair(keyValues, func(key string, value any) {
+		buffer.WriteByte(',')
+		writeJsonValue(&buffer, key)
+		buffer.WriteByte(':')
+		writeJsonValue(&buffer, value)
+	})
+
+	buffer.WriteString("}\n")
+	return buffer.Bytes()
+}
+
+// forEachPair calls the callback with each key and value. Errors and other values with a String method are
+// converted to strings. A trailing key without a value is reported under the key "!BADKEY".
+func forEachPair(keyValues []any, callback func(key string, value any)) {
+	for i := 0; i < len(keyValues); i += 2 {
+		if i+1 == len(keyValues) {
+			callback("!BADKEY", keyValues[i])
+			return
+		}
+
+		value := keyValues[i+1]
+		switch v := value.(type) {
+		case error:
+			value = v.Error()
+		case fmt.Stringer:
+			value = v.String()
+		}
+
+		callback(fmt.Sprint(keyValues[i]), value)
+	}
+}
+
+func writeJsonValue(buffer *bytes.Buffer, value any) {
+	encoded, err := json.Marshal(value)
+
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprint(value))
+	}
+
+	buffer.Write(encoded)
+}
+
+func quoteIfNeeded(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+var defaultLogger atomic.Pointer[Logger]
+
+func init() {
+	defaultLogger.Store(New(os.Stderr, LevelInfo, false))
+}
+
+// Default returns the logger used by the package level functions. It writes text at the info level to stderr
+// until it is replaced with SetDefault.
+func Default() *Logger {
+	return defaultLogger.Load()
+}
+
+// SetDefault replaces the logger used by the package level functions.
+func SetDefault(l *Logger) {
+	defaultLogger.Store(l)
+}
+
+// Debug logs the message with the default logger.
+func Debug(msg string, keyValues ...any) {
+	Default().log(LevelDebug, msg, keyValues)
+}
+
+// Info logs the message with the default logger.
+func Info(msg string, keyValues ...any) {
+	Default().log(LevelInfo, msg, keyValues)
+}
+
+// Warn logs the message with the default logger.
+func Warn(msg string, keyValues ...any) {
+	Default().log(LevelWarn, msg, keyValues)
+}
+
+// Error logs the message with the default logger.
+func Error(msg string, keyValues ...any) {
+	Default().log(LevelError, msg, keyValues)
+}
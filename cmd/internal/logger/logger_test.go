@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestLogger(level Level, json bool) (*Logger, *bytes.Buffer) {
+	buffer := &bytes.Buffer{}
+	l := New(buffer, level, json)
+	l.now = func() time.Time {
+		return time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+	return l, buffer
+}
+
+func TestTextFormat(t *testing.T) {
+	l, buffer := newTestLogger(LevelInfo, false)
+
+	l.Info("request", "method", "GET", "status", 200, "duration", 1500*time.Millisecond, "error", errors.New("not found"))
+
+	expected := "2023-01-02T03:04:05Z INFO request method=GET status=200 duration=1.5s error=\"not found\"\n"
+	if buffer.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buffer.String())
+	}
+}
+
+func TestJsonFormat(t *testing.T) {
+	l, buffer := newTestLogger(LevelInfo, true)
+
+	l.Warn("request", "status", 500, "url", "https://octopus.example/api", "dangling")
+
+	expected := `{"time":"2023-01-02T03:04:05Z","level":"WARN","msg":"request","status":500,"url":"https://octopus.example/api","!BADKEY":"dangling"}` + "\n"
+	if buffer.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buffer.String())
+	}
+
+	if !json.Valid(buffer.Bytes()) {
+		t.Errorf("expected valid JSON, got %s", buffer.String())
+	}
+}
+
+func TestLevel(t *testing.T) {
+	l, buffer := newTestLogger(LevelWarn, false)
+
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+
+	expected := "2023-01-02T03:04:05Z WARN warn\n2023-01-02T03:04:05Z ERROR error\n"
+	if buffer.String() != expected {
+		t.Errorf("expected only the warn and error messages, got %q", buffer.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for input, expected := range map[string]Level{"debug": LevelDebug, "INFO": LevelInfo, "warn": LevelWarn, " error ": LevelError} {
+		level, err := ParseLevel(input)
+
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", input, err)
+		}
+
+		if level != expected {
+			t.Errorf("expected %q to be %s, got %s", input, expected, level)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
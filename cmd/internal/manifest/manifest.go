@@ -0,0 +1,257 @@
+// Package manifest describes the resources in an export, so the export can be audited, and so CI pipelines can
+// check what was exported before applying the Terraform configuration.
+package manifest
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
+	hcljson "github.com/hashicorp/hcl2/hcl/json"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/converters"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// FileName is the name of the manifest written alongside the Terraform configuration.
+const FileName = "manifest.json"
+
+// ManifestVersion is incremented when the manifest format changes in a way that breaks existing consumers.
+const ManifestVersion = 1
+
+// addressRegex extracts the Terraform address from a resource lookup like ${octopusdeploy_project.name.id}
+var addressRegex = regexp.MustCompile(`^\$\{((?:data\.)?[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+)`)
+
+// Manifest lists the resources that were found in Octopus and the input variables required to apply the
+// exported configuration.
+type Manifest struct {
+	Version   int        `json:"version"`
+	Resources []Resource `json:"resources"`
+	Variables []Variable `json:"variables"`
+}
+
+// Resource is an Octopus resource. Resources that were not exported have an empty address and file, and a
+// warning explaining why they were not exported.
+type Resource struct {
+	Id       string   `json:"id"`
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Exported bool     `json:"exported"`
+	Address  string   `json:"address"`
+	File     string   `json:"file"`
+	Warnings []string `json:"warnings"`
+}
+
+// Variable is an input variable with no default value, which must be supplied when the configuration is applied.
+type Variable struct {
+	Name        string `json:"name"`
+	File        string `json:"file"`
+	Description string `json:"description"`
+	Sensitive   bool   `json:"sensitive"`
+}
+
+// Build creates the manifest from the discovered resources and the generated files.
+func Build(dependencies *converters.ResourceDetailsCollection, unresolved []converters.UnresolvedReference, files map[string]string) Manifest {
+	manifest := Manifest{
+		Version:   ManifestVersion,
+		Resources: []Resource{},
+		Variables: requiredVariables(files),
+	}
+
+	sensitiveVariables := map[string][]string{}
+	for _, v := range manifest.Variables {
+		if v.Sensitive {
+			sensitiveVariables[v.File] = append(sensitiveVariables[v.File], v.Name)
+		}
+	}
+
+	warnings := map[string][]string{}
+	for _, w := range dependencies.Warnings() {
+		warnings[key(w.ResourceType, w.Id)] = append(warnings[key(w.ResourceType, w.Id)], w.Message)
+	}
+
+	for _, e := range dependencies.Errors() {
+		warnings[key(e.ResourceType, e.Id)] = append(warnings[key(e.ResourceType, e.Id)],
+			"The resource failed to export: "+e.Err.Error())
+	}
+
+	for _, u := range unresolved {
+		warnings[key(u.RequesterType, u.RequesterId)] = append(warnings[key(u.RequesterType, u.RequesterId)],
+			"The reference to "+u.ResourceType+" "+u.Id+" is empty because the resource was not exported")
+	}
+
+	// The layout can write several resources to one file
+	resourcesInFile := map[string]int{}
+	for _, r := range dependencies.SortedResources() {
+		if r.Id != "" && r.ToHcl != nil {
+			resourcesInFile[dependencies.FileName(r)]++
+		}
+	}
+
+	found := map[string]bool{}
+	for _, r := range dependencies.SortedResources() {
+		// Resources with no ID, like the provider, and resources rendered by their parent are not Octopus resources
+		if r.Id == "" || r.ToHcl == nil || found[key(r.ResourceType, r.Id)] {
+			continue
+		}
+
+		found[key(r.ResourceType, r.Id)] = true
+
+		resource := Resource{
+			Id:       r.Id,
+			Name:     r.Name,
+			Type:     r.ResourceType,
+			Warnings: append([]string{}, warnings[key(r.ResourceType, r.Id)]...),
+		}
+
+		fileName := dependencies.FileName(r)
+		if _, ok := files[fileName]; ok {
+			resource.Exported = true
+			resource.File = fileName
+
+			if match := addressRegex.FindStringSubmatch(r.Lookup); match != nil {
+				resource.Address = match[1]
+			}
+
+			// Resources looked up by their exact name are referenced through a local that filters the data source
+			if strings.HasPrefix(resource.Address, "data.") || strings.HasPrefix(resource.Address, "local.") {
+				resource.Warnings = append(resource.Warnings,
+					"The existing resource is looked up by name rather than created by the Terraform configuration")
+			}
+
+			// Variables are named after the resource they are used by, which identifies the resource when the
+			// file holds other resources
+			terraformName := resource.Address[strings.LastIndex(resource.Address, ".")+1:]
+			for _, v := range sensitiveVariables[fileName] {
+				if resourcesInFile[fileName] > 1 && (terraformName == "" || !strings.HasPrefix(v, terraformName)) {
+					continue
+				}
+
+				resource.Warnings = append(resource.Warnings,
+					"The sensitive value was not exported, and must be supplied with the variable "+v)
+			}
+		}
+
+		manifest.Resources = append(manifest.Resources, resource)
+	}
+
+	// Add the resources that were not exported at all
+	notExported := []Resource{}
+	for _, w := range dependencies.Warnings() {
+		notExported = append(notExported, Resource{Id: w.Id, Name: w.Name, Type: w.ResourceType})
+	}
+
+	for _, e := range dependencies.Errors() {
+		notExported = append(notExported, Resource{Id: e.Id, Type: e.ResourceType})
+	}
+
+	for _, r := range notExported {
+		if found[key(r.Type, r.Id)] {
+			continue
+		}
+
+		found[key(r.Type, r.Id)] = true
+
+		r.Warnings = warnings[key(r.Type, r.Id)]
+		manifest.Resources = append(manifest.Resources, r)
+	}
+
+	return manifest
+}
+
+// ToJson returns the manifest as indented JSON.
+func (m Manifest) ToJson() (string, error) {
+	output, err := json.MarshalIndent(m, "", "  ")
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(output) + "\n", nil
+}
+
+// variableSchema describes the variable blocks in a file.
+var variableSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "variable", LabelNames: []string{"name"}}},
+}
+
+// requiredVariables returns the variables with no default value, ordered by file and name. Variables are read
+// from files in the HCL native syntax ending with .tf, and the Terraform JSON syntax ending with .tf.json.
+func requiredVariables(files map[string]string) []Variable {
+	variables := []Variable{}
+
+	for fileName, content := range files {
+		var file *hcl.File
+		var diags hcl.Diagnostics
+
+		if strings.HasSuffix(fileName, ".tf.json") {
+			file, diags = hcljson.Parse([]byte(content), fileName)
+		} else if path.Ext(fileName) == ".tf" {
+			file, diags = hclsyntax.ParseConfig([]byte(content), fileName, hcl.Pos{Line: 1, Column: 1})
+		} else {
+			continue
+		}
+
+		if diags.HasErrors() {
+			continue
+		}
+
+		body, _, _ := file.Body.PartialContent(variableSchema)
+
+		for _, block := range body.Blocks {
+			attributes, _ := block.Body.JustAttributes()
+
+			if _, ok := attributes["default"]; ok {
+				continue
+			}
+
+			variables = append(variables, Variable{
+				Name:        block.Labels[0],
+				File:        fileName,
+				Description: stringAttribute(attributes, "description"),
+				Sensitive:   stringAttribute(attributes, "sensitive") == "true",
+			})
+		}
+	}
+
+	sort.Slice(variables, func(i, j int) bool {
+		if variables[i].File != variables[j].File {
+			return variables[i].File < variables[j].File
+		}
+		return variables[i].Name < variables[j].Name
+	})
+
+	return variables
+}
+
+// stringAttribute returns a constant attribute as a string, or an empty string if the attribute is not set or
+// is not a constant.
+func stringAttribute(attributes hcl.Attributes, name string) string {
+	attribute, ok := attributes[name]
+
+	if !ok {
+		return ""
+	}
+
+	value, diags := attribute.Expr.Value(nil)
+
+	if diags.HasErrors() || !value.IsWhollyKnown() || value.IsNull() {
+		return ""
+	}
+
+	value, err := convert.Convert(value, cty.String)
+
+	if err != nil {
+		return ""
+	}
+
+	return value.AsString()
+}
+
+func key(resourceType string, id string) string {
+	return resourceType + "/" + id
+}
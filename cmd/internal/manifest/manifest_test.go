@@ -0,0 +1,292 @@
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/converters"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/workerpool"
+)
+
+func TestBuild(t *testing.T) {
+	render := func() (string, error) { return "", nil }
+	dependencies := converters.ResourceDetailsCollection{}
+	dependencies.AddResource(
+		converters.ResourceDetails{FileName: "space_population/provider.tf", ToHcl: render},
+		converters.ResourceDetails{
+			Id:           "Feeds-1",
+			Name:         "Docker Hub",
+			ResourceType: "Feeds",
+			Lookup:       "${octopusdeploy_docker_container_registry.feed_docker_hub.id}",
+			FileName:     "space_population/feed_docker_hub.tf",
+			ToHcl:        render,
+		},
+		converters.ResourceDetails{
+			Id:           "Lifecycles-1",
+			Name:         "Default Lifecycle",
+			ResourceType: "Lifecycles",
+			Lookup:       "${data.octopusdeploy_lifecycles.lifecycle_default_lifecycle.lifecycles[0].id}",
+			FileName:     "space_population/lifecycle_default_lifecycle.tf",
+			ToHcl:        render,
+		},
+		converters.ResourceDetails{
+			Id:           "Feeds-2",
+			Name:         "Projects",
+			ResourceType: "Feeds",
+			FileName:     "space_population/feed_projects.tf",
+			ToHcl:        render,
+		},
+	)
+	dependencies.AddWarning(converters.ResourceWarning{
+		ResourceType: "Projects/Projects-1/Triggers",
+		Id:           "ProjectTriggers-1",
+		Name:         "Daily",
+		Message:      "The trigger was not exported",
+	})
+
+	unresolved := []converters.UnresolvedReference{{
+		RequesterType: "Lifecycles",
+		RequesterId:   "Lifecycles-1",
+		ResourceType:  "Environments",
+		Id:            "Environments-9",
+	}}
+
+	files := map[string]string{
+		"space_population/provider_vars.tf": `variable "octopus_server" {
+  type        = string
+  description = "The URL of the Octopus server"
+}`,
+		"space_population/feed_docker_hub.tf": `resource "octopusdeploy_docker_container_registry" "feed_docker_hub" {
+  password = "${var.feed_docker_hub_password}"
+}
+variable "feed_docker_hub_password" {
+  type      = string
+  sensitive = true
+}
+variable "feed_docker_hub_username" {
+  type    = string
+  default = "admin"
+}`,
+		"space_population/lifecycle_default_lifecycle.tf": `data "octopusdeploy_lifecycles" "lifecycle_default_lifecycle" {
+}`,
+	}
+
+	actual := Build(&dependencies, unresolved, files)
+
+	expected := Manifest{
+		Version: ManifestVersion,
+		Resources: []Resource{
+			{
+				Id:       "Feeds-1",
+				Name:     "Docker Hub",
+				Type:     "Feeds",
+				Exported: true,
+				Address:  "octopusdeploy_docker_container_registry.feed_docker_hub",
+				File:     "space_population/feed_docker_hub.tf",
+				Warnings: []string{"The sensitive value was not exported, and must be supplied with the variable feed_docker_hub_password"},
+			},
+			{
+				Id:       "Feeds-2",
+				Name:     "Projects",
+				Type:     "Feeds",
+				Warnings: []string{},
+			},
+			{
+				Id:       "Lifecycles-1",
+				Name:     "Default Lifecycle",
+				Type:     "Lifecycles",
+				Exported: true,
+				Address:  "data.octopusdeploy_lifecycles.lifecycle_default_lifecycle",
+				File:     "space_population/lifecycle_default_lifecycle.tf",
+				Warnings: []string{
+					"The reference to Environments Environments-9 is empty because the resource was not exported",
+					"The existing resource is looked up by name rather than created by the Terraform configuration",
+				},
+			},
+			{
+				Id:       "ProjectTriggers-1",
+				Name:     "Daily",
+				Type:     "Projects/Projects-1/Triggers",
+				Warnings: []string{"The trigger was not exported"},
+			},
+		},
+		Variables: []Variable{
+			{Name: "feed_docker_hub_password", File: "space_population/feed_docker_hub.tf", Sensitive: true},
+			{Name: "octopus_server", File: "space_population/provider_vars.tf", Description: "The URL of the Octopus server"},
+		},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+
+	if _, err := actual.ToJson(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildTerraformJson(t *testing.T) {
+	render := func() (string, error) { return "", nil }
+	dependencies := converters.ResourceDetailsCollection{Format: "tfjson"}
+	dependencies.AddResource(converters.ResourceDetails{
+		Id:           "Feeds-1",
+		Name:         "Docker Hub",
+		ResourceType: "Feeds",
+		Lookup:       "${octopusdeploy_docker_container_registry.feed_docker_hub.id}",
+		FileName:     "space_population/feed_docker_hub.tf",
+		ToHcl:        render,
+	})
+
+	files := map[string]string{
+		"space_population/feed_docker_hub.tf.json": `{
+  "resource": {"octopusdeploy_docker_container_registry": {"feed_docker_hub": {"password": "${var.feed_docker_hub_password}"}}},
+  "variable": {
+    "feed_docker_hub_password": {"type": "string", "sensitive": true},
+    "feed_docker_hub_username": {"type": "string", "default": "admin"}
+  }
+}`,
+	}
+
+	actual := Build(&dependencies, nil, files)
+
+	if len(actual.Resources) != 1 || !actual.Resources[0].Exported ||
+		actual.Resources[0].File != "space_population/feed_docker_hub.tf.json" {
+		t.Fatalf("expected the feed to be exported to the JSON file, got %+v", actual.Resources)
+	}
+
+	expected := []Variable{
+		{Name: "feed_docker_hub_password", File: "space_population/feed_docker_hub.tf.json", Sensitive: true},
+	}
+
+	if !reflect.DeepEqual(actual.Variables, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, actual.Variables)
+	}
+}
+
+// tenantVariablesClient returns the variables of two tenants that set the same project and common templates.
+type tenantVariablesClient struct{}
+
+func (c tenantVariablesClient) GetSpaceBaseUrl(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+func (c tenantVariablesClient) GetSpace(ctx context.Context, resources *octopus.Space) error {
+	return nil
+}
+
+func (c tenantVariablesClient) GetResourceById(ctx context.Context, resourceType string, id string, resources any) (bool, error) {
+	return false, nil
+}
+
+func (c tenantVariablesClient) GetAllResources(ctx context.Context, resourceType string, resources any, queryParams ...[]string) error {
+	tenants := []string{}
+	for _, tenant := range []string{"Tenants-1", "Tenants-2"} {
+		tenants = append(tenants, `{
+  "TenantId": "`+tenant+`",
+  "TenantName": "`+tenant+`",
+  "ProjectVariables": {"Projects-1": {"ProjectId": "Projects-1", "Variables": {"Environments-1": {"template-1": "value"}}}},
+  "LibraryVariables": {"LibraryVariableSets-1": {"LibraryVariableSetId": "LibraryVariableSets-1", "Variables": {"template-2": "value"}}}
+}`)
+	}
+
+	return json.Unmarshal([]byte("["+strings.Join(tenants, ",")+"]"), resources)
+}
+
+func TestBuildTenantVariables(t *testing.T) {
+	dependencies := converters.ResourceDetailsCollection{WorkerPool: workerpool.NewWorkerPool(1)}
+
+	err := converters.TenantVariableConverter{Client: tenantVariablesClient{}}.ToHcl(context.Background(), &dependencies)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, _, err := dependencies.RenderFiles(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{}
+	for _, r := range Build(&dependencies, nil, files).Resources {
+		if r.Type == "TenantVariables/All" {
+			ids = append(ids, r.Id)
+		}
+	}
+
+	// Each tenant sets the templates, so each variable is listed
+	expected := []string{
+		"Tenants-1/LibraryVariableSets-1/template-2",
+		"Tenants-1/Projects-1/Environments-1/template-1",
+		"Tenants-2/LibraryVariableSets-1/template-2",
+		"Tenants-2/Projects-1/Environments-1/template-1",
+	}
+
+	sort.Strings(ids)
+
+	if !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+}
+
+// sensitiveProcessClient returns a deployment process with an action that has a sensitive property.
+type sensitiveProcessClient struct {
+	tenantVariablesClient
+}
+
+func (c sensitiveProcessClient) GetResourceById(ctx context.Context, resourceType string, id string, resources any) (bool, error) {
+	return true, json.Unmarshal([]byte(`{
+  "Id": "`+id+`",
+  "ProjectId": "Projects-1",
+  "Steps": [{
+    "Name": "Deploy",
+    "Actions": [{
+      "Id": "Actions-1",
+      "Name": "Upload",
+      "ActionType": "Octopus.AwsUploadS3",
+      "Properties": {
+        "Octopus.Action.Aws.Region": "us-east-1",
+        "Octopus.Action.Aws.SecretKey": {"HasValue": true, "NewValue": null}
+      }
+    }]
+  }]
+}`), resources)
+}
+
+func TestBuildSensitiveActionProperties(t *testing.T) {
+	dependencies := converters.ResourceDetailsCollection{WorkerPool: workerpool.NewWorkerPool(1)}
+
+	err := converters.DeploymentProcessConverter{Client: sensitiveProcessClient{}}.
+		ToHclByIdAndName(context.Background(), "deploymentprocess-Projects-1", "Web App", &dependencies)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, _, err := dependencies.RenderFiles(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range Build(&dependencies, nil, files).Resources {
+		if r.Type != "DeploymentProcesses" {
+			continue
+		}
+
+		expected := []string{`The sensitive property Octopus.Action.Aws.SecretKey of the action Upload in the step Deploy was replaced with "replace me with a password"`}
+
+		if !reflect.DeepEqual(r.Warnings, expected) {
+			t.Fatalf("expected %v, got %v", expected, r.Warnings)
+		}
+
+		return
+	}
+
+	t.Fatal("expected the deployment process to be listed")
+}
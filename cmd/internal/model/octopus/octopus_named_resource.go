@@ -0,0 +1,7 @@
+package octopus
+
+// NamedResource holds the fields shared by every named Octopus resource.
+type NamedResource struct {
+	Id   string
+	Name string
+}
@@ -0,0 +1,10 @@
+package terraform
+
+type TerraformImport struct {
+	To string `hcl:"to"`
+	Id string `hcl:"id"`
+}
+
+type TerraformRequiredVersion struct {
+	RequiredVersion string `hcl:"required_version"`
+}
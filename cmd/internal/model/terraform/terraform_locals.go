@@ -0,0 +1,5 @@
+package terraform
+
+// TerraformLocals is a locals block. The local values are written as attributes of the block.
+type TerraformLocals struct {
+}
@@ -0,0 +1,12 @@
+package terraform
+
+// TerraformLookupData is a data source that finds a resource by name. It is used in place of a
+// dependency that was not exported.
+type TerraformLookupData struct {
+	Type        string   `hcl:"type,label"`
+	Name        string   `hcl:"name,label"`
+	Ids         []string `hcl:"ids"`
+	PartialName string   `hcl:"partial_name"`
+	Skip        int      `hcl:"skip"`
+	Take        int      `hcl:"take"`
+}
@@ -0,0 +1,7 @@
+package terraform
+
+// TerraformModule calls a child module. The input variables of the module are written as attributes of the block.
+type TerraformModule struct {
+	Name   string `hcl:"name,label"`
+	Source string `hcl:"source"`
+}
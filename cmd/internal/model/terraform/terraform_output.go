@@ -4,3 +4,9 @@ type TerraformOutput struct {
 	Name  string `hcl:"name,label"`
 	Value string `hcl:"value"`
 }
+
+type TerraformOutputMap struct {
+	Name        string `hcl:"name,label"`
+	Description string `hcl:"description"`
+	Sensitive   bool   `hcl:"sensitive"`
+}
@@ -1,7 +1,15 @@
 package terraform
 
+// DefaultOctopusProviderVersion is the version constraint of the Octopus provider used when no other constraint is set
+const DefaultOctopusProviderVersion = "0.10.1"
+
 type TerraformConfig struct {
 	RequiredProviders RequiredProviders `hcl:"required_providers,block"`
+	Backend           *TerraformBackend `hcl:"backend,block"`
+}
+
+type TerraformBackend struct {
+	Type string `hcl:"type,label"`
 }
 
 type RequiredProviders struct {
@@ -20,13 +28,26 @@ type TerraformProvider struct {
 	SpaceId *string `hcl:"space_id"`
 }
 
-func (c TerraformConfig) CreateTerraformConfig() TerraformConfig {
-	return TerraformConfig{
+// CreateTerraformConfig returns the config requiring the Octopus provider with the version constraint, or
+// DefaultOctopusProviderVersion if the constraint is empty. A non-empty backend adds an empty backend block of that
+// type, which is configured when the directory is initialised.
+func (c TerraformConfig) CreateTerraformConfig(providerVersion string, backend string) TerraformConfig {
+	if providerVersion == "" {
+		providerVersion = DefaultOctopusProviderVersion
+	}
+
+	config := TerraformConfig{
 		RequiredProviders: RequiredProviders{
 			OctopusProvider: OctopusProvider{
 				Source:  "OctopusDeployLabs/octopusdeploy",
-				Version: "0.10.1",
+				Version: providerVersion,
 			},
 		},
 	}
+
+	if backend != "" {
+		config.Backend = &TerraformBackend{Type: backend}
+	}
+
+	return config
 }
@@ -0,0 +1,7 @@
+package terraform
+
+type TerraformRemoteState struct {
+	Type    string `hcl:"type,label"`
+	Name    string `hcl:"name,label"`
+	Backend string `hcl:"backend"`
+}
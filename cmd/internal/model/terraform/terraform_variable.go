@@ -8,3 +8,10 @@ type TerraformVariable struct {
 	Description string  `hcl:"description"`
 	Default     *string `hcl:"default"`
 }
+
+type TerraformMapVariable struct {
+	Name        string             `hcl:"name,label"`
+	Type        string             `hcl:"type"`
+	Description string             `hcl:"description"`
+	Default     *map[string]string `hcl:"default"`
+}
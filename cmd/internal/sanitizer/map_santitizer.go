@@ -4,13 +4,16 @@ import (
 	"fmt"
 )
 
+// SensitivePlaceholder replaces the values that are not strings, like sensitive values, which the API does not return
+const SensitivePlaceholder = "replace me with a password"
+
 func SanitizeMap(input map[string]any) map[string]string {
 	fixedMap := map[string]string{}
 	for k, v := range input {
 		if _, ok := v.(string); ok {
 			fixedMap[k] = fmt.Sprintf("%v", v)
 		} else {
-			fixedMap[k] = "replace me with a password"
+			fixedMap[k] = SensitivePlaceholder
 		}
 	}
 	return fixedMap
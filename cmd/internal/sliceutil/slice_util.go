@@ -1,5 +1,7 @@
 package sliceutil
 
+import "sort"
+
 func Contains(a []string, x string) bool {
 	for _, n := range a {
 		if x == n {
@@ -8,3 +10,13 @@ func Contains(a []string, x string) bool {
 	}
 	return false
 }
+
+// SortedKeys returns the map keys in order, so the output generated from a map doesn't change between exports
+func SortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -91,10 +91,16 @@ func ParseBoolPointer(input *string) *bool {
 	return &value
 }
 
+// UnEscapeDollar restores the strings in the Terraform configuration files that are a single interpolation. Other
+// files, like scripts, are left as is.
 func UnEscapeDollar(fileMap map[string]string) map[string]string {
 	// Unescape dollar signs because of https://github.com/hashicorp/hcl/issues/323
 	regex := regexp.MustCompile(`"\$\$\{(.*?)\}"`)
 	for k, v := range fileMap {
+		if !strings.HasSuffix(k, ".tf") && !strings.HasSuffix(k, ".tf.json") {
+			continue
+		}
+
 		fileMap[k] = regex.ReplaceAllString(v, "\"${$1}\"")
 	}
 
@@ -0,0 +1,270 @@
+// Package validation checks the generated Terraform configuration before it is written, so errors in the
+// generated HCL are reported by the export rather than by terraform init or terraform plan.
+package validation
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
+	hcljson "github.com/hashicorp/hcl2/hcl/json"
+)
+
+// Problem is an error found in a generated file.
+type Problem struct {
+	FileName string
+	Line     int
+	Message  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.FileName, p.Line, p.Message)
+}
+
+// Problems is returned as an error when any generated file is invalid.
+type Problems []Problem
+
+func (p Problems) Error() string {
+	messages := make([]string, len(p))
+	for i, problem := range p {
+		messages[i] = problem.String()
+	}
+	return "the generated Terraform configuration is invalid:\n" + strings.Join(messages, "\n")
+}
+
+// ignoredRoots are references that are not declared by a block in the configuration.
+var ignoredRoots = map[string]bool{
+	"local":     true,
+	"module":    true,
+	"path":      true,
+	"terraform": true,
+	"count":     true,
+	"each":      true,
+	"self":      true,
+}
+
+// jsonSchema describes the top level blocks declared in files written in the Terraform JSON syntax.
+var jsonSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "resource", LabelNames: []string{"type", "name"}},
+		{Type: "data", LabelNames: []string{"type", "name"}},
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "output", LabelNames: []string{"name"}},
+		{Type: "provider", LabelNames: []string{"name"}},
+	},
+}
+
+// ValidateFiles parses each Terraform file, which maps a file name to its content, and checks that every
+// reference to a resource, data source or variable is declared by a file in the same directory. Files in the
+// HCL native syntax end with .tf, and files in the Terraform JSON syntax end with .tf.json. A Problems error
+// is returned if any file is invalid.
+func ValidateFiles(files map[string]string) error {
+	problems := Problems{}
+	directories := map[string]*module{}
+
+	fileNames := make([]string, 0, len(files))
+	for fileName := range files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	for _, fileName := range fileNames {
+		isJson := strings.HasSuffix(fileName, ".tf.json")
+
+		if !isJson && !strings.HasSuffix(fileName, ".tf") {
+			continue
+		}
+
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		if isJson {
+			file, diags = hcljson.Parse([]byte(files[fileName]), fileName)
+		} else {
+			file, diags = hclsyntax.ParseConfig([]byte(files[fileName]), fileName, hcl.Pos{Line: 1, Column: 1})
+		}
+
+		for _, diag := range diags {
+			problems = append(problems, diagnosticToProblem(fileName, diag))
+		}
+
+		if diags.HasErrors() {
+			continue
+		}
+
+		directory := path.Dir(fileName)
+		if _, ok := directories[directory]; !ok {
+			directories[directory] = &module{declarations: map[string]bool{}}
+		}
+
+		if isJson {
+			problems = append(problems, directories[directory].addJsonBody(fileName, file.Body)...)
+		} else {
+			directories[directory].addBody(file.Body.(*hclsyntax.Body))
+		}
+	}
+
+	directoryNames := make([]string, 0, len(directories))
+	for directory := range directories {
+		directoryNames = append(directoryNames, directory)
+	}
+	sort.Strings(directoryNames)
+
+	for _, directory := range directoryNames {
+		problems = append(problems, directories[directory].unresolvedReferences()...)
+	}
+
+	if len(problems) != 0 {
+		return problems
+	}
+
+	return nil
+}
+
+func diagnosticToProblem(fileName string, diag *hcl.Diagnostic) Problem {
+	problem := Problem{FileName: fileName, Message: diag.Summary}
+
+	if diag.Detail != "" {
+		problem.Message += ": " + diag.Detail
+	}
+
+	if diag.Subject != nil {
+		problem.Line = diag.Subject.Start.Line
+	}
+
+	return problem
+}
+
+// module holds the declarations and references of the files in one directory.
+type module struct {
+	declarations map[string]bool
+	references   []hcl.Traversal
+}
+
+func (m *module) addBody(body *hclsyntax.Body) {
+	for _, block := range body.Blocks {
+		switch {
+		case block.Type == "resource" && len(block.Labels) == 2:
+			m.declarations[block.Labels[0]+"."+block.Labels[1]] = true
+		case block.Type == "data" && len(block.Labels) == 2:
+			m.declarations["data."+block.Labels[0]+"."+block.Labels[1]] = true
+		case block.Type == "variable" && len(block.Labels) == 1:
+			m.declarations["var."+block.Labels[0]] = true
+		}
+
+		m.addReferences(block)
+	}
+}
+
+// addJsonBody adds the declarations and references of a file in the Terraform JSON syntax. The nested blocks of a
+// JSON body can't be told apart from attributes without the provider schema, so every property of a block is
+// treated as an attribute, which still finds the references in the nested blocks.
+func (m *module) addJsonBody(fileName string, body hcl.Body) []Problem {
+	problems := []Problem{}
+
+	content, _, diags := body.PartialContent(jsonSchema)
+	for _, diag := range diags {
+		problems = append(problems, diagnosticToProblem(fileName, diag))
+	}
+
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "resource":
+			m.declarations[block.Labels[0]+"."+block.Labels[1]] = true
+		case "data":
+			m.declarations["data."+block.Labels[0]+"."+block.Labels[1]] = true
+		case "variable":
+			m.declarations["var."+block.Labels[0]] = true
+		}
+
+		attributes, _ := block.Body.JustAttributes()
+		for name, attribute := range attributes {
+			// The type of a variable is a type constraint, and lifecycle and depends_on hold addresses as strings
+			if (block.Type == "variable" && name == "type") || name == "lifecycle" || name == "depends_on" {
+				continue
+			}
+
+			m.references = append(m.references, attribute.Expr.Variables()...)
+		}
+	}
+
+	return problems
+}
+
+func (m *module) addReferences(block *hclsyntax.Block) {
+	// The lifecycle arguments refer to the attributes of the resource itself rather than other resources
+	if block.Type == "lifecycle" {
+		return
+	}
+
+	for name, attribute := range block.Body.Attributes {
+		// The type of a variable is a type constraint, like string, rather than a reference
+		if block.Type == "variable" && name == "type" {
+			continue
+		}
+
+		m.references = append(m.references, attribute.Expr.Variables()...)
+	}
+
+	for _, child := range block.Body.Blocks {
+		m.addReferences(child)
+	}
+}
+
+func (m *module) unresolvedReferences() []Problem {
+	problems := []Problem{}
+
+	for _, reference := range m.references {
+		address, ok := referenceAddress(reference)
+
+		if !ok || m.declarations[address] {
+			continue
+		}
+
+		problems = append(problems, Problem{
+			FileName: reference.SourceRange().Filename,
+			Line:     reference.SourceRange().Start.Line,
+			Message:  "the reference to " + address + " is not declared in this directory",
+		})
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].String() < problems[j].String()
+	})
+
+	return problems
+}
+
+// referenceAddress returns the address of the declaration a reference points to, like var.name,
+// data.type.name or type.name, and false if the reference does not point to a declaration.
+func referenceAddress(reference hcl.Traversal) (string, bool) {
+	root := reference.RootName()
+
+	if ignoredRoots[root] {
+		return "", false
+	}
+
+	parts := []string{root}
+	length := 2
+	if root == "data" {
+		length = 3
+	}
+
+	for _, step := range reference[1:] {
+		if len(parts) == length {
+			break
+		}
+
+		attr, ok := step.(hcl.TraverseAttr)
+
+		if !ok {
+			break
+		}
+
+		parts = append(parts, attr.Name)
+	}
+
+	return strings.Join(parts, "."), true
+}
@@ -0,0 +1,127 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateFiles(t *testing.T) {
+	files := map[string]string{
+		"space_population/provider_vars.tf": `variable "octopus_server" {
+  type = string
+}`,
+		"space_population/environment.tf": `data "octopusdeploy_lifecycles" "default" {
+  partial_name = "Default"
+}
+resource "octopusdeploy_environment" "environment_dev" {
+  name = "${var.octopus_server}"
+  lifecycle {
+    ignore_changes = [sort_order]
+  }
+}`,
+		"space_population/project.tf": `resource "octopusdeploy_project" "project" {
+  environments = ["${octopusdeploy_environment.environment_dev.id}"]
+  lifecycle_id = "${data.octopusdeploy_lifecycles.default.lifecycles[0].id}"
+  depends_on   = [octopusdeploy_environment.environment_dev]
+  tags         = { for k, v in local.tags : k => v }
+}`,
+		"README.md": "${not.hcl",
+	}
+
+	if err := ValidateFiles(files); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateFilesSyntaxError(t *testing.T) {
+	files := map[string]string{
+		"space_population/environment.tf": `resource "octopusdeploy_environment" "environment_dev" {
+  name = "Dev"
+  description = "missing quote
+}`,
+	}
+
+	problems := validate(t, files)
+
+	if len(problems) == 0 || problems[0].FileName != "space_population/environment.tf" || problems[0].Line != 3 {
+		t.Fatalf("expected a syntax error on line 3, got %v", problems)
+	}
+}
+
+func TestValidateFilesUndeclaredReference(t *testing.T) {
+	files := map[string]string{
+		"space_creation/space.tf": `resource "octopusdeploy_environment" "environment_dev" {
+  name = "Dev"
+}`,
+		"space_population/project.tf": `resource "octopusdeploy_project" "project" {
+  name = "${var.project_name}"
+
+  environments = ["${octopusdeploy_environment.environment_dev.id}"]
+}`,
+	}
+
+	problems := validate(t, files)
+	expected := []string{
+		"space_population/project.tf:2: the reference to var.project_name is not declared in this directory",
+		"space_population/project.tf:4: the reference to octopusdeploy_environment.environment_dev is not declared in this directory",
+	}
+
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %v", len(expected), problems)
+	}
+
+	for i, problem := range problems {
+		if problem.String() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], problem.String())
+		}
+	}
+}
+
+func TestValidateTerraformJsonFiles(t *testing.T) {
+	files := map[string]string{
+		"space_population/provider_vars.tf.json": `{"variable": {"octopus_server": {"type": "string"}}}`,
+		"space_population/environment.tf.json": `{
+  "//": "Import with terraform import octopusdeploy_environment.environment_dev ${RESOURCE_ID}",
+  "resource": {"octopusdeploy_environment": {"environment_dev": {
+    "name": "${var.octopus_server}",
+    "lifecycle": [{"ignore_changes": ["sort_order"]}]
+  }}}
+}`,
+		"space_population/project.tf.json": `{"resource": {"octopusdeploy_project": {"project": {
+  "depends_on": ["octopusdeploy_environment.environment_dev"],
+  "template": [{"default_value": "${octopusdeploy_environment.environment_dev.id}"}]
+}}}}`,
+	}
+
+	if err := ValidateFiles(files); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateTerraformJsonFilesUndeclaredReference(t *testing.T) {
+	files := map[string]string{
+		"space_population/project.tf.json": `{"resource": {"octopusdeploy_project": {"project": {
+  "template": [{"default_value": "${octopusdeploy_environment.missing.id}"}]
+}}}}`,
+		"space_population/broken.tf.json": `{"resource": `,
+	}
+
+	problems := validate(t, files)
+
+	last := problems[len(problems)-1]
+	if problems[0].FileName != "space_population/broken.tf.json" ||
+		last.Message != "the reference to octopusdeploy_environment.missing is not declared in this directory" {
+		t.Fatalf("expected a syntax error and an undeclared reference, got %v", problems)
+	}
+}
+
+func validate(t *testing.T, files map[string]string) Problems {
+	err := ValidateFiles(files)
+	problems := Problems{}
+
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected the files to be invalid, got %v", err)
+	}
+
+	return problems
+}
@@ -0,0 +1,93 @@
+package workerpool
+
+import (
+	"sync"
+)
+
+// WorkerPool limits the number of goroutines used to discover dependencies. A nil WorkerPool
+// is valid, and runs all work serially on the calling goroutine.
+type WorkerPool struct {
+	slots chan struct{}
+}
+
+// NewWorkerPool creates a pool that allows up to parallelism tasks to run in their own goroutines.
+// A parallelism of 1 or less returns nil, which runs all work serially.
+func NewWorkerPool(parallelism int) *WorkerPool {
+	if parallelism <= 1 {
+		return nil
+	}
+
+	return &WorkerPool{
+		slots: make(chan struct{}, parallelism),
+	}
+}
+
+// NewGroup creates a group of tasks that share the slots in this pool.
+func (p *WorkerPool) NewGroup() *Group {
+	return &Group{pool: p}
+}
+
+// Group is a collection of tasks whose completion can be waited on. Tasks that can not be assigned
+// a free slot in the pool are run on the calling goroutine. This means nested groups can never deadlock
+// waiting for a slot held by a parent task, while the total number of goroutines is still bounded by
+// the pool size.
+type Group struct {
+	pool *WorkerPool
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	err  error
+}
+
+// Go runs the task, either in a new goroutine if the pool has a free slot, or on the calling goroutine.
+// Once any task has failed, subsequent tasks are skipped. A task may run after the loop that queued it has moved
+// on, so loop variables are copied before the task captures them.
+func (g *Group) Go(task func() error) {
+	if g.failed() {
+		return
+	}
+
+	if g.pool != nil {
+		select {
+		case g.pool.slots <- struct{}{}:
+			g.wg.Add(1)
+			go func() {
+				defer func() {
+					<-g.pool.slots
+					g.wg.Done()
+				}()
+				g.setError(task())
+			}()
+			return
+		default:
+		}
+	}
+
+	g.setError(task())
+}
+
+// Wait blocks until all tasks have completed, and returns the first error.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+func (g *Group) failed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err != nil
+}
+
+func (g *Group) setError(err error) {
+	if err == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err == nil {
+		g.err = err
+	}
+}
@@ -0,0 +1,82 @@
+package workerpool
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGroupRunsAllTasks(t *testing.T) {
+	for _, pool := range []*WorkerPool{nil, NewWorkerPool(4)} {
+		var count int32
+		group := pool.NewGroup()
+		for i := 0; i < 100; i++ {
+			group.Go(func() error {
+				atomic.AddInt32(&count, 1)
+				return nil
+			})
+		}
+
+		if err := group.Wait(); err != nil {
+			t.Fatal(err)
+		}
+
+		if count != 100 {
+			t.Fatalf("Expected 100 tasks to run, but %d ran", count)
+		}
+	}
+}
+
+func TestGroupBoundsConcurrency(t *testing.T) {
+	pool := NewWorkerPool(3)
+	var running int32
+	var maxRunning int32
+
+	group := pool.NewGroup()
+	for i := 0; i < 50; i++ {
+		group.Go(func() error {
+			// Nested groups share the pool, and must not deadlock
+			nested := pool.NewGroup()
+			for j := 0; j < 5; j++ {
+				nested.Go(func() error {
+					current := atomic.AddInt32(&running, 1)
+					for {
+						observed := atomic.LoadInt32(&maxRunning)
+						if current <= observed || atomic.CompareAndSwapInt32(&maxRunning, observed, current) {
+							break
+						}
+					}
+					atomic.AddInt32(&running, -1)
+					return nil
+				})
+			}
+			return nested.Wait()
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pool allows 3 goroutines, plus the calling goroutine runs tasks when no slot is free
+	if maxRunning > 4 {
+		t.Fatalf("Expected at most 4 concurrent tasks, but found %d", maxRunning)
+	}
+}
+
+func TestGroupReturnsError(t *testing.T) {
+	group := NewWorkerPool(2).NewGroup()
+	for i := 0; i < 10; i++ {
+		i := i
+		group.Go(func() error {
+			if i == 5 {
+				return errors.New("task failed")
+			}
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err == nil {
+		t.Fatal("Expected the group to return an error")
+	}
+}
@@ -1,14 +1,18 @@
 package writers
 
-import "fmt"
+import (
+	"fmt"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sliceutil"
+)
 
 type ConsoleWriter struct {
 }
 
+// Write prints the files ordered by name, so the output doesn't change between exports
 func (c ConsoleWriter) Write(files map[string]string) (string, error) {
-	for k, v := range files {
+	for _, k := range sliceutil.SortedKeys(files) {
 		fmt.Println(k)
-		fmt.Println(v)
+		fmt.Println(files[k])
 	}
 
 	return "", nil
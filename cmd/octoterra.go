@@ -1,68 +1,512 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/converters"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/credentials"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/dryrun"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/graph"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/imports"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/logger"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/manifest"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
-	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/terraform"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/sliceutil"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/validation"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/workerpool"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/writers"
 	"os"
+	"os/signal"
 	"strings"
+	"time"
 )
 
+// Arguments captures the options passed to octoterra on the command line.
+type Arguments struct {
+	Url    string
+	Space  string
+	ApiKey string
+	// AccessToken is an Octopus bearer token, used in place of the ApiKey.
+	AccessToken string
+	// OidcToken is an ID token from an external identity provider, exchanged for an access token.
+	OidcToken string
+	// ServiceAccountId is the Octopus service account the OidcToken is exchanged for.
+	ServiceAccountId string
+	// Profile is the name of the profile to read from the CredentialsFile.
+	Profile         string
+	CredentialsFile string
+	Destination     string
+	Console         bool
+	ProjectId       string
+	ProjectName     string
+	// CaBundle, ClientCertificate, ClientKey, Proxy and InsecureSkipVerify configure the HTTP transport.
+	CaBundle           string
+	ClientCertificate  string
+	ClientKey          string
+	Proxy              string
+	InsecureSkipVerify bool
+	// Headers are additional headers, in the format "Name: value", sent with every request.
+	Headers StringSliceArgs
+	// Record is a directory where every API response is saved as a snapshot.
+	Record string
+	// Replay is a directory holding a snapshot that is served in place of the Octopus API.
+	Replay string
+	// Parallelism is the maximum number of goroutines used to discover the resources to export.
+	Parallelism int
+	// Timeout is the maximum time allowed for the whole export. Zero means no limit.
+	Timeout time.Duration
+	// RequestTimeout is the maximum time allowed for each request to the Octopus API. Zero means no limit.
+	RequestTimeout time.Duration
+	// Strict fails the export, without writing any files, if any resource could not be exported or was exported
+	// with changes.
+	Strict bool
+	// ContinueOnError exports every resource that can be exported, reporting the resources that failed at the end.
+	ContinueOnError bool
+	// UnresolvedReferences is how references to resources that were not exported are handled. One of
+	// UnresolvedReferencesWarn, UnresolvedReferencesFail or UnresolvedReferencesLookup.
+	UnresolvedReferences string
+	// LogLevel is the minimum level of the messages written to stderr. One of debug, info, warn or error.
+	LogLevel string
+	// LogFormat is the format of the messages written to stderr. One of LogFormatText or LogFormatJson.
+	LogFormat string
+	// Quiet only writes errors to stderr, overriding the LogLevel.
+	Quiet bool
+	// DryRun discovers the resources to export and prints a summary, without rendering or writing any files.
+	DryRun bool
+	// Graph is the format of the dependency graph written with the export. One of graph.FormatDot or
+	// graph.FormatMermaid, or empty to not write a graph.
+	Graph string
+	// Format is the syntax of the generated configuration. One of hcl.FormatHcl or hcl.FormatTfJson.
+	Format string
+	// Module exports the project as a Terraform module, with the dependencies of the project passed in as input
+	// variables, and a sample root module that calls the module.
+	Module bool
+	// LookupProjectDependencies references the shared resources a project depends on, like lifecycles, environments
+	// and feeds, through data sources that find the existing resources by name, rather than exporting them.
+	LookupProjectDependencies bool
+	// ImportBlocks writes import blocks that adopt the existing resources into the state. One of imports.ModeLookup
+	// or imports.ModeVariable, or empty to not write import blocks.
+	ImportBlocks string
+	// ImportSpaceId is the space the existing resources are found in by name with imports.ModeLookup.
+	ImportSpaceId string
+	// ImportScripts writes bash and PowerShell scripts that import the existing resources into the state.
+	ImportScripts bool
+	// ProviderVersion is the version constraint of the Octopus provider.
+	ProviderVersion string
+	// Backend is the type of the backend block written to each directory, or empty for no backend block.
+	Backend string
+	// SensitiveOutputs marks the outputs holding the IDs of accounts, certificates and git credentials as sensitive.
+	SensitiveOutputs bool
+	// RemoteState reads the ID of the space created by space_creation from its state in space_population.
+	RemoteState bool
+	// Layout is how the resources are grouped into files. One of converters.LayoutResource, converters.LayoutType,
+	// converters.LayoutProject or converters.LayoutSingle.
+	Layout string
+	// ScriptFiles writes the inline scripts of steps and script modules to their own files, which are read by the
+	// configuration with the file function.
+	ScriptFiles bool
+}
+
+// The exit codes returned when the export fails.
+const (
+	exitCodeFailed = 1
+	// exitCodePartialExport means some resources failed to export with -continue-on-error, and the others were written
+	exitCodePartialExport = 2
+	// exitCodeStrict means the export was not written because of problems found with -strict
+	exitCodeStrict = 3
+)
+
+// exitCodeError is an error that sets the exit code of the process.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e exitCodeError) Unwrap() error {
+	return e.err
+}
+
+// moduleRootDirectory holds the sample root module that calls the project module exported with -module
+const moduleRootDirectory = "root_module"
+
+const (
+	// UnresolvedReferencesWarn prints the references to resources that were not exported after the export
+	UnresolvedReferencesWarn = "warn"
+	// UnresolvedReferencesFail fails the export if any resource references a resource that was not exported
+	UnresolvedReferencesFail = "fail"
+	// UnresolvedReferencesLookup replaces references to resources that were not exported with data sources
+	// that look up the existing resources by name
+	UnresolvedReferencesLookup = "lookup"
+)
+
+const (
+	// LogFormatText writes each message as "time LEVEL message key=value"
+	LogFormatText = "text"
+	// LogFormatJson writes each message as a JSON object
+	LogFormatJson = "json"
+)
+
+// StringSliceArgs collects the values of a flag that can be passed multiple times.
+type StringSliceArgs []string
+
+func (s *StringSliceArgs) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *StringSliceArgs) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func main() {
-	url, space, apiKey, dest, console, projectId, projectName := parseUrl()
+	args := parseUrl()
+
+	if err := configureLogger(args); err != nil {
+		logger.Error(err.Error())
+		os.Exit(exitCodeFailed)
+	}
 
-	var err error = nil
+	// Ctrl-C cancels any in flight requests rather than killing the process mid write
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	if projectName != "" {
-		projectId, err := ConvertProjectNameToId(url, space, apiKey, projectName)
+	if args.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, args.Timeout)
+		defer cancel()
+	}
+
+	err := resolveCredentials(ctx, &args)
+
+	if err == nil {
+		err = run(ctx, args)
+	}
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			logger.Error("the export did not complete within the timeout", "timeout", args.Timeout)
+		}
+
+		logger.Error(err.Error())
+		stop()
+
+		os.Exit(exitCodeOf(err))
+	}
+}
+
+// exitCodeOf returns the exit code of the process when the export fails with the error.
+func exitCodeOf(err error) int {
+	var exitErr exitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	return exitCodeFailed
+}
+
+// configureLogger replaces the default logger with one that writes messages to stderr at the configured level
+// and in the configured format.
+func configureLogger(args Arguments) error {
+	level, err := logger.ParseLevel(args.LogLevel)
+
+	if err != nil {
+		return err
+	}
+
+	if args.Quiet {
+		level = logger.LevelError
+	}
+
+	if args.LogFormat != LogFormatText && args.LogFormat != LogFormatJson {
+		return errors.New("log-format must be one of " + LogFormatText + " or " + LogFormatJson)
+	}
+
+	logger.SetDefault(logger.New(os.Stderr, level, args.LogFormat == LogFormatJson))
+
+	return nil
+}
+
+func run(ctx context.Context, args Arguments) error {
+	if args.UnresolvedReferences != UnresolvedReferencesWarn && args.UnresolvedReferences != UnresolvedReferencesFail &&
+		args.UnresolvedReferences != UnresolvedReferencesLookup {
+		return errors.New("unresolvedReferences must be one of " + UnresolvedReferencesWarn + ", " +
+			UnresolvedReferencesFail + " or " + UnresolvedReferencesLookup)
+	}
+
+	if _, found := graph.FileNames[args.Graph]; args.Graph != "" && !found {
+		return errors.New("graph must be one of " + graph.FormatDot + " or " + graph.FormatMermaid)
+	}
+
+	if args.Format != hcl.FormatHcl && args.Format != hcl.FormatTfJson {
+		return errors.New("format must be one of " + hcl.FormatHcl + " or " + hcl.FormatTfJson)
+	}
+
+	if args.Layout != converters.LayoutResource && args.Layout != converters.LayoutType &&
+		args.Layout != converters.LayoutProject && args.Layout != converters.LayoutSingle {
+		return errors.New("layout must be one of " + converters.LayoutResource + ", " + converters.LayoutType + ", " +
+			converters.LayoutProject + " or " + converters.LayoutSingle)
+	}
+
+	if args.ImportBlocks != "" && args.ImportBlocks != imports.ModeLookup && args.ImportBlocks != imports.ModeVariable {
+		return errors.New("importBlocks must be one of " + imports.ModeLookup + " or " + imports.ModeVariable)
+	}
+
+	if args.ImportBlocks == imports.ModeLookup && args.ImportSpaceId == "" {
+		return errors.New("importBlocks " + imports.ModeLookup + " requires importSpaceId")
+	}
+
+	// Resources can only be imported into the root module
+	if args.Module && (args.ImportBlocks != "" || args.ImportScripts) {
+		return errors.New("importBlocks and importScripts can not be used with module")
+	}
+
+	if args.Backend != "" && !sliceutil.Contains(converters.Backends, args.Backend) {
+		return errors.New("backend must be one of " + strings.Join(converters.Backends, ", "))
+	}
+
+	// The remote state is written by the space_creation directory, which is only exported with a space
+	if args.RemoteState && (args.Backend == "" || args.ProjectId != "" || args.ProjectName != "") {
+		return errors.New("remoteState requires backend, and can not be used with projectId or projectName")
+	}
+
+	if args.Module && args.ProjectId == "" && args.ProjectName == "" {
+		return errors.New("module requires projectId or projectName")
+	}
+
+	if args.LookupProjectDependencies && args.ProjectId == "" && args.ProjectName == "" {
+		return errors.New("lookupProjectDependencies requires projectId or projectName")
+	}
+
+	if args.ProjectName != "" {
+		projectId, err := ConvertProjectNameToId(ctx, args)
 
 		if err != nil {
-			fmt.Println(err.Error())
-			os.Exit(1)
+			return err
 		}
 
-		err = ConvertProjectToTerraform(url, space, apiKey, dest, console, projectId)
-	} else if projectId != "" {
-		err = ConvertProjectToTerraform(url, space, apiKey, dest, console, projectId)
-	} else {
-		err = ConvertSpaceToTerraform(url, space, apiKey, dest, console)
+		args.ProjectId = projectId
+		return ConvertProjectToTerraform(ctx, args)
+	}
+
+	if args.ProjectId != "" {
+		return ConvertProjectToTerraform(ctx, args)
+	}
+
+	return ConvertSpaceToTerraform(ctx, args)
+}
+
+// resolveCredentials populates the URL and secrets that were not passed on the command line from the
+// environment variables and then the credentials file. A secret of "-" is read from stdin, and an
+// OIDC token is exchanged for an access token.
+func resolveCredentials(ctx context.Context, args *Arguments) error {
+	fromArgs := credentials.Credentials{
+		Url:              args.Url,
+		ApiKey:           args.ApiKey,
+		AccessToken:      args.AccessToken,
+		OidcToken:        args.OidcToken,
+		ServiceAccountId: args.ServiceAccountId,
+	}
+
+	stdinSecrets := 0
+	for _, secret := range []*string{&fromArgs.ApiKey, &fromArgs.AccessToken, &fromArgs.OidcToken} {
+		if *secret != "-" {
+			continue
+		}
+
+		stdinSecrets++
+		if stdinSecrets > 1 {
+			return errors.New("only one of -apiKey, -accessToken and -oidcToken can be read from stdin")
+		}
+
+		value, err := credentials.ReadSecret(os.Stdin)
+
+		if err != nil {
+			return err
+		}
+
+		*secret = value
+	}
+
+	profile := args.Profile
+	if profile == "" {
+		profile = credentials.DefaultProfile
+	}
+
+	fromProfile, err := credentials.FromProfile(args.CredentialsFile, profile, args.Profile != "")
+
+	if err != nil {
+		return err
+	}
+
+	resolved := credentials.Resolve(fromArgs, credentials.FromEnvironment(), fromProfile)
+
+	args.Url = resolved.Url
+	args.ApiKey = resolved.ApiKey
+	args.AccessToken = resolved.AccessToken
+	args.OidcToken = resolved.OidcToken
+	args.ServiceAccountId = resolved.ServiceAccountId
+
+	// A replayed snapshot makes no requests, so there is no token to exchange
+	if args.AccessToken == "" && args.OidcToken != "" && args.Replay == "" {
+		octopusClient, err := newOctopusClient(*args)
+
+		if err != nil {
+			return err
+		}
+
+		args.AccessToken, err = octopusClient.ExchangeOidcToken(ctx, args.ServiceAccountId, args.OidcToken)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newOctopusClient creates the client used to query the Octopus API.
+func newOctopusClient(args Arguments) (client.OctopusApiClient, error) {
+	httpClient, err := client.NewHttpClient(client.TransportOptions{
+		CaBundle:           args.CaBundle,
+		ClientCertificate:  args.ClientCertificate,
+		ClientKey:          args.ClientKey,
+		Proxy:              args.Proxy,
+		InsecureSkipVerify: args.InsecureSkipVerify,
+	})
+
+	if err != nil {
+		return client.OctopusApiClient{}, err
+	}
+
+	headers := map[string]string{}
+	for _, header := range args.Headers {
+		name, value, found := strings.Cut(header, ":")
+
+		if !found || strings.TrimSpace(name) == "" {
+			return client.OctopusApiClient{}, errors.New("headers must be in the format \"Name: value\", but was " + header)
+		}
+
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	return client.OctopusApiClient{
+		Url:            args.Url,
+		Space:          args.Space,
+		ApiKey:         args.ApiKey,
+		AccessToken:    args.AccessToken,
+		HttpClient:     httpClient,
+		Headers:        headers,
+		RequestTimeout: args.RequestTimeout,
+	}, nil
+}
+
+// newClient creates the client used by the converters. It queries the Octopus API, optionally recording
+// the responses, or replays a previously recorded snapshot.
+func newClient(args Arguments) (client.OctopusClient, error) {
+	if args.Replay != "" {
+		return client.NewReplayOctopusClient(args.Replay)
+	}
+
+	octopusClient, err := newOctopusClient(args)
+
+	if err != nil {
+		return nil, err
 	}
 
+	if args.Record != "" {
+		return client.NewRecordingOctopusClient(octopusClient, args.Record, args.Url, args.Space)
+	}
+
+	return octopusClient, nil
+}
+
+// newCountingClient creates the client used by the converters, counting the API calls made by the export.
+func newCountingClient(args Arguments) (*client.CountingOctopusClient, error) {
+	octopusClient, err := newClient(args)
+
 	if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(1)
+		return nil, err
+	}
+
+	return client.NewCountingOctopusClient(octopusClient), nil
+}
+
+// printDryRun prints the resources that were discovered, the API calls needed to export them, and the dependency
+// graph if a format was selected, to stdout.
+// Any resources that failed to export are reported as they would be by a full export.
+func printDryRun(args Arguments, dependencies *converters.ResourceDetailsCollection, counter *client.CountingOctopusClient) error {
+	fmt.Print(dryrun.Build(dependencies, counter.Calls()).String())
+
+	if args.Graph != "" {
+		dependencyGraph, err := graph.Build(dependencies).Render(args.Graph)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Print("\n" + dependencyGraph)
+	}
+
+	resourceErrors := dependencies.Errors()
+	printProblems(dependencies.Warnings(), resourceErrors, nil)
+
+	if len(resourceErrors) != 0 {
+		return exitCodeError{
+			code: exitCodePartialExport,
+			err:  errors.New(fmt.Sprint(len(resourceErrors)) + " resources failed to export"),
+		}
 	}
+
+	return nil
 }
 
-func ConvertProjectNameToId(url string, space string, apiKey string, name string) (string, error) {
-	client := client.OctopusClient{
-		Url:    url,
-		Space:  space,
-		ApiKey: apiKey,
+func ConvertProjectNameToId(ctx context.Context, args Arguments) (string, error) {
+	client, err := newClient(args)
+
+	if err != nil {
+		return "", err
 	}
 
 	collection := octopus.GeneralCollection[octopus.Project]{}
-	client.GetAllResources("Projects", &collection, []string{"name", name})
+	err = client.GetAllResources(ctx, "Projects", &collection, []string{"name", args.ProjectName})
+
+	if err != nil {
+		return "", err
+	}
 
 	for _, p := range collection.Items {
-		if p.Name == name {
+		if p.Name == args.ProjectName {
 			return p.Id, nil
 		}
 	}
 
-	return "", errors.New("did not find project with name " + name)
+	return "", errors.New("did not find project with name " + args.ProjectName)
 }
 
-func ConvertSpaceToTerraform(url string, space string, apiKey string, dest string, console bool) error {
-	client := client.OctopusClient{
-		Url:    url,
-		Space:  space,
-		ApiKey: apiKey,
+// newProviderGenerator creates the generator of the provider, backend and variables configured by the arguments.
+func newProviderGenerator(args Arguments) converters.TerraformProviderGenerator {
+	return converters.TerraformProviderGenerator{
+		ProviderVersion:        args.ProviderVersion,
+		Backend:                args.Backend,
+		SpaceIdFromRemoteState: args.RemoteState,
+		SensitiveOutputs:       args.SensitiveOutputs,
+	}
+}
+
+func ConvertSpaceToTerraform(ctx context.Context, args Arguments) error {
+	client, err := newCountingClient(args)
+
+	if err != nil {
+		return err
 	}
 
 	machinePolicyConverter := converters.MachinePolicyConverter{Client: client}
@@ -176,6 +620,7 @@ func ConvertSpaceToTerraform(url string, space string, apiKey string, dest strin
 
 	spaceConverter := converters.SpaceConverter{
 		Client:                      client,
+		ProviderGenerator:           newProviderGenerator(args),
 		AccountConverter:            accountConverter,
 		EnvironmentConverter:        environmentConverter,
 		LibraryVariableSetConverter: libraryVariableSetConverter,
@@ -219,38 +664,71 @@ func ConvertSpaceToTerraform(url string, space string, apiKey string, dest strin
 		FeedConverter:                     feedConverter,
 	}
 
-	dependencies := converters.ResourceDetailsCollection{}
+	dependencies := converters.ResourceDetailsCollection{
+		WorkerPool:      workerpool.NewWorkerPool(args.Parallelism),
+		ContinueOnError: args.ContinueOnError,
+		Format:          args.Format,
+		Layout:          args.Layout,
+		ScriptFiles:     args.ScriptFiles,
+	}
 
-	err := spaceConverter.ToHcl(&dependencies)
+	err = spaceConverter.ToHcl(ctx, &dependencies)
 
 	if err != nil {
 		return err
 	}
 
-	hcl, err := processResources(dependencies.Resources)
+	if args.DryRun {
+		return printDryRun(args, &dependencies, client)
+	}
+
+	hcl, unresolved, err := renderResources(ctx, args, client, &dependencies)
 
 	if err != nil {
 		return err
 	}
 
-	err = writeFiles(strutil.UnEscapeDollar(hcl), dest, console)
-
-	return err
+	return writeExport(args, &dependencies, hcl, unresolved)
 }
 
-func ConvertProjectToTerraform(url string, space string, apiKey string, dest string, console bool, projectId string) error {
-	client := client.OctopusClient{
-		Url:    url,
-		Space:  space,
-		ApiKey: apiKey,
+func ConvertProjectToTerraform(ctx context.Context, args Arguments) error {
+	client, err := newCountingClient(args)
+
+	if err != nil {
+		return err
 	}
 
-	dependencies := converters.ResourceDetailsCollection{}
+	dependencies := converters.ResourceDetailsCollection{
+		WorkerPool:      workerpool.NewWorkerPool(args.Parallelism),
+		ContinueOnError: args.ContinueOnError,
+		Format:          args.Format,
+		Layout:          args.Layout,
+		ScriptFiles:     args.ScriptFiles,
+	}
 
-	converters.TerraformProviderGenerator{}.ToHcl("space_population", &dependencies)
+	// In module mode, the dependencies shared with other projects are passed to the module as input variables.
+	// Otherwise, they can be looked up by name in the space the project is applied to.
+	moduleInputs := &converters.ModuleInputs{}
+	dependencyConverter := func(resourceType string, converter converters.ConverterById) converters.ConverterById {
+		if args.Module && converters.IsModuleInput(resourceType) {
+			return converters.ModuleVariableConverter{Client: client, ResourceType: resourceType, Inputs: moduleInputs}
+		}
+		if args.LookupProjectDependencies {
+			return converters.DataLookupConverter{Client: client, ResourceType: resourceType}
+		}
+		return converter
+	}
 
-	environmentConverter := converters.EnvironmentConverter{Client: client}
-	lifecycleConverter := converters.LifecycleConverter{Client: client, EnvironmentConverter: environmentConverter}
+	if args.Module {
+		newProviderGenerator(args).ToModuleHcl("space_population", &dependencies)
+		converters.ModuleRootGenerator{Inputs: moduleInputs, ProviderGenerator: newProviderGenerator(args)}.
+			ToHcl(moduleRootDirectory, "space_population", args.ProjectId, &dependencies)
+	} else {
+		newProviderGenerator(args).ToHcl("space_population", &dependencies)
+	}
+
+	environmentConverter := dependencyConverter("Environments", converters.EnvironmentConverter{Client: client})
+	lifecycleConverter := dependencyConverter("Lifecycles", converters.LifecycleConverter{Client: client, EnvironmentConverter: environmentConverter})
 	gitCredentialsConverter := converters.GitCredentialsConverter{Client: client}
 	tagsetConverter := converters.TagSetConverter{Client: client}
 	channelConverter := converters.ChannelConverter{
@@ -258,7 +736,7 @@ func ConvertProjectToTerraform(url string, space string, apiKey string, dest str
 		LifecycleConverter: lifecycleConverter,
 	}
 
-	projectGroupConverter := converters.ProjectGroupConverter{Client: client}
+	projectGroupConverter := dependencyConverter("ProjectGroups", converters.ProjectGroupConverter{Client: client})
 	tenantVariableConverter := converters.TenantVariableConverter{Client: client}
 	tenantConverter := converters.TenantConverter{
 		Client:                  client,
@@ -268,12 +746,12 @@ func ConvertProjectToTerraform(url string, space string, apiKey string, dest str
 	}
 
 	machinePolicyConverter := converters.MachinePolicyConverter{Client: client}
-	accountConverter := converters.AccountConverter{
+	accountConverter := dependencyConverter("Accounts", converters.AccountConverter{
 		Client:               client,
 		EnvironmentConverter: environmentConverter,
 		TenantConverter:      tenantConverter,
-	}
-	certificateConverter := converters.CertificateConverter{Client: client}
+	})
+	certificateConverter := dependencyConverter("Certificates", converters.CertificateConverter{Client: client})
 
 	kubernetesTargetConverter := converters.KubernetesTargetConverter{
 		Client:                 client,
@@ -334,8 +812,8 @@ func ConvertProjectToTerraform(url string, space string, apiKey string, dest str
 		EnvironmentConverter:   environmentConverter,
 	}
 
-	feedConverter := converters.FeedConverter{Client: client}
-	workerPoolConverter := converters.WorkerPoolConverter{Client: client}
+	feedConverter := dependencyConverter("Feeds", converters.FeedConverter{Client: client})
+	workerPoolConverter := dependencyConverter("WorkerPools", converters.WorkerPoolConverter{Client: client})
 
 	variableSetConverter := converters.VariableSetConverter{
 		Client:                            client,
@@ -356,9 +834,9 @@ func ConvertProjectToTerraform(url string, space string, apiKey string, dest str
 		CertificateConverter:              certificateConverter,
 		WorkerPoolConverter:               workerPoolConverter,
 	}
-	libraryVariableSetConverter := converters.LibraryVariableSetConverter{Client: client, VariableSetConverter: variableSetConverter}
+	libraryVariableSetConverter := dependencyConverter("LibraryVariableSets", converters.LibraryVariableSetConverter{Client: client, VariableSetConverter: variableSetConverter})
 
-	err := converters.ProjectConverter{
+	err = converters.ProjectConverter{
 		Client:                      client,
 		LifecycleConverter:          lifecycleConverter,
 		GitCredentialsConverter:     gitCredentialsConverter,
@@ -376,90 +854,288 @@ func ConvertProjectToTerraform(url string, space string, apiKey string, dest str
 		},
 		VariableSetConverter: variableSetConverter,
 		ChannelConverter:     channelConverter,
-	}.ToHclById(projectId, &dependencies)
+	}.ToHclById(ctx, args.ProjectId, &dependencies)
 
 	if err != nil {
 		return err
 	}
 
-	hcl, err := processResources(dependencies.Resources)
+	if args.DryRun {
+		return printDryRun(args, &dependencies, client)
+	}
+
+	hcl, unresolved, err := renderResources(ctx, args, client, &dependencies)
 
 	if err != nil {
 		return err
 	}
 
-	err = writeFiles(strutil.UnEscapeDollar(hcl), dest, console)
+	return writeExport(args, &dependencies, hcl, unresolved)
+}
+
+// renderResources creates a map of file names to file content, and handles the references to any resources
+// that were not exported as configured by the UnresolvedReferences argument.
+func renderResources(ctx context.Context, args Arguments, client client.OctopusClient, dependencies *converters.ResourceDetailsCollection) (map[string]string, []converters.UnresolvedReference, error) {
+	err := addImportBlocks(ctx, args, dependencies)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hcl, unresolved, err := dependencies.RenderFiles(ctx)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(unresolved) != 0 && args.UnresolvedReferences == UnresolvedReferencesLookup {
+		err = converters.DataLookupConverter{Client: client}.ToHclByUnresolvedReferences(ctx, unresolved, dependencies)
 
-	return err
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Render the resources again, so the references use the new data sources
+		hcl, unresolved, err = dependencies.RenderFiles(ctx)
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(unresolved) != 0 && args.UnresolvedReferences == UnresolvedReferencesFail {
+		printUnresolvedReferences(unresolved)
+		return nil, nil, errors.New("the export references " + fmt.Sprint(len(unresolved)) + " resources that were not exported")
+	}
+
+	return hcl, unresolved, nil
 }
 
-// processResources creates a map of file names to file content
-func processResources(resources []converters.ResourceDetails) (map[string]string, error) {
-	fileMap := map[string]string{}
+// addImportBlocks adds the import blocks configured by the ImportBlocks argument. The existing resources are found
+// by name in the ImportSpaceId space on the same server.
+func addImportBlocks(ctx context.Context, args Arguments, dependencies *converters.ResourceDetailsCollection) error {
+	if args.ImportBlocks == "" {
+		return nil
+	}
+
+	targets := imports.Targets(dependencies)
+	ids := map[string]string{}
 
-	for _, r := range resources {
-		// Some resources are already resolved by their parent, but exist in the resource details map as a lookup.
-		// In these cases, ToHclByProjectId is nil.
-		if r.ToHcl == nil {
-			continue
+	if args.ImportBlocks == imports.ModeLookup {
+		targetArgs := args
+		targetArgs.Space = args.ImportSpaceId
+		targetClient, err := newClient(targetArgs)
+
+		if err != nil {
+			return err
 		}
 
-		hcl, err := r.ToHcl()
+		ids, err = imports.ResolveIds(ctx, targetClient, args.ImportSpaceId, targets)
 
 		if err != nil {
-			return nil, err
+			return err
+		}
+	}
+
+	imports.AddImportBlocks(args.ImportBlocks, targets, ids, dependencies)
+	return nil
+}
+
+// writeExport writes the files and reports any problems with the export. In strict mode, nothing is written if
+// there are any problems. If resources failed to export, the other resources are written and an error is returned.
+func writeExport(args Arguments, dependencies *converters.ResourceDetailsCollection, hcl map[string]string, unresolved []converters.UnresolvedReference) error {
+	warnings := dependencies.Warnings()
+	resourceErrors := dependencies.Errors()
+
+	if args.Strict && len(warnings)+len(resourceErrors)+len(unresolved) != 0 {
+		printProblems(warnings, resourceErrors, unresolved)
+		return exitCodeError{
+			code: exitCodeStrict,
+			err:  errors.New("the export was not written because -strict was set and there were problems exporting the resources"),
+		}
+	}
+
+	files, err := addManifest(hcl, dependencies, unresolved)
+
+	if err != nil {
+		return err
+	}
+
+	files, err = addGraph(files, args.Graph, dependencies)
+
+	if err != nil {
+		return err
+	}
+
+	if args.ImportScripts {
+		for fileName, script := range imports.Scripts(imports.Targets(dependencies)) {
+			files[fileName] = script
 		}
+	}
+
+	err = writeFiles(files, args.Destination, args.Console)
+
+	if err != nil {
+		return err
+	}
 
-		if len(strings.TrimSpace(hcl)) != 0 {
-			fileMap[r.FileName] = hcl
+	printProblems(warnings, resourceErrors, unresolved)
+
+	if len(resourceErrors) != 0 {
+		return exitCodeError{
+			code: exitCodePartialExport,
+			err:  errors.New(fmt.Sprint(len(resourceErrors)) + " resources failed to export. The other resources were written"),
 		}
 	}
 
-	return fileMap, nil
+	return nil
 }
 
-func parseUrl() (string, string, string, string, bool, string, string) {
-	var url string
-	flag.StringVar(&url, "url", "", "The Octopus URL e.g. https://myinstance.octopus.app")
+// printProblems prints a summary of the resources that were skipped or that failed to export, and the references
+// to resources that were not exported
+func printProblems(warnings []converters.ResourceWarning, resourceErrors []converters.ResourceError, unresolved []converters.UnresolvedReference) {
+	for _, w := range warnings {
+		logger.Warn("resource was not exported, or was exported with changes",
+			"type", w.ResourceType, "id", w.Id, "name", w.Name, "reason", w.Message)
+	}
 
-	var space string
-	flag.StringVar(&space, "space", "", "The Octopus space name or ID")
+	for _, e := range resourceErrors {
+		logger.Error("resource failed to export", "type", e.ResourceType, "id", e.Id, "error", e.Err)
+	}
 
-	var apiKey string
-	flag.StringVar(&apiKey, "apiKey", "", "The Octopus api key")
+	printUnresolvedReferences(unresolved)
+}
 
-	var dest string
-	flag.StringVar(&dest, "dest", "", "The directory to place the Terraform files in")
+// addManifest adds a manifest describing the exported resources to the files
+func addManifest(files map[string]string, dependencies *converters.ResourceDetailsCollection, unresolved []converters.UnresolvedReference) (map[string]string, error) {
+	manifestJson, err := manifest.Build(dependencies, unresolved, files).ToJson()
 
-	var console bool
-	flag.BoolVar(&console, "console", false, "Dump Terraform files to the console")
+	if err != nil {
+		return nil, err
+	}
 
-	var projectId string
-	flag.StringVar(&projectId, "projectId", "", "Limit the export to a single project")
+	files[manifest.FileName] = manifestJson
 
-	var projectName string
-	flag.StringVar(&projectName, "projectName", "", "Limit the export to a single project")
+	return files, nil
+}
+
+// addGraph adds the dependency graph in the format to the files, if a format was selected
+func addGraph(files map[string]string, format string, dependencies *converters.ResourceDetailsCollection) (map[string]string, error) {
+	if format == "" {
+		return files, nil
+	}
+
+	dependencyGraph, err := graph.Build(dependencies).Render(format)
+
+	if err != nil {
+		return nil, err
+	}
+
+	files[graph.FileNames[format]] = dependencyGraph
+
+	return files, nil
+}
+
+// printUnresolvedReferences prints a summary of the references to resources that were not exported
+func printUnresolvedReferences(unresolved []converters.UnresolvedReference) {
+	for _, u := range unresolved {
+		logger.Warn("reference to a resource that was not exported is empty in the generated configuration",
+			"file", u.FileName, "type", u.RequesterType, "id", u.RequesterId,
+			"referencedType", u.ResourceType, "referencedId", u.Id)
+	}
+}
+
+func parseUrl() Arguments {
+	arguments := Arguments{}
+
+	flag.StringVar(&arguments.Url, "url", "", "The Octopus URL e.g. https://myinstance.octopus.app. Defaults to the "+credentials.UrlEnvVar+" environment variable")
+	flag.StringVar(&arguments.Space, "space", "", "The Octopus space name or ID")
+	flag.StringVar(&arguments.ApiKey, "apiKey", "", "The Octopus api key. Set to - to read the key from stdin. Defaults to the "+credentials.ApiKeyEnvVar+" environment variable")
+	flag.StringVar(&arguments.AccessToken, "accessToken", "", "An Octopus access token sent as a bearer token. Set to - to read the token from stdin. Defaults to the "+credentials.AccessTokenEnvVar+" environment variable")
+	flag.StringVar(&arguments.OidcToken, "oidcToken", "", "An OIDC ID token exchanged for an Octopus access token. Set to - to read the token from stdin")
+	flag.StringVar(&arguments.ServiceAccountId, "serviceAccountId", "", "The ID of the Octopus service account the OIDC token is exchanged for")
+	flag.StringVar(&arguments.Profile, "profile", "", "The profile to read from the credentials file. Defaults to the "+credentials.DefaultProfile+" profile")
+	flag.StringVar(&arguments.CredentialsFile, "credentialsFile", credentials.DefaultCredentialsFile(), "The file holding the credentials profiles")
+	flag.StringVar(&arguments.Destination, "dest", "", "The directory to place the Terraform files in")
+	flag.BoolVar(&arguments.Console, "console", false, "Dump Terraform files to the console")
+	flag.StringVar(&arguments.ProjectId, "projectId", "", "Limit the export to a single project")
+	flag.StringVar(&arguments.ProjectName, "projectName", "", "Limit the export to a single project")
+	flag.StringVar(&arguments.CaBundle, "caBundle", "", "A PEM file with additional certificate authorities to trust, e.g. an internal CA")
+	flag.StringVar(&arguments.ClientCertificate, "clientCertificate", "", "A PEM client certificate used for mutual TLS. Requires -clientKey")
+	flag.StringVar(&arguments.ClientKey, "clientKey", "", "The PEM private key of the client certificate")
+	flag.StringVar(&arguments.Proxy, "proxy", "", "The HTTP(S) proxy URL e.g. http://proxy:3128. Defaults to the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables")
+	flag.BoolVar(&arguments.InsecureSkipVerify, "insecureSkipVerify", false, "Do not verify the Octopus server certificate. Only use this for testing")
+	flag.Var(&arguments.Headers, "header", "An additional header sent with each request, in the format \"Name: value\". Can be specified multiple times")
+	flag.StringVar(&arguments.Record, "record", "", "Save every response from the Octopus API to a snapshot in this directory")
+	flag.StringVar(&arguments.Replay, "replay", "", "Export the snapshot saved with -record in this directory, without connecting to Octopus")
+	flag.DurationVar(&arguments.Timeout, "timeout", 0, "The maximum time allowed for the whole export e.g. 10m. Defaults to no limit")
+	flag.DurationVar(&arguments.RequestTimeout, "requestTimeout", time.Minute, "The maximum time allowed for each request to the Octopus API e.g. 30s. Set to 0 for no limit")
+	flag.BoolVar(&arguments.Strict, "strict", false, "Fail the export, without writing any files, if any resource could not be exported, was exported with changes, or references a resource that was not exported. Exits with code 3")
+	flag.BoolVar(&arguments.ContinueOnError, "continue-on-error", false, "Export every resource that can be exported, and report the resources that failed at the end. Exits with code 2 if any resource failed")
+	flag.StringVar(&arguments.UnresolvedReferences, "unresolvedReferences", UnresolvedReferencesWarn, "How references to resources that were not exported are handled. "+
+		"Set to "+UnresolvedReferencesWarn+" to print them after the export, "+UnresolvedReferencesFail+" to fail the export, or "+
+		UnresolvedReferencesLookup+" to look up the existing resources by name with data sources")
+	flag.StringVar(&arguments.LogLevel, "log-level", "info", "The minimum level of the messages written to stderr. One of debug, info, warn or error. "+
+		"Set to debug to log every request to the Octopus API with its status code and duration")
+	flag.StringVar(&arguments.LogFormat, "log-format", LogFormatText, "The format of the messages written to stderr. One of "+LogFormatText+" or "+LogFormatJson)
+	flag.BoolVar(&arguments.Quiet, "quiet", false, "Only write errors to stderr, e.g. in CI pipelines. Overrides -log-level")
+	flag.BoolVar(&arguments.DryRun, "dry-run", false, "Discover the resources to export, and print them by type and by the dependency that pulled them in, "+
+		"with the estimated number of API calls, without writing any files")
+	flag.StringVar(&arguments.Graph, "graph", "", "Write a graph of the dependencies between the exported resources, to "+
+		graph.FileNames[graph.FormatDot]+" when set to "+graph.FormatDot+" or "+graph.FileNames[graph.FormatMermaid]+" when set to "+graph.FormatMermaid+
+		". With -dry-run, the graph is printed instead")
+	flag.StringVar(&arguments.Format, "format", hcl.FormatHcl, "The syntax of the generated configuration. Set to "+hcl.FormatHcl+
+		" for the HCL native syntax, or "+hcl.FormatTfJson+" for the Terraform JSON syntax, written to .tf.json files, which is easier to process with scripts")
+	flag.BoolVar(&arguments.Module, "module", false, "Export the project as a Terraform module, with the lifecycle, environments, feeds, accounts, worker pools and project group "+
+		"passed in as input variables, and a sample root module in the "+moduleRootDirectory+" directory that looks up the existing resources by name. Requires -projectId or -projectName")
+	flag.BoolVar(&arguments.LookupProjectDependencies, "lookupProjectDependencies", false, "Reference the lifecycles, environments, feeds, accounts, certificates, worker pools, "+
+		"project group and library variable sets of the project with data sources that find the existing resources by name, instead of exporting them. Requires -projectId or -projectName")
+	flag.StringVar(&arguments.ImportBlocks, "importBlocks", "", "Write Terraform 1.5 import blocks that adopt the existing resources into the state. Set to "+
+		imports.ModeLookup+" to find the IDs of the existing resources by name in the space set with -importSpaceId, or "+imports.ModeVariable+
+		" to pass the IDs in as variables, which requires Terraform 1.6")
+	flag.StringVar(&arguments.ImportSpaceId, "importSpaceId", "", "The ID of the space holding the existing resources to import with -importBlocks "+imports.ModeLookup)
+	flag.BoolVar(&arguments.ImportScripts, "importScripts", false, "Write "+imports.BashScriptFileName+" and "+imports.PowerShellScriptFileName+
+		" scripts that find the existing resources by name and import them into the state")
+	flag.StringVar(&arguments.ProviderVersion, "providerVersion", terraform.DefaultOctopusProviderVersion, "The version constraint of the Octopus Terraform provider, e.g. \"~> 0.12\"")
+	flag.StringVar(&arguments.Backend, "backend", "", "Write a backend block of this type, which is one of "+strings.Join(converters.Backends, ", ")+
+		". The settings of the backend are written to backend.tfbackend, and passed to terraform init with -backend-config=backend.tfbackend")
+	flag.BoolVar(&arguments.SensitiveOutputs, "sensitiveOutputs", false, "Mark the outputs.tf outputs holding the IDs of accounts, certificates and git credentials as sensitive")
+	flag.BoolVar(&arguments.RemoteState, "remoteState", false, "Read the ID of the new space from the state of space_creation with a terraform_remote_state data source, "+
+		"rather than passing it to space_population in the octopus_space_id variable. Requires -backend")
+	flag.StringVar(&arguments.Layout, "layout", converters.LayoutResource, "How the resources are grouped into files. Set to "+
+		converters.LayoutResource+" for one file per resource, "+converters.LayoutType+" for one file per resource type like projects.tf, "+
+		converters.LayoutProject+" for one file per project holding its deployment process, channels, triggers and variables, with the other resources in one file per type "+
+		"(projects are written to files rather than directories, because Terraform only reads the files in one directory as one module), or "+
+		converters.LayoutSingle+" for a single main.tf")
+	flag.BoolVar(&arguments.ScriptFiles, "scriptFiles", false, "Write the inline scripts of steps and script modules to .ps1, .sh, .py, .csx or .fsx files, based on the syntax of each script, "+
+		"in the scripts directory, and read them with the file function")
+	flag.IntVar(&arguments.Parallelism, "parallelism", 10, "The maximum number of concurrent requests made to the Octopus API while discovering resources. Set to 1 to discover resources serially")
 
 	flag.Parse()
 
-	return url, space, apiKey, dest, console, projectId, projectName
+	return arguments
 }
 
+// writeFiles validates the generated files, and only writes them if they are all valid
 func writeFiles(files map[string]string, dest string, console bool) error {
+	if err := validation.ValidateFiles(files); err != nil {
+		return err
+	}
+
 	writer := writers.NewFileWriter(dest)
 	output, err := writer.Write(files)
 	if err != nil {
 		return err
 	}
-	fmt.Println(output)
+	logger.Info("wrote the Terraform configuration", "dest", output, "files", len(files))
 
 	if console {
 		consoleWriter := writers.ConsoleWriter{}
-		output, err = consoleWriter.Write(files)
+		// The console output is the only thing written to stdout, so it can be piped to other tools
+		_, err = consoleWriter.Write(files)
 		if err != nil {
 			return err
 		}
-		fmt.Println(output)
 	}
 
 	return nil
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	officialclient "github.com/OctopusDeploy/go-octopusdeploy/v2/pkg/client"
 	"github.com/google/uuid"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/client"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/converters"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/fakeoctopus"
+	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/hcl"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/model/octopus"
 	"github.com/mcasperson/OctopusTerraformExport/cmd/internal/strutil"
 	"github.com/mcasperson/OctopusTerraformTestFramework/test"
@@ -21,8 +25,8 @@ func getTempDir() string {
 }
 
 // createClient creates a client used to access the Octopus API
-func createClient(container *test.OctopusContainer, space string) *client.OctopusClient {
-	return &client.OctopusClient{
+func createClient(container *test.OctopusContainer, space string) *client.OctopusApiClient {
+	return &client.OctopusApiClient{
 		Url:    container.URI,
 		Space:  space,
 		ApiKey: test.ApiKey,
@@ -31,19 +35,39 @@ func createClient(container *test.OctopusContainer, space string) *client.Octopu
 
 func exportSpaceImportAndTest(t *testing.T, initialiseModuleDir string, terraformModuleDir string, initialiseVars []string, populateVars []string, testFunc func(t *testing.T, container *test.OctopusContainer, recreatedSpaceId string) error) {
 	exportImportAndTest(t, initialiseModuleDir, terraformModuleDir, initialiseVars, populateVars, func(url string, space string, apiKey string, dest string) error {
-		return ConvertSpaceToTerraform(url, space, test.ApiKey, dest, true)
+		return ConvertSpaceToTerraform(context.Background(), Arguments{
+			Url:         url,
+			Space:       space,
+			ApiKey:      test.ApiKey,
+			Destination: dest,
+			Console:     true,
+			Parallelism: 10,
+		})
 	}, testFunc)
 }
 
 func exportProjectImportAndTest(t *testing.T, projectName string, initialiseModuleDir string, terraformModuleDir string, initialiseVars []string, populateVars []string, testFunc func(t *testing.T, container *test.OctopusContainer, recreatedSpaceId string) error) {
 	exportImportAndTest(t, initialiseModuleDir, terraformModuleDir, initialiseVars, populateVars, func(url string, space string, apiKey string, dest string) error {
-		projectId, err := ConvertProjectNameToId(url, space, test.ApiKey, projectName)
+		projectId, err := ConvertProjectNameToId(context.Background(), Arguments{
+			Url:         url,
+			Space:       space,
+			ApiKey:      test.ApiKey,
+			ProjectName: projectName,
+		})
 
 		if err != nil {
 			return err
 		}
 
-		return ConvertProjectToTerraform(url, space, test.ApiKey, dest, true, projectId)
+		return ConvertProjectToTerraform(context.Background(), Arguments{
+			Url:         url,
+			Space:       space,
+			ApiKey:      test.ApiKey,
+			Destination: dest,
+			Console:     true,
+			ProjectId:   projectId,
+			Parallelism: 10,
+		})
 	}, testFunc)
 }
 
@@ -95,7 +119,7 @@ func TestSpaceExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		space := octopus.Space{}
-		err := octopusClient.GetSpace(&space)
+		err := octopusClient.GetSpace(context.Background(), &space)
 
 		if err != nil {
 			return err
@@ -132,7 +156,7 @@ func TestProjectGroupExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.ProjectGroup]{}
-		err := octopusClient.GetAllResources("ProjectGroups", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "ProjectGroups", &collection)
 
 		if err != nil {
 			return err
@@ -168,7 +192,7 @@ func TestAwsAccountExport(t *testing.T) {
 			octopusClient := createClient(container, recreatedSpaceId)
 
 			collection := octopus.GeneralCollection[octopus.Account]{}
-			err := octopusClient.GetAllResources("Accounts", &collection)
+			err := octopusClient.GetAllResources(context.Background(), "Accounts", &collection)
 
 			if err != nil {
 				return err
@@ -199,7 +223,7 @@ func TestAzureAccountExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Account]{}
-		err := octopusClient.GetAllResources("Accounts", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Accounts", &collection)
 
 		if err != nil {
 			return err
@@ -246,7 +270,7 @@ func TestUsernamePasswordAccountExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Account]{}
-		err := octopusClient.GetAllResources("Accounts", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Accounts", &collection)
 
 		if err != nil {
 			return err
@@ -293,7 +317,7 @@ func TestGcpAccountExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Account]{}
-		err := octopusClient.GetAllResources("Accounts", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Accounts", &collection)
 
 		if err != nil {
 			return err
@@ -341,7 +365,7 @@ func TestSshAccountExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Account]{}
-		err := octopusClient.GetAllResources("Accounts", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Accounts", &collection)
 
 		if err != nil {
 			return err
@@ -396,7 +420,7 @@ func TestAzureSubscriptionAccountExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Account]{}
-		err := octopusClient.GetAllResources("Accounts", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Accounts", &collection)
 
 		if err != nil {
 			return err
@@ -443,7 +467,7 @@ func TestTokenAccountExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Account]{}
-		err := octopusClient.GetAllResources("Accounts", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Accounts", &collection)
 
 		if err != nil {
 			return err
@@ -494,7 +518,7 @@ func TestHelmFeedExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Feed]{}
-		err := octopusClient.GetAllResources("Feeds", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Feeds", &collection)
 
 		if err != nil {
 			return err
@@ -554,7 +578,7 @@ func TestDockerFeedExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Feed]{}
-		err := octopusClient.GetAllResources("Feeds", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Feeds", &collection)
 
 		if err != nil {
 			return err
@@ -630,7 +654,7 @@ func TestEcrFeedExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Feed]{}
-		err := octopusClient.GetAllResources("Feeds", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Feeds", &collection)
 
 		if err != nil {
 			return err
@@ -690,7 +714,7 @@ func TestMavenFeedExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Feed]{}
-		err := octopusClient.GetAllResources("Feeds", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Feeds", &collection)
 
 		if err != nil {
 			return err
@@ -758,7 +782,7 @@ func TestNugetFeedExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Feed]{}
-		err := octopusClient.GetAllResources("Feeds", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Feeds", &collection)
 
 		if err != nil {
 			return err
@@ -828,7 +852,7 @@ func TestWorkerPoolExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.WorkerPool]{}
-		err := octopusClient.GetAllResources("WorkerPools", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "WorkerPools", &collection)
 
 		if err != nil {
 			return err
@@ -874,7 +898,7 @@ func TestEnvironmentExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Environment]{}
-		err := octopusClient.GetAllResources("Environments", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Environments", &collection)
 
 		if err != nil {
 			return err
@@ -916,7 +940,7 @@ func TestLifecycleExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Lifecycle]{}
-		err := octopusClient.GetAllResources("Lifecycles", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Lifecycles", &collection)
 
 		if err != nil {
 			return err
@@ -974,7 +998,7 @@ func TestVariableSetExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.LibraryVariableSet]{}
-		err := octopusClient.GetAllResources("LibraryVariableSets", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "LibraryVariableSets", &collection)
 
 		if err != nil {
 			return err
@@ -991,7 +1015,7 @@ func TestVariableSetExport(t *testing.T) {
 				}
 
 				resource := octopus.VariableSet{}
-				_, err = octopusClient.GetResourceById("Variables", v.VariableSetId, &resource)
+				_, err = octopusClient.GetResourceById(context.Background(), "Variables", v.VariableSetId, &resource)
 
 				if len(resource.Variables) != 1 {
 					t.Fatal("The library variable set must have one associated variable")
@@ -1039,7 +1063,7 @@ func TestProjectExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Project]{}
-		err := octopusClient.GetAllResources("Projects", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Projects", &collection)
 
 		if err != nil {
 			return err
@@ -1117,7 +1141,7 @@ func TestProjectChannelExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Project]{}
-		err := octopusClient.GetAllResources("Projects", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Projects", &collection)
 
 		if err != nil {
 			return err
@@ -1130,7 +1154,7 @@ func TestProjectChannelExport(t *testing.T) {
 				found = true
 
 				collection := octopus.GeneralCollection[octopus.Channel]{}
-				err = octopusClient.GetAllResources("Projects/"+v.Id+"/channels", &collection)
+				err = octopusClient.GetAllResources(context.Background(), "Projects/"+v.Id+"/channels", &collection)
 
 				channelName := "Test"
 				foundChannel := false
@@ -1187,7 +1211,7 @@ func TestTagSetExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.TagSet]{}
-		err := octopusClient.GetAllResources("TagSets", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "TagSets", &collection)
 
 		if err != nil {
 			return err
@@ -1250,7 +1274,7 @@ func TestGitCredentialsExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.GitCredentials]{}
-		err := octopusClient.GetAllResources("Git-Credentials", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Git-Credentials", &collection)
 
 		if err != nil {
 			return err
@@ -1292,7 +1316,7 @@ func TestScriptModuleExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.LibraryVariableSet]{}
-		err := octopusClient.GetAllResources("LibraryVariableSets", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "LibraryVariableSets", &collection)
 
 		if err != nil {
 			return err
@@ -1309,7 +1333,7 @@ func TestScriptModuleExport(t *testing.T) {
 				}
 
 				resource := octopus.VariableSet{}
-				_, err = octopusClient.GetResourceById("Variables", v.VariableSetId, &resource)
+				_, err = octopusClient.GetResourceById(context.Background(), "Variables", v.VariableSetId, &resource)
 
 				if len(resource.Variables) != 2 {
 					t.Fatal("The library variable set must have two associated variables")
@@ -1382,7 +1406,7 @@ func TestTenantsExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Tenant]{}
-		err := octopusClient.GetAllResources("Tenants", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Tenants", &collection)
 
 		if err != nil {
 			return err
@@ -1433,7 +1457,7 @@ func TestCertificateExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Certificate]{}
-		err := octopusClient.GetAllResources("Certificates", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Certificates", &collection)
 
 		if err != nil {
 			return err
@@ -1487,7 +1511,7 @@ func TestTenantVariablesExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := []octopus.TenantVariable{}
-		err := octopusClient.GetAllResources("TenantVariables/All", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "TenantVariables/All", &collection)
 
 		if err != nil {
 			return err
@@ -1527,7 +1551,7 @@ func TestMachinePolicyExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.MachinePolicy]{}
-		err := octopusClient.GetAllResources("MachinePolicies", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "MachinePolicies", &collection)
 
 		if err != nil {
 			return err
@@ -1633,7 +1657,7 @@ func TestProjectTriggerExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Project]{}
-		err := octopusClient.GetAllResources("Projects", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Projects", &collection)
 
 		if err != nil {
 			return err
@@ -1647,7 +1671,7 @@ func TestProjectTriggerExport(t *testing.T) {
 				foundProject = true
 
 				triggers := octopus.GeneralCollection[octopus.ProjectTrigger]{}
-				err = octopusClient.GetAllResources("Projects/"+project.Id+"/Triggers", &triggers)
+				err = octopusClient.GetAllResources(context.Background(), "Projects/"+project.Id+"/Triggers", &triggers)
 
 				for _, trigger := range triggers.Items {
 					foundTrigger = true
@@ -1689,7 +1713,7 @@ func TestK8sTargetExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.KubernetesEndpointResource]{}
-		err := octopusClient.GetAllResources("Machines", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Machines", &collection)
 
 		if err != nil {
 			return err
@@ -1731,7 +1755,7 @@ func TestSshTargetExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.SshEndpointResource]{}
-		err := octopusClient.GetAllResources("Machines", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Machines", &collection)
 
 		if err != nil {
 			return err
@@ -1770,7 +1794,7 @@ func TestListeningTargetExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.ListeningEndpointResource]{}
-		err := octopusClient.GetAllResources("Machines", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Machines", &collection)
 
 		if err != nil {
 			return err
@@ -1821,7 +1845,7 @@ func TestPollingTargetExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.PollingEndpointResource]{}
-		err := octopusClient.GetAllResources("Machines", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Machines", &collection)
 
 		if err != nil {
 			return err
@@ -1872,7 +1896,7 @@ func TestCloudRegionTargetExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.CloudRegionResource]{}
-		err := octopusClient.GetAllResources("Machines", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Machines", &collection)
 
 		if err != nil {
 			return err
@@ -1915,7 +1939,7 @@ func TestOfflineDropTargetExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.OfflineDropResource]{}
-		err := octopusClient.GetAllResources("Machines", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Machines", &collection)
 
 		if err != nil {
 			return err
@@ -1971,7 +1995,7 @@ func TestAzureCloudServiceTargetExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.AzureCloudServiceResource]{}
-		err := octopusClient.GetAllResources("Machines", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Machines", &collection)
 
 		if err != nil {
 			return err
@@ -2030,7 +2054,7 @@ func TestAzureServiceFabricTargetExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.AzureServiceFabricResource]{}
-		err := octopusClient.GetAllResources("Machines", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Machines", &collection)
 
 		if err != nil {
 			return err
@@ -2089,7 +2113,7 @@ func TestAzureWebAppTargetExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.AzureWebAppResource]{}
-		err := octopusClient.GetAllResources("Machines", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Machines", &collection)
 
 		if err != nil {
 			return err
@@ -2157,7 +2181,7 @@ func TestSingleProjectGroupExport(t *testing.T) {
 		// Test that the project exported its project group
 		err := func() error {
 			collection := octopus.GeneralCollection[octopus.ProjectGroup]{}
-			err := octopusClient.GetAllResources("ProjectGroups", &collection)
+			err := octopusClient.GetAllResources(context.Background(), "ProjectGroups", &collection)
 
 			if err != nil {
 				return err
@@ -2186,7 +2210,7 @@ func TestSingleProjectGroupExport(t *testing.T) {
 		// Verify that the single project was exported
 		err = func() error {
 			projectCollection := octopus.GeneralCollection[octopus.Project]{}
-			err = octopusClient.GetAllResources("Projects", &projectCollection)
+			err = octopusClient.GetAllResources(context.Background(), "Projects", &projectCollection)
 
 			if err != nil {
 				return err
@@ -2207,7 +2231,7 @@ func TestSingleProjectGroupExport(t *testing.T) {
 			}
 
 			variableSet := octopus.VariableSet{}
-			_, err = octopusClient.GetResourceById("Variables", *projectCollection.Items[0].VariableSetId, &variableSet)
+			_, err = octopusClient.GetResourceById(context.Background(), "Variables", *projectCollection.Items[0].VariableSetId, &variableSet)
 
 			if err != nil {
 				return err
@@ -2230,7 +2254,7 @@ func TestSingleProjectGroupExport(t *testing.T) {
 		// Verify that the single channel was exported
 		err = func() error {
 			channelsCollection := octopus.GeneralCollection[octopus.Channel]{}
-			err = octopusClient.GetAllResources("Channels", &channelsCollection)
+			err = octopusClient.GetAllResources(context.Background(), "Channels", &channelsCollection)
 
 			if err != nil {
 				return err
@@ -2261,7 +2285,7 @@ func TestSingleProjectGroupExport(t *testing.T) {
 		// Verify that the single trigger was exported
 		err = func() error {
 			triggersCollection := octopus.GeneralCollection[octopus.ProjectTrigger]{}
-			err = octopusClient.GetAllResources("ProjectTriggers", &triggersCollection)
+			err = octopusClient.GetAllResources(context.Background(), "ProjectTriggers", &triggersCollection)
 
 			if err != nil {
 				return err
@@ -2292,7 +2316,7 @@ func TestSingleProjectGroupExport(t *testing.T) {
 		// Verify that the single tenant was exported
 		err = func() error {
 			tenantsCollection := octopus.GeneralCollection[octopus.Tenant]{}
-			err = octopusClient.GetAllResources("Tenants", &tenantsCollection)
+			err = octopusClient.GetAllResources(context.Background(), "Tenants", &tenantsCollection)
 
 			if err != nil {
 				return err
@@ -2322,7 +2346,7 @@ func TestSingleProjectGroupExport(t *testing.T) {
 		// Verify that the tenant tags were exported
 		err = func() error {
 			tagsCollection := octopus.GeneralCollection[octopus.TagSet]{}
-			err = octopusClient.GetAllResources("TagSets", &tagsCollection)
+			err = octopusClient.GetAllResources(context.Background(), "TagSets", &tagsCollection)
 
 			if err != nil {
 				return err
@@ -2352,7 +2376,7 @@ func TestSingleProjectGroupExport(t *testing.T) {
 		// Verify that the environments were exported
 		err = func() error {
 			environmentsCollection := octopus.GeneralCollection[octopus.Tenant]{}
-			err = octopusClient.GetAllResources("Environments", &environmentsCollection)
+			err = octopusClient.GetAllResources(context.Background(), "Environments", &environmentsCollection)
 
 			if err != nil {
 				return err
@@ -2401,7 +2425,7 @@ func TestSingleProjectGroupExport(t *testing.T) {
 		// Verify that the library variable set was exported
 		err = func() error {
 			libraryVariableSetCollection := octopus.GeneralCollection[octopus.LibraryVariableSet]{}
-			err = octopusClient.GetAllResources("LibraryVariableSets", &libraryVariableSetCollection)
+			err = octopusClient.GetAllResources(context.Background(), "LibraryVariableSets", &libraryVariableSetCollection)
 
 			if err != nil {
 				return err
@@ -2432,7 +2456,7 @@ func TestSingleProjectGroupExport(t *testing.T) {
 		// Verify that the library variable set was exported
 		err = func() error {
 			collection := octopus.GeneralCollection[octopus.Lifecycle]{}
-			err = octopusClient.GetAllResources("Lifecycles", &collection)
+			err = octopusClient.GetAllResources(context.Background(), "Lifecycles", &collection)
 
 			if err != nil {
 				return err
@@ -2459,7 +2483,7 @@ func TestSingleProjectGroupExport(t *testing.T) {
 		// Verify that the git credential was exported
 		err = func() error {
 			collection := octopus.GeneralCollection[octopus.GitCredentials]{}
-			err = octopusClient.GetAllResources("Git-Credentials", &collection)
+			err = octopusClient.GetAllResources(context.Background(), "Git-Credentials", &collection)
 
 			if err != nil {
 				return err
@@ -2500,7 +2524,7 @@ func TestProjectWithGitUsernameExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Project]{}
-		err := octopusClient.GetAllResources("Projects", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Projects", &collection)
 
 		if err != nil {
 			return err
@@ -2538,7 +2562,7 @@ func TestProjectWithDollarSignsExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Project]{}
-		err := octopusClient.GetAllResources("Projects", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Projects", &collection)
 
 		if err != nil {
 			return err
@@ -2568,7 +2592,7 @@ func TestProjectTerraformInlineScriptExport(t *testing.T) {
 		octopusClient := createClient(container, recreatedSpaceId)
 
 		collection := octopus.GeneralCollection[octopus.Project]{}
-		err := octopusClient.GetAllResources("Projects", &collection)
+		err := octopusClient.GetAllResources(context.Background(), "Projects", &collection)
 
 		if err != nil {
 			return err
@@ -2589,3 +2613,33 @@ func TestProjectTerraformInlineScriptExport(t *testing.T) {
 		return nil
 	})
 }
+
+// TestMissingProjectExport verifies that exporting a project that does not exist fails without writing any files
+func TestMissingProjectExport(t *testing.T) {
+	server := fakeoctopus.NewServer()
+	defer server.Close()
+
+	for _, continueOnError := range []bool{false, true} {
+		dest := t.TempDir()
+		err := run(context.Background(), Arguments{
+			Url:                  server.URL,
+			Space:                "Spaces-1",
+			ApiKey:               "API-FAKE",
+			Destination:          dest,
+			ProjectId:            "Projects-999",
+			Parallelism:          10,
+			ContinueOnError:      continueOnError,
+			UnresolvedReferences: UnresolvedReferencesWarn,
+			Format:               hcl.FormatHcl,
+			Layout:               converters.LayoutResource,
+		})
+
+		if err == nil || exitCodeOf(err) == 0 {
+			t.Fatalf("expected a missing project to fail with a non-zero exit code (continue on error: %v)", continueOnError)
+		}
+
+		if _, err := os.Stat(filepath.Join(dest, "space_population", "project_project_.tf")); err == nil {
+			t.Fatalf("expected no project to be written (continue on error: %v)", continueOnError)
+		}
+	}
+}
@@ -571,8 +571,24 @@ function getElementByXpath(path) {
     return document.evaluate(path, document, null, XPathResult.FIRST_ORDERED_NODE_TYPE, null).singleNodeValue;
 }
 
+// Cancels the export that is currently running, if any
+let hclExportController = null
+
+function abortHclExport() {
+    if (hclExportController != null) {
+        hclExportController.abort()
+        hclExportController = null
+    }
+}
+
+// Stop making API requests once the user leaves the page
+window.addEventListener("pagehide", abortHclExport)
+
 function dumpToHcl() {
     try {
+        // Any previous export is for a project that is no longer displayed
+        abortHclExport()
+
         if (window.location.href.split("/")[5] === "projects") {
             // Remove an existing menu item
             let existing = document.getElementById("hcl-export")
@@ -597,6 +613,9 @@ function dumpToHcl() {
             hclOutput.appendChild(hclContent);
             nav.append(hclOutput);
 
+            const controller = new AbortController()
+            hclExportController = controller
+
             // Download the WASM file
             GM_xmlhttpRequest({
                 method: "GET",
@@ -613,7 +632,7 @@ function dumpToHcl() {
                             let space = window.location.href.split("/")[4]
                             let project = window.location.href.split("/")[6]
 
-                            convertProject(server, space, project)
+                            convertProject(server, space, project, controller.signal)
                                 .then(function(hcl) {
 
                                     hclOutput.style.cursor = 'pointer';